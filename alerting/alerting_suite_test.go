@@ -0,0 +1,13 @@
+package alerting_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAlerting(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Alerting Suite")
+}
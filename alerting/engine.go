@@ -0,0 +1,228 @@
+package alerting
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/pivotal-golang/lager"
+)
+
+type State string
+
+const (
+	Inactive State = "inactive"
+	Pending  State = "pending"
+	Firing   State = "firing"
+)
+
+// Alert is a rule whose condition holds for one of the metrics it selects
+type Alert struct {
+	Rule       string                 `json:"rule"`
+	Expression string                 `json:"expression"`
+	State      State                  `json:"state"`
+	Tags       map[string]interface{} `json:"tags,omitempty"`
+	Value      float64                `json:"value"`
+	ActiveAt   time.Time              `json:"active_at"`
+	FiredAt    *time.Time             `json:"fired_at,omitempty"`
+}
+
+// Engine evaluates every rule against every snapshot it receives, at the
+// time the snapshot was collected. Metrics that disappear from a snapshot
+// no longer satisfy any rule. The notifier, if any, observes the alerts
+// after every evaluation.
+type Engine struct {
+	snapshots <-chan collection.Snapshot
+	rules     []Rule
+	notifier  *Notifier
+	logger    lager.Logger
+
+	lock   *sync.RWMutex
+	active map[string]Alert
+}
+
+func NewEngine(
+	snapshots <-chan collection.Snapshot,
+	rules []Rule,
+	notifier *Notifier,
+	logger lager.Logger,
+) *Engine {
+	return &Engine{
+		snapshots: snapshots,
+		rules:     rules,
+		notifier:  notifier,
+		logger:    logger.Session("alerting"),
+
+		lock:   &sync.RWMutex{},
+		active: map[string]Alert{},
+	}
+}
+
+func (e *Engine) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			e.Evaluate(snapshot)
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// Alerts are the pending and firing alerts, ordered by rule
+func (e *Engine) Alerts() []Alert {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	keys := make([]string, 0, len(e.active))
+	for key := range e.active {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	alerts := make([]Alert, len(keys))
+	for i, key := range keys {
+		alerts[i] = e.active[key]
+	}
+
+	return alerts
+}
+
+func (e *Engine) Evaluate(snapshot collection.Snapshot) {
+	e.evaluate(snapshot)
+
+	if e.notifier != nil {
+		e.notifier.Observe(snapshot.Timestamp, e.Alerts())
+	}
+}
+
+func (e *Engine) evaluate(snapshot collection.Snapshot) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	now := snapshot.Timestamp
+	holding := map[string]bool{}
+
+	for _, rule := range e.rules {
+		for _, context := range snapshot.Contexts {
+			for _, m := range context.Metrics {
+				if !rule.matches(context.Name, m) {
+					continue
+				}
+
+				value, ok := metric.Of(m)
+				if !ok || value.Type == metric.Histogram {
+					continue
+				}
+
+				key := alertKey(rule.Name, m.Tags)
+
+				// an unreadable BBS says nothing about the condition, so an
+				// active alert is left as it is
+				if instruments.IsUnreadable(context.Name, value.Number) {
+					if _, found := e.active[key]; found {
+						holding[key] = true
+					}
+					continue
+				}
+
+				if !rule.holds(value.Number) {
+					continue
+				}
+
+				holding[key] = true
+
+				alert, found := e.active[key]
+				if !found {
+					alert = Alert{
+						Rule:       rule.Name,
+						Expression: rule.Expression(),
+						State:      Pending,
+						Tags:       m.Tags,
+						ActiveAt:   now,
+						Value:      value.Number,
+					}
+					e.transition(alert, Inactive)
+				}
+				alert.Value = value.Number
+
+				if alert.State == Pending && now.Sub(alert.ActiveAt) >= rule.For {
+					firedAt := now
+					alert.State = Firing
+					alert.FiredAt = &firedAt
+					e.transition(alert, Pending)
+				}
+
+				e.active[key] = alert
+			}
+		}
+	}
+
+	for key, alert := range e.active {
+		if !holding[key] {
+			delete(e.active, key)
+
+			previous := alert.State
+			alert.State = Inactive
+			e.transition(alert, previous)
+		}
+	}
+}
+
+func (e *Engine) transition(alert Alert, from State) {
+	data := lager.Data{
+		"rule":       alert.Rule,
+		"expression": alert.Expression,
+		"from":       from,
+		"to":         alert.State,
+		"value":      alert.Value,
+	}
+	if len(alert.Tags) > 0 {
+		data["tags"] = alert.Tags
+	}
+
+	switch alert.State {
+	case Firing:
+		e.logger.Error("alert-firing", nil, data)
+	case Pending:
+		e.logger.Info("alert-pending", data)
+	default:
+		e.logger.Info("alert-resolved", data)
+	}
+}
+
+// ServeHTTP lists the pending and firing alerts
+func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	payload, err := json.Marshal(struct {
+		Alerts []Alert `json:"alerts"`
+	}{e.Alerts()})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+func alertKey(rule string, tags map[string]interface{}) string {
+	parts := make([]string, 0, len(tags))
+	for tag, value := range tags {
+		parts = append(parts, fmt.Sprintf("%s=%v", tag, value))
+	}
+	sort.Strings(parts)
+
+	return rule + "{" + strings.Join(parts, ",") + "}"
+}
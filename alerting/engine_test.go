@@ -0,0 +1,269 @@
+package alerting_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+func snapshotAt(at time.Time, pending float64, storeErrors map[string]float64) collection.Snapshot {
+	metricsServer := instrumentation.Context{Name: "MetricsServer"}
+	for operation, errors := range storeErrors {
+		metricsServer.Metrics = append(metricsServer.Metrics, instrumentation.Metric{
+			Name:  "StoreErrors",
+			Value: metric.NewCounter(uint64(errors), "errors", ""),
+			Tags:  map[string]interface{}{"operation": operation},
+		})
+	}
+
+	return collection.Snapshot{
+		Timestamp: at,
+		Contexts: []instrumentation.Context{
+			{Name: "Tasks", Metrics: []instrumentation.Metric{{Name: "Pending", Value: metric.NewGauge(pending, "tasks", "")}}},
+			metricsServer,
+		},
+	}
+}
+
+func mustParse(expression string) Rule {
+	rule, err := ParseRule(expression)
+	Ω(err).ShouldNot(HaveOccurred())
+	return rule
+}
+
+var _ = Describe("Engine", func() {
+	var (
+		logger *lagertest.TestLogger
+		engine *Engine
+		start  time.Time
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		start = time.Unix(1000, 0)
+		engine = NewEngine(nil, []Rule{
+			mustParse("TooManyPending: Tasks.Pending > 50 for 5m"),
+			mustParse("GetErrors: MetricsServer.StoreErrors{operation=Get} > 0"),
+		}, nil, logger)
+	})
+
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	It("has no alerts while the conditions don't hold", func() {
+		engine.Evaluate(snapshotAt(at(0), 10, nil))
+		Ω(engine.Alerts()).Should(BeEmpty())
+	})
+
+	It("is pending until the condition has held for the duration, then fires", func() {
+		engine.Evaluate(snapshotAt(at(0), 60, nil))
+
+		alerts := engine.Alerts()
+		Ω(alerts).Should(HaveLen(1))
+		Ω(alerts[0].Rule).Should(Equal("TooManyPending"))
+		Ω(alerts[0].Expression).Should(Equal("Tasks.Pending > 50 for 5m0s"))
+		Ω(alerts[0].State).Should(Equal(Pending))
+		Ω(alerts[0].Value).Should(Equal(float64(60)))
+		Ω(alerts[0].ActiveAt).Should(Equal(at(0)))
+		Ω(alerts[0].FiredAt).Should(BeNil())
+
+		engine.Evaluate(snapshotAt(at(4), 70, nil))
+		Ω(engine.Alerts()[0].State).Should(Equal(Pending))
+		Ω(engine.Alerts()[0].Value).Should(Equal(float64(70)))
+
+		engine.Evaluate(snapshotAt(at(5), 70, nil))
+		alerts = engine.Alerts()
+		Ω(alerts[0].State).Should(Equal(Firing))
+		Ω(alerts[0].ActiveAt).Should(Equal(at(0)))
+		Ω(*alerts[0].FiredAt).Should(Equal(at(5)))
+	})
+
+	It("goes back to inactive once the condition stops holding", func() {
+		engine.Evaluate(snapshotAt(at(0), 60, nil))
+		engine.Evaluate(snapshotAt(at(5), 60, nil))
+		Ω(engine.Alerts()[0].State).Should(Equal(Firing))
+
+		engine.Evaluate(snapshotAt(at(6), 40, nil))
+		Ω(engine.Alerts()).Should(BeEmpty())
+
+		engine.Evaluate(snapshotAt(at(7), 60, nil))
+		Ω(engine.Alerts()[0].State).Should(Equal(Pending))
+		Ω(engine.Alerts()[0].ActiveAt).Should(Equal(at(7)))
+	})
+
+	It("resets a pending alert whose condition stops holding", func() {
+		engine.Evaluate(snapshotAt(at(0), 60, nil))
+		engine.Evaluate(snapshotAt(at(3), 40, nil))
+		engine.Evaluate(snapshotAt(at(5), 60, nil))
+
+		Ω(engine.Alerts()[0].State).Should(Equal(Pending))
+		Ω(engine.Alerts()[0].ActiveAt).Should(Equal(at(5)))
+	})
+
+	It("fires immediately without a duration, once per matching metric", func() {
+		engine.Evaluate(snapshotAt(at(0), 0, map[string]float64{"Get": 2, "Delete": 3}))
+
+		alerts := engine.Alerts()
+		Ω(alerts).Should(HaveLen(1))
+		Ω(alerts[0].Rule).Should(Equal("GetErrors"))
+		Ω(alerts[0].State).Should(Equal(Firing))
+		Ω(alerts[0].Tags).Should(Equal(map[string]interface{}{"operation": "Get"}))
+	})
+
+	It("resolves alerts whose metric disappears", func() {
+		engine.Evaluate(snapshotAt(at(0), 0, map[string]float64{"Get": 2}))
+		Ω(engine.Alerts()).Should(HaveLen(1))
+
+		engine.Evaluate(snapshotAt(at(1), 0, nil))
+		Ω(engine.Alerts()).Should(BeEmpty())
+	})
+
+	Context("when the BBS could not be read", func() {
+		BeforeEach(func() {
+			engine = NewEngine(nil, []Rule{
+				mustParse("TooManyPending: Tasks.Pending > 50 for 5m"),
+				mustParse("NothingPending: Tasks.Pending < 1"),
+			}, nil, logger)
+		})
+
+		It("doesn't alert on the -1 the instruments report", func() {
+			engine.Evaluate(snapshotAt(at(0), -1, nil))
+			Ω(engine.Alerts()).Should(BeEmpty())
+		})
+
+		It("leaves active alerts as they are", func() {
+			engine.Evaluate(snapshotAt(at(0), 60, nil))
+			engine.Evaluate(snapshotAt(at(5), 60, nil))
+
+			engine.Evaluate(snapshotAt(at(6), -1, nil))
+			alerts := engine.Alerts()
+			Ω(alerts).Should(HaveLen(1))
+			Ω(alerts[0].State).Should(Equal(Firing))
+			Ω(alerts[0].Value).Should(Equal(float64(60)))
+			Ω(*alerts[0].FiredAt).Should(Equal(at(5)))
+		})
+	})
+
+	It("alerts on negative values outside the BBS instruments", func() {
+		engine = NewEngine(nil, []Rule{
+			mustParse("LowScore: Anomalies.Score < -3"),
+			mustParse("HighScore: Anomalies.Score > 3"),
+		}, nil, logger)
+
+		scoreAt := func(at time.Time, score float64) collection.Snapshot {
+			return collection.Snapshot{
+				Timestamp: at,
+				Contexts: []instrumentation.Context{
+					{Name: "Anomalies", Metrics: []instrumentation.Metric{{Name: "Score", Value: metric.NewGauge(score, "deviations", "")}}},
+				},
+			}
+		}
+
+		engine.Evaluate(scoreAt(at(0), 4))
+		Ω(engine.Alerts()).Should(HaveLen(1))
+		Ω(engine.Alerts()[0].Rule).Should(Equal("HighScore"))
+
+		engine.Evaluate(scoreAt(at(1), -1))
+		Ω(engine.Alerts()).Should(BeEmpty())
+
+		engine.Evaluate(scoreAt(at(2), -4))
+		Ω(engine.Alerts()).Should(HaveLen(1))
+		Ω(engine.Alerts()[0].Rule).Should(Equal("LowScore"))
+		Ω(engine.Alerts()[0].Value).Should(Equal(float64(-4)))
+	})
+
+	It("logs every transition", func() {
+		engine.Evaluate(snapshotAt(at(0), 60, nil))
+		engine.Evaluate(snapshotAt(at(5), 70, nil))
+		engine.Evaluate(snapshotAt(at(6), 40, nil))
+
+		logs := logger.Logs()
+		Ω(logs).Should(HaveLen(3))
+
+		Ω(logs[0].Message).Should(Equal("test.alerting.alert-pending"))
+		Ω(logs[0].Data["rule"]).Should(Equal("TooManyPending"))
+		Ω(logs[0].Data["from"]).Should(Equal("inactive"))
+		Ω(logs[0].Data["value"]).Should(Equal(float64(60)))
+
+		Ω(logs[1].Message).Should(Equal("test.alerting.alert-firing"))
+		Ω(logs[1].LogLevel).Should(Equal(lager.ERROR))
+		Ω(logs[1].Data["from"]).Should(Equal("pending"))
+		Ω(logs[1].Data["value"]).Should(Equal(float64(70)))
+
+		Ω(logs[2].Message).Should(Equal("test.alerting.alert-resolved"))
+		Ω(logs[2].Data["from"]).Should(Equal("firing"))
+		Ω(logs[2].Data["to"]).Should(Equal("inactive"))
+	})
+
+	It("lets the notifier observe the alerts after every evaluation", func() {
+		notifier := NewNotifier(time.Hour, logger)
+		notifications := notifier.Subscribe()
+		engine = NewEngine(nil, []Rule{mustParse("Tasks.Pending > 50")}, notifier, logger)
+
+		engine.Evaluate(snapshotAt(at(0), 60, nil))
+
+		var notification Notification
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.State).Should(Equal(Firing))
+		Ω(notification.Timestamp).Should(Equal(at(0)))
+
+		engine.Evaluate(snapshotAt(at(1), 40, nil))
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.State).Should(Equal(Resolved))
+	})
+
+	It("evaluates every snapshot it receives", func() {
+		snapshots := make(chan collection.Snapshot)
+		engine = NewEngine(snapshots, []Rule{mustParse("Tasks.Pending > 50")}, nil, logger)
+
+		process := ifrit.Envoke(engine)
+		defer func() {
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		}()
+
+		snapshots <- snapshotAt(at(0), 60, nil)
+		Eventually(engine.Alerts).Should(HaveLen(1))
+	})
+
+	It("serves the active alerts", func() {
+		engine.Evaluate(snapshotAt(at(0), 60, map[string]float64{"Get": 1}))
+
+		server := httptest.NewServer(engine)
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer response.Body.Close()
+
+		Ω(response.StatusCode).Should(Equal(http.StatusOK))
+		Ω(response.Header.Get("Content-Type")).Should(Equal("application/json"))
+
+		var body struct {
+			Alerts []map[string]interface{} `json:"alerts"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&body)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(body.Alerts).Should(HaveLen(2))
+		Ω(body.Alerts[0]["rule"]).Should(Equal("GetErrors"))
+		Ω(body.Alerts[0]["state"]).Should(Equal("firing"))
+		Ω(body.Alerts[0]["tags"]).Should(Equal(map[string]interface{}{"operation": "Get"}))
+		Ω(body.Alerts[1]["rule"]).Should(Equal("TooManyPending"))
+		Ω(body.Alerts[1]["state"]).Should(Equal("pending"))
+		Ω(body.Alerts[1]).ShouldNot(HaveKey("fired_at"))
+	})
+})
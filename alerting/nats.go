@@ -0,0 +1,54 @@
+package alerting
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/cloudfoundry/yagnats"
+	"github.com/pivotal-golang/lager"
+)
+
+// NATS publishes every notification as JSON on a subject
+type NATS struct {
+	notifications <-chan Notification
+	natsClient    yagnats.NATSClient
+	subject       string
+	logger        lager.Logger
+}
+
+func NewNATS(
+	notifications <-chan Notification,
+	natsClient yagnats.NATSClient,
+	subject string,
+	logger lager.Logger,
+) *NATS {
+	return &NATS{
+		notifications: notifications,
+		natsClient:    natsClient,
+		subject:       subject,
+		logger:        logger.Session("nats-notifier", lager.Data{"subject": subject}),
+	}
+}
+
+func (n *NATS) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case notification := <-n.notifications:
+			payload, err := json.Marshal(notification)
+			if err != nil {
+				n.logger.Error("failed-to-marshal", err)
+				continue
+			}
+
+			err = n.natsClient.Publish(n.subject, payload)
+			if err != nil {
+				n.logger.Error("failed-to-publish", err, lager.Data{"rule": notification.Rule, "state": notification.State})
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
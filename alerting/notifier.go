@@ -0,0 +1,137 @@
+package alerting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// Resolved is the state notified once a firing alert stops holding
+const Resolved State = "resolved"
+
+// NotificationQueueSize is how many notifications a sink can fall behind by
+// before the oldest are dropped
+const NotificationQueueSize = 100
+
+type Notification struct {
+	Alert
+
+	// Repeat is set when the alert was already notified as firing
+	Repeat    bool      `json:"repeat"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Notifier decides which alerts to notify sinks of. Pending alerts are not
+// notified. A firing alert is notified when it starts firing, and again
+// every repeat interval while it keeps firing; it is notified as resolved
+// once it stops. An alert that fires again within the repeat interval of
+// its last notification is held back until the interval has passed, so a
+// flapping alert is notified at most once per repeat interval.
+type Notifier struct {
+	repeatInterval time.Duration
+	logger         lager.Logger
+
+	lock        *sync.Mutex
+	notified    map[string]notified
+	subscribers []chan Notification
+}
+
+type notified struct {
+	alert  Alert
+	firing bool
+	at     time.Time
+}
+
+func NewNotifier(repeatInterval time.Duration, logger lager.Logger) *Notifier {
+	return &Notifier{
+		repeatInterval: repeatInterval,
+		logger:         logger.Session("notifier"),
+
+		lock:     &sync.Mutex{},
+		notified: map[string]notified{},
+	}
+}
+
+// Subscribe returns a channel receiving every notification; a subscriber
+// that falls more than NotificationQueueSize behind loses the oldest
+func (n *Notifier) Subscribe() <-chan Notification {
+	notifications := make(chan Notification, NotificationQueueSize)
+
+	n.lock.Lock()
+	n.subscribers = append(n.subscribers, notifications)
+	n.lock.Unlock()
+
+	return notifications
+}
+
+// Observe takes the alerts active at the time of an evaluation
+func (n *Notifier) Observe(now time.Time, alerts []Alert) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	firing := map[string]bool{}
+	for _, alert := range alerts {
+		if alert.State != Firing {
+			continue
+		}
+
+		key := alertKey(alert.Rule, alert.Tags)
+		firing[key] = true
+
+		previous, found := n.notified[key]
+		switch {
+		case !found:
+		case previous.firing && n.repeatInterval > 0 && now.Sub(previous.at) >= n.repeatInterval:
+		case !previous.firing && now.Sub(previous.at) >= n.repeatInterval:
+		default:
+			if previous.firing {
+				previous.alert = alert
+				n.notified[key] = previous
+			}
+			continue
+		}
+
+		n.notified[key] = notified{alert: alert, firing: true, at: now}
+		n.publish(Notification{
+			Alert:     alert,
+			Repeat:    found && previous.firing,
+			Timestamp: now,
+		})
+	}
+
+	for key, previous := range n.notified {
+		if firing[key] {
+			continue
+		}
+
+		if previous.firing {
+			resolved := previous.alert
+			resolved.State = Resolved
+
+			n.notified[key] = notified{alert: resolved, firing: false, at: previous.at}
+			n.publish(Notification{Alert: resolved, Timestamp: now})
+		} else if now.Sub(previous.at) >= n.repeatInterval {
+			delete(n.notified, key)
+		}
+	}
+}
+
+func (n *Notifier) publish(notification Notification) {
+	for _, subscriber := range n.subscribers {
+		select {
+		case subscriber <- notification:
+		default:
+			select {
+			case dropped := <-subscriber:
+				n.logger.Info("dropped-notification", lager.Data{
+					"rule":  dropped.Rule,
+					"state": dropped.State,
+				})
+			default:
+			}
+
+			subscriber <- notification
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package alerting_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+)
+
+var _ = Describe("Notifier", func() {
+	var (
+		notifier      *Notifier
+		notifications <-chan Notification
+		start         time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Unix(1000, 0)
+		notifier = NewNotifier(time.Hour, lagertest.NewTestLogger("test"))
+		notifications = notifier.Subscribe()
+	})
+
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	alert := func(state State, value float64) Alert {
+		return Alert{
+			Rule:       "TooManyPending",
+			Expression: "Tasks.Pending > 50",
+			State:      state,
+			Value:      value,
+			ActiveAt:   start,
+		}
+	}
+
+	It("does not notify pending alerts", func() {
+		notifier.Observe(at(0), []Alert{alert(Pending, 60)})
+		Ω(notifications).ShouldNot(Receive())
+	})
+
+	It("notifies once when an alert starts firing", func() {
+		notifier.Observe(at(0), []Alert{alert(Firing, 60)})
+
+		var notification Notification
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.Rule).Should(Equal("TooManyPending"))
+		Ω(notification.State).Should(Equal(Firing))
+		Ω(notification.Value).Should(Equal(float64(60)))
+		Ω(notification.Repeat).Should(BeFalse())
+		Ω(notification.Timestamp).Should(Equal(at(0)))
+
+		notifier.Observe(at(1), []Alert{alert(Firing, 61)})
+		notifier.Observe(at(59), []Alert{alert(Firing, 62)})
+		Ω(notifications).ShouldNot(Receive())
+	})
+
+	It("repeats every repeat interval while firing", func() {
+		notifier.Observe(at(0), []Alert{alert(Firing, 60)})
+		Ω(notifications).Should(Receive())
+
+		notifier.Observe(at(60), []Alert{alert(Firing, 70)})
+
+		var notification Notification
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.Repeat).Should(BeTrue())
+		Ω(notification.Value).Should(Equal(float64(70)))
+
+		notifier.Observe(at(61), []Alert{alert(Firing, 70)})
+		Ω(notifications).ShouldNot(Receive())
+	})
+
+	It("notifies when a firing alert resolves, with its last value", func() {
+		notifier.Observe(at(0), []Alert{alert(Firing, 60)})
+		notifier.Observe(at(1), []Alert{alert(Firing, 65)})
+		Ω(notifications).Should(Receive())
+
+		notifier.Observe(at(2), nil)
+
+		var notification Notification
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.State).Should(Equal(Resolved))
+		Ω(notification.Value).Should(Equal(float64(65)))
+		Ω(notification.Timestamp).Should(Equal(at(2)))
+
+		notifier.Observe(at(3), nil)
+		Ω(notifications).ShouldNot(Receive())
+	})
+
+	It("does not notify resolved alerts that were only pending", func() {
+		notifier.Observe(at(0), []Alert{alert(Pending, 60)})
+		notifier.Observe(at(1), nil)
+		Ω(notifications).ShouldNot(Receive())
+	})
+
+	It("holds back an alert that fires again within the repeat interval", func() {
+		for minute := 0; minute < 40; minute += 2 {
+			notifier.Observe(at(minute), []Alert{alert(Firing, 60)})
+			notifier.Observe(at(minute+1), nil)
+		}
+
+		Ω(notifications).Should(HaveLen(2))
+		Ω((<-notifications).State).Should(Equal(Firing))
+		Ω((<-notifications).State).Should(Equal(Resolved))
+
+		notifier.Observe(at(59), []Alert{alert(Firing, 60)})
+		Ω(notifications).ShouldNot(Receive())
+
+		notifier.Observe(at(60), []Alert{alert(Firing, 60)})
+
+		var notification Notification
+		Ω(notifications).Should(Receive(&notification))
+		Ω(notification.State).Should(Equal(Firing))
+		Ω(notification.Repeat).Should(BeFalse())
+	})
+
+	It("tells alerts of the same rule apart by their tags", func() {
+		get := alert(Firing, 1)
+		get.Tags = map[string]interface{}{"operation": "Get"}
+		remove := alert(Firing, 1)
+		remove.Tags = map[string]interface{}{"operation": "Delete"}
+
+		notifier.Observe(at(0), []Alert{get})
+		notifier.Observe(at(1), []Alert{get, remove})
+		Ω(notifications).Should(HaveLen(2))
+	})
+
+	Context("without a repeat interval", func() {
+		BeforeEach(func() {
+			notifier = NewNotifier(0, lagertest.NewTestLogger("test"))
+			notifications = notifier.Subscribe()
+		})
+
+		It("never repeats, but notifies every time an alert fires again", func() {
+			notifier.Observe(at(0), []Alert{alert(Firing, 60)})
+			notifier.Observe(at(1), []Alert{alert(Firing, 60)})
+			notifier.Observe(at(2), nil)
+			notifier.Observe(at(3), []Alert{alert(Firing, 60)})
+
+			Ω(notifications).Should(HaveLen(3))
+		})
+	})
+
+	It("drops the oldest notifications of a subscriber that falls behind", func() {
+		alerts := []Alert{}
+		for i := 0; i <= NotificationQueueSize; i++ {
+			firing := alert(Firing, float64(i))
+			firing.Tags = map[string]interface{}{"index": i}
+
+			alerts = append(alerts, firing)
+			notifier.Observe(start.Add(time.Duration(i)*time.Second), alerts)
+		}
+
+		Ω(notifications).Should(HaveLen(NotificationQueueSize))
+		Ω((<-notifications).Value).Should(Equal(float64(1)))
+	})
+})
@@ -0,0 +1,190 @@
+package alerting
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+)
+
+type Config struct {
+	Rules []Rule
+
+	// notifications are only sent when a webhook URL or NATS subject is set
+	RepeatInterval time.Duration
+	Webhook        WebhookConfig
+	NATSSubject    string
+}
+
+// Rule is a threshold on a metric, like
+//
+//	TooManyPendingTasks: Tasks.Pending > 50 for 5m
+//
+// The name is optional and defaults to the expression. Tags select among the
+// metrics with the same name, e.g. MetricsServer.StoreErrors{operation=Get}.
+// A rule whose condition holds is pending until it has held for the For
+// duration, and then fires.
+type Rule struct {
+	Name      string
+	Context   string
+	Metric    string
+	Tags      map[string]string
+	Operator  string
+	Threshold float64
+	For       time.Duration
+}
+
+var ruleExpression = regexp.MustCompile(
+	`^(?:([\w-]+)\s*:\s*)?` + // name
+		`(\w+)\.(\w+)` + // context and metric
+		`(?:\{([^}]*)\})?` + // tags
+		`\s*(>=|<=|==|!=|>|<)\s*` + // operator
+		`(\S+)` + // threshold
+		`(?:\s+for\s+(\S+))?$`, // duration
+)
+
+func ParseRule(expression string) (Rule, error) {
+	expression = strings.TrimSpace(expression)
+
+	match := ruleExpression.FindStringSubmatch(expression)
+	if match == nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: expected [name:] Context.Metric[{tag=value}] <op> <threshold> [for <duration>]", expression)
+	}
+
+	rule := Rule{
+		Name:     match[1],
+		Context:  match[2],
+		Metric:   match[3],
+		Operator: match[5],
+	}
+
+	if match[4] != "" {
+		rule.Tags = map[string]string{}
+		for _, tag := range strings.Split(match[4], ",") {
+			parts := strings.SplitN(tag, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return Rule{}, fmt.Errorf("invalid rule %q: invalid tag %q", expression, tag)
+			}
+			rule.Tags[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	var err error
+	rule.Threshold, err = strconv.ParseFloat(match[6], 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: invalid threshold %q", expression, match[6])
+	}
+
+	if match[7] != "" {
+		rule.For, err = time.ParseDuration(match[7])
+		if err != nil || rule.For < 0 {
+			return Rule{}, fmt.Errorf("invalid rule %q: invalid duration %q", expression, match[7])
+		}
+	}
+
+	if rule.Name == "" {
+		rule.Name = rule.Expression()
+	}
+
+	return rule, nil
+}
+
+// ParseRules reads a rule per line, skipping blank lines and # comments
+func ParseRules(reader io.Reader) ([]Rule, error) {
+	rules := []Rule{}
+	names := map[string]bool{}
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		if names[rule.Name] {
+			return nil, fmt.Errorf("line %d: duplicate rule %q", line, rule.Name)
+		}
+		names[rule.Name] = true
+
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+func LoadRules(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRules(file)
+}
+
+// Expression is the rule without its name
+func (r Rule) Expression() string {
+	expression := r.Context + "." + r.Metric
+	if len(r.Tags) > 0 {
+		tags := make([]string, 0, len(r.Tags))
+		for tag, value := range r.Tags {
+			tags = append(tags, tag+"="+value)
+		}
+		sort.Strings(tags)
+
+		expression += "{" + strings.Join(tags, ",") + "}"
+	}
+
+	expression += " " + r.Operator + " " + strconv.FormatFloat(r.Threshold, 'g', -1, 64)
+	if r.For > 0 {
+		expression += " for " + r.For.String()
+	}
+
+	return expression
+}
+
+func (r Rule) matches(context string, m instrumentation.Metric) bool {
+	if context != r.Context || m.Name != r.Metric {
+		return false
+	}
+
+	for tag, value := range r.Tags {
+		actual, found := m.Tags[tag]
+		if !found || fmt.Sprint(actual) != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Rule) holds(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	case "!=":
+		return value != r.Threshold
+	}
+
+	return false
+}
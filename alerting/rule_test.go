@@ -0,0 +1,101 @@
+package alerting_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rule", func() {
+	Describe("ParseRule", func() {
+		It("parses a threshold with a duration", func() {
+			rule, err := ParseRule("Tasks.Pending > 50 for 5m")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(rule).Should(Equal(Rule{
+				Name:      "Tasks.Pending > 50 for 5m0s",
+				Context:   "Tasks",
+				Metric:    "Pending",
+				Operator:  ">",
+				Threshold: 50,
+				For:       5 * time.Minute,
+			}))
+		})
+
+		It("parses a named rule without a duration", func() {
+			rule, err := ParseRule("NoExecutors: ServiceRegistrations.Executor == 0")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(rule.Name).Should(Equal("NoExecutors"))
+			Ω(rule.Context).Should(Equal("ServiceRegistrations"))
+			Ω(rule.Metric).Should(Equal("Executor"))
+			Ω(rule.Operator).Should(Equal("=="))
+			Ω(rule.Threshold).Should(Equal(float64(0)))
+			Ω(rule.For).Should(BeZero())
+		})
+
+		It("parses tags", func() {
+			rule, err := ParseRule("MetricsServer.StoreErrors{operation=Get}>=1.5")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(rule.Tags).Should(Equal(map[string]string{"operation": "Get"}))
+			Ω(rule.Operator).Should(Equal(">="))
+			Ω(rule.Threshold).Should(Equal(1.5))
+			Ω(rule.Expression()).Should(Equal("MetricsServer.StoreErrors{operation=Get} >= 1.5"))
+		})
+
+		It("parses every operator", func() {
+			for _, operator := range []string{">", ">=", "<", "<=", "==", "!="} {
+				rule, err := ParseRule("Tasks.Pending " + operator + " 1")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rule.Operator).Should(Equal(operator))
+			}
+		})
+
+		It("rejects invalid rules", func() {
+			for _, expression := range []string{
+				"",
+				"Tasks > 50",
+				"Tasks.Pending 50",
+				"Tasks.Pending > fifty",
+				"Tasks.Pending > 50 for ever",
+				"Tasks.Pending{state} > 50",
+				"Tasks.Pending > 50 until 5m",
+			} {
+				_, err := ParseRule(expression)
+				Ω(err).Should(HaveOccurred(), expression)
+			}
+		})
+	})
+
+	Describe("ParseRules", func() {
+		It("parses a rule per line, skipping blank lines and comments", func() {
+			rules, err := ParseRules(strings.NewReader(`
+# queue is backing up
+TooManyPending: Tasks.Pending > 50 for 5m
+
+ServiceRegistrations.Executor == 0
+`))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(rules).Should(HaveLen(2))
+			Ω(rules[0].Name).Should(Equal("TooManyPending"))
+			Ω(rules[1].Name).Should(Equal("ServiceRegistrations.Executor == 0"))
+		})
+
+		It("reports the line of an invalid rule", func() {
+			_, err := ParseRules(strings.NewReader("Tasks.Pending > 50\nnonsense\n"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("line 2"))
+		})
+
+		It("rejects duplicate names", func() {
+			_, err := ParseRules(strings.NewReader("A: Tasks.Pending > 50\nA: Tasks.Running > 50\n"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("duplicate"))
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package alerting_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"syscall"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Sinks", func() {
+	var (
+		notifications chan Notification
+		logger        *lagertest.TestLogger
+		process       ifrit.Process
+	)
+
+	firedAt := time.Unix(1300, 0).UTC()
+	notification := Notification{
+		Alert: Alert{
+			Rule:       "TooManyPending",
+			Expression: "Tasks.Pending > 50 for 5m0s",
+			State:      Firing,
+			Value:      60,
+			ActiveAt:   time.Unix(1000, 0).UTC(),
+			FiredAt:    &firedAt,
+		},
+		Timestamp: firedAt,
+	}
+
+	expectedPayload := `{
+		"rule": "TooManyPending",
+		"expression": "Tasks.Pending > 50 for 5m0s",
+		"state": "firing",
+		"value": 60,
+		"active_at": "1970-01-01T00:16:40Z",
+		"fired_at": "1970-01-01T00:21:40Z",
+		"repeat": false,
+		"timestamp": "1970-01-01T00:21:40Z"
+	}`
+
+	BeforeEach(func() {
+		notifications = make(chan Notification)
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Describe("Webhook", func() {
+		var (
+			receiver *ghttp.Server
+			config   WebhookConfig
+		)
+
+		BeforeEach(func() {
+			receiver = ghttp.NewServer()
+			config = WebhookConfig{
+				URL:           receiver.URL() + "/alerts",
+				Secret:        "the-secret",
+				MaxRetries:    2,
+				RetryInterval: 10 * time.Millisecond,
+			}
+		})
+
+		JustBeforeEach(func() {
+			process = ifrit.Envoke(NewWebhook(notifications, config, logger))
+		})
+
+		AfterEach(func() {
+			receiver.Close()
+		})
+
+		It("posts every notification as JSON", func() {
+			receiver.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/alerts"),
+				ghttp.VerifyContentType("application/json"),
+				ghttp.VerifyJSON(expectedPayload),
+				ghttp.RespondWith(http.StatusOK, nil),
+			))
+
+			notifications <- notification
+			Eventually(receiver.ReceivedRequests).Should(HaveLen(1))
+		})
+
+		It("signs the body with the secret", func() {
+			signatures := make(chan string, 1)
+			bodies := make(chan []byte, 1)
+			receiver.AppendHandlers(func(w http.ResponseWriter, req *http.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				signatures <- req.Header.Get(SignatureHeader)
+				bodies <- body
+			})
+
+			notifications <- notification
+
+			var signature string
+			Eventually(signatures).Should(Receive(&signature))
+			Ω(signature).Should(Equal("sha256=" + Sign("the-secret", <-bodies)))
+		})
+
+		Context("without a secret", func() {
+			BeforeEach(func() {
+				config.Secret = ""
+			})
+
+			It("does not sign", func() {
+				receiver.AppendHandlers(func(w http.ResponseWriter, req *http.Request) {
+					Ω(req.Header).ShouldNot(HaveKey(SignatureHeader))
+				})
+
+				notifications <- notification
+				Eventually(receiver.ReceivedRequests).Should(HaveLen(1))
+			})
+		})
+
+		It("retries failed posts", func() {
+			receiver.AppendHandlers(
+				ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+				ghttp.RespondWith(429, nil),
+				ghttp.CombineHandlers(
+					ghttp.VerifyJSON(expectedPayload),
+					ghttp.RespondWith(http.StatusOK, nil),
+				),
+			)
+
+			notifications <- notification
+			Eventually(receiver.ReceivedRequests).Should(HaveLen(3))
+		})
+
+		It("gives up after the maximum number of retries", func() {
+			receiver.AllowUnhandledRequests = true
+			receiver.UnhandledRequestStatusCode = http.StatusServiceUnavailable
+
+			notifications <- notification
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-notify"))
+			Ω(receiver.ReceivedRequests()).Should(HaveLen(3))
+		})
+
+		It("does not retry rejected notifications", func() {
+			receiver.AppendHandlers(ghttp.RespondWith(http.StatusBadRequest, "no thanks"))
+
+			notifications <- notification
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("no thanks"))
+			Consistently(receiver.ReceivedRequests, 50*time.Millisecond).Should(HaveLen(1))
+		})
+	})
+
+	Describe("NATS", func() {
+		var natsClient *fakeyagnats.FakeYagnats
+
+		BeforeEach(func() {
+			natsClient = fakeyagnats.New()
+			process = ifrit.Envoke(NewNATS(notifications, natsClient, "diego.runtime.alerts", logger))
+		})
+
+		It("publishes every notification as JSON", func() {
+			notifications <- notification
+
+			Eventually(func() []yagnats.Message {
+				return natsClient.PublishedMessages("diego.runtime.alerts")
+			}).Should(HaveLen(1))
+
+			Ω(natsClient.PublishedMessages("diego.runtime.alerts")[0].Payload).Should(MatchJSON(expectedPayload))
+		})
+	})
+})
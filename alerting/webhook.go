@@ -0,0 +1,90 @@
+package alerting
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/http_post"
+	"github.com/pivotal-golang/lager"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the body, keyed by
+// the webhook secret, as sha256=<signature>
+const SignatureHeader = "X-Runtime-Metrics-Signature"
+
+type WebhookConfig struct {
+	URL    string
+	Secret string
+
+	MaxRetries    int
+	RetryInterval time.Duration
+}
+
+// Webhook posts every notification as JSON, retrying failed posts; a 4xx
+// response other than 429 means the receiver rejected the notification
+// and is not retried
+type Webhook struct {
+	notifications <-chan Notification
+	config        WebhookConfig
+	httpClient    *http.Client
+	logger        lager.Logger
+}
+
+func NewWebhook(notifications <-chan Notification, config WebhookConfig, logger lager.Logger) *Webhook {
+	return &Webhook{
+		notifications: notifications,
+		config:        config,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		logger:        logger.Session("webhook", lager.Data{"url": config.URL}),
+	}
+}
+
+func (w *Webhook) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case notification := <-w.notifications:
+			payload, err := json.Marshal(notification)
+			if err != nil {
+				w.logger.Error("failed-to-marshal", err)
+				continue
+			}
+
+			signalled, err := http_post.Retry(w.config.MaxRetries, w.config.RetryInterval, signals, func() error {
+				return http_post.Post(w.httpClient, w.config.URL, w.header(payload), payload)
+			})
+			if err != nil {
+				w.logger.Error("failed-to-notify", err, lager.Data{"rule": notification.Rule, "state": notification.State})
+			}
+			if signalled {
+				return nil
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+func (w *Webhook) header(payload []byte) http.Header {
+	header := http.Header{"Content-Type": {"application/json"}}
+	if w.config.Secret != "" {
+		header.Set(SignatureHeader, "sha256="+Sign(w.config.Secret, payload))
+	}
+
+	return header
+}
+
+// Sign is the hex encoded HMAC-SHA256 of the payload keyed by the secret,
+// for receivers to verify the SignatureHeader with
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/dump"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
+	Bbs "github.com/cloudfoundry-incubator/runtime-schema/bbs"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+// analyzeDump prints the varz the BBS instruments, and the SLO tracker when
+// objectives are given, would have emitted against an exported store,
+// without reaching the cluster it was exported from. The anomaly scores are
+// left out, as they need a series of collections rather than one, and so
+// are the metrics of the metrics server itself.
+func analyzeDump() {
+	if *dumpPath == "" {
+		log.Fatal("analyze requires -dump")
+	}
+
+	// the varz goes to stdout, so logs go to stderr
+	logger := lager.NewLogger("runtime-metrics-server")
+	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.INFO))
+
+	store, err := dump.Load(*dumpPath)
+	if err != nil {
+		log.Fatalf("failed to load %s: %s", *dumpPath, err)
+	}
+
+	logger.Info("loaded-dump", lager.Data{"path": *dumpPath, "keys": store.Keys()})
+
+	runtimeBBS := Bbs.NewBBS(store, timeprovider.NewTimeProvider(), logger)
+
+	instrumentables := []instrumentation.Instrumentable{
+		instruments.NewTaskInstrument(runtimeBBS),
+		instruments.NewServiceRegistryInstrument(runtimeBBS),
+	}
+
+	if *sloObjectives != "" {
+		objectives, err := slo.LoadObjectives(*sloObjectives)
+		if err != nil {
+			log.Fatalf("failed to load %s: %s", *sloObjectives, err)
+		}
+
+		burnRateWindows, err := slo.ParseWindows(*sloBurnRateWindows)
+		if err != nil {
+			log.Fatalf("invalid -sloBurnRateWindows: %s", err)
+		}
+
+		tracker, err := slo.New(runtimeBBS, slo.Config{
+			Objectives:      objectives,
+			Resolution:      *sloResolution,
+			BurnRateWindows: burnRateWindows,
+		}, timeprovider.NewTimeProvider(), logger)
+		if err != nil {
+			log.Fatalf("invalid slo config: %s", err)
+		}
+
+		instrumentables = append(instrumentables, tracker)
+	}
+
+	varz, err := instrumentation.NewVarzMessage("runtime", instrumentables)
+	if err != nil {
+		log.Fatalf("failed to instrument %s: %s", *dumpPath, err)
+	}
+
+	output, err := json.MarshalIndent(varz, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to marshal varz: %s", err)
+	}
+
+	os.Stdout.Write(append(output, '\n'))
+}
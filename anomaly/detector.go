@@ -0,0 +1,251 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+const ContextName = "Anomalies"
+
+type Config struct {
+	// Watches are the metrics to detect anomalies in, like Tasks.Pending
+	Watches []Watch
+
+	// Alpha is the weight of a new value in the moving average and
+	// variance of its series; smaller values learn the baseline slower
+	Alpha float64
+
+	// WarmUp is the number of values of a series, or of an hour of the day
+	// of a series if Seasonal, observed before it is scored
+	WarmUp int
+
+	// MinDeviation is the smallest deviation a score is relative to, so a
+	// series that has been constant doesn't score the first change as an
+	// infinite anomaly
+	MinDeviation float64
+
+	// Seasonal keeps a separate baseline for every hour of the day, for
+	// metrics whose load differs between day and night
+	Seasonal bool
+}
+
+type Watch struct {
+	Context string
+	Metric  string
+}
+
+// ParseWatches parses a comma separated list of Context.Metric
+func ParseWatches(list string) ([]Watch, error) {
+	watches := []Watch{}
+	for _, watch := range strings.Split(list, ",") {
+		watch = strings.TrimSpace(watch)
+		if watch == "" {
+			continue
+		}
+
+		parts := strings.SplitN(watch, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid metric %q, expected Context.Metric", watch)
+		}
+
+		watches = append(watches, Watch{Context: parts[0], Metric: parts[1]})
+	}
+
+	return watches, nil
+}
+
+// Detector scores how far the values of the watched metrics are from an
+// exponentially weighted moving average, in exponentially weighted standard
+// deviations. The instruments emitting the watched metrics are wrapped with
+// Observe, and the detector itself emits the scores of the values observed
+// since it last emitted, so it must come after them in the collection.
+type Detector struct {
+	config       Config
+	timeProvider timeprovider.TimeProvider
+
+	lock      *sync.Mutex
+	baselines map[string]*baseline
+	scores    []score
+}
+
+type baseline struct {
+	mean     float64
+	variance float64
+	count    int
+}
+
+type score struct {
+	context string
+	name    string
+	unit    string
+	tags    map[string]interface{}
+
+	score    float64
+	baseline float64
+}
+
+// Validate checks the weight of a new value is within (0, 1] and the warm
+// up isn't negative
+func (config Config) Validate() error {
+	if config.Alpha <= 0 || config.Alpha > 1 {
+		return fmt.Errorf("invalid alpha %g, expected a weight greater than 0 and at most 1", config.Alpha)
+	}
+
+	if config.WarmUp < 0 {
+		return fmt.Errorf("invalid warm up %d, expected a number of values that isn't negative", config.WarmUp)
+	}
+
+	return nil
+}
+
+func New(config Config, timeProvider timeprovider.TimeProvider) (*Detector, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Detector{
+		config:       config,
+		timeProvider: timeProvider,
+
+		lock:      new(sync.Mutex),
+		baselines: map[string]*baseline{},
+	}, nil
+}
+
+// Observe wraps an instrument so the detector sees the watched metrics it
+// emits; the context is passed on unchanged
+func (d *Detector) Observe(instrument instrumentation.Instrumentable) instrumentation.Instrumentable {
+	return &observedInstrument{
+		instrument: instrument,
+		detector:   d,
+	}
+}
+
+func (d *Detector) Emit() instrumentation.Context {
+	d.lock.Lock()
+	scores := d.scores
+	d.scores = nil
+	d.lock.Unlock()
+
+	metrics := []instrumentation.Metric{}
+	for _, s := range scores {
+		tags := map[string]interface{}{}
+		for tag, value := range s.tags {
+			tags[tag] = value
+		}
+		tags["context"] = s.context
+		tags["metric"] = s.name
+
+		metrics = append(metrics,
+			instrumentation.Metric{
+				Name:  "Score",
+				Value: metric.NewGauge(s.score, "", "Deviations of the last value of a metric from its baseline, negative when below it"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "Baseline",
+				Value: metric.NewGauge(s.baseline, s.unit, "Moving average of a metric the last value was scored against"),
+				Tags:  tags,
+			},
+		)
+	}
+
+	return instrumentation.Context{
+		Name:    ContextName,
+		Metrics: metrics,
+	}
+}
+
+func (d *Detector) observe(context instrumentation.Context) {
+	now := d.timeProvider.Time()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, m := range context.Metrics {
+		if !d.watches(context.Name, m.Name) {
+			continue
+		}
+
+		value, ok := metric.Of(m)
+		// the instruments report -1 when the BBS could not be read
+		if !ok || value.Type == metric.Histogram || value.Number < 0 {
+			continue
+		}
+
+		key := seriesKey(context.Name, m.Name, m.Tags)
+		if d.config.Seasonal {
+			key = fmt.Sprintf("%s@%02d", key, now.UTC().Hour())
+		}
+
+		b, found := d.baselines[key]
+		if !found {
+			b = &baseline{mean: value.Number}
+			d.baselines[key] = b
+		}
+
+		s := score{
+			context:  context.Name,
+			name:     m.Name,
+			unit:     value.Unit,
+			tags:     m.Tags,
+			baseline: b.mean,
+		}
+		if b.count >= d.config.WarmUp {
+			deviation := math.Max(math.Sqrt(b.variance), d.config.MinDeviation)
+			if deviation > 0 {
+				s.score = (value.Number - b.mean) / deviation
+			}
+		}
+		d.scores = append(d.scores, s)
+
+		b.update(value.Number, d.config.Alpha)
+	}
+}
+
+func (d *Detector) watches(context string, name string) bool {
+	for _, watch := range d.config.Watches {
+		if watch.Context == context && watch.Metric == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (b *baseline) update(value float64, alpha float64) {
+	diff := value - b.mean
+	increment := alpha * diff
+	b.mean += increment
+	b.variance = (1 - alpha) * (b.variance + diff*increment)
+	b.count++
+}
+
+type observedInstrument struct {
+	instrument instrumentation.Instrumentable
+	detector   *Detector
+}
+
+func (o *observedInstrument) Emit() instrumentation.Context {
+	context := o.instrument.Emit()
+	o.detector.observe(context)
+	return context
+}
+
+func seriesKey(context string, name string, tags map[string]interface{}) string {
+	parts := make([]string, 0, len(tags))
+	for tag, value := range tags {
+		parts = append(parts, tag+"="+fmt.Sprint(value))
+	}
+	sort.Strings(parts)
+
+	return context + "/" + name + "{" + strings.Join(parts, ",") + "}"
+}
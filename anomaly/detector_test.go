@@ -0,0 +1,211 @@
+package anomaly_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/anomaly"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeInstrument struct {
+	context instrumentation.Context
+}
+
+func (f *fakeInstrument) Emit() instrumentation.Context {
+	return f.context
+}
+
+var _ = Describe("Detector", func() {
+	var (
+		timeProvider *faketimeprovider.FakeTimeProvider
+		instrument   *fakeInstrument
+		config       Config
+		detector     *Detector
+		observed     instrumentation.Instrumentable
+	)
+
+	pending := func(value float64) instrumentation.Context {
+		return instrumentation.Context{
+			Name: "Tasks",
+			Metrics: []instrumentation.Metric{
+				{Name: "Pending", Value: metric.NewGauge(value, "tasks", "")},
+				{Name: "Running", Value: metric.NewGauge(value, "tasks", "")},
+			},
+		}
+	}
+
+	collect := func(value float64) instrumentation.Context {
+		instrument.context = pending(value)
+		observed.Emit()
+		return detector.Emit()
+	}
+
+	scoreOf := func(context instrumentation.Context) float64 {
+		for _, m := range context.Metrics {
+			if m.Name == "Score" {
+				return m.Value.(metric.Value).Number
+			}
+		}
+
+		Fail("no score emitted")
+		return 0
+	}
+
+	BeforeEach(func() {
+		timeProvider = faketimeprovider.New(time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC))
+		instrument = &fakeInstrument{}
+		config = Config{
+			Watches:      []Watch{{Context: "Tasks", Metric: "Pending"}},
+			Alpha:        0.1,
+			WarmUp:       5,
+			MinDeviation: 1,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		detector, err = New(config, timeProvider)
+		Ω(err).ShouldNot(HaveOccurred())
+		observed = detector.Observe(instrument)
+	})
+
+	It("passes the observed context on unchanged", func() {
+		instrument.context = pending(3)
+		Ω(observed.Emit()).Should(Equal(pending(3)))
+	})
+
+	It("emits a score and baseline gauge for the watched metrics only", func() {
+		context := collect(10)
+
+		Ω(context.Name).Should(Equal("Anomalies"))
+		Ω(context.Metrics).Should(HaveLen(2))
+
+		tags := map[string]interface{}{"context": "Tasks", "metric": "Pending"}
+		Ω(context.Metrics[0].Name).Should(Equal("Score"))
+		Ω(context.Metrics[0].Tags).Should(Equal(tags))
+		Ω(context.Metrics[1].Name).Should(Equal("Baseline"))
+		Ω(context.Metrics[1].Tags).Should(Equal(tags))
+		Ω(context.Metrics[1].Value).Should(Equal(metric.NewGauge(10, "tasks", "Moving average of a metric the last value was scored against")))
+	})
+
+	It("only emits the scores of values observed since it last emitted", func() {
+		collect(10)
+		Ω(detector.Emit().Metrics).Should(BeEmpty())
+	})
+
+	It("doesn't score values while warming up", func() {
+		for i := 0; i < 5; i++ {
+			Ω(scoreOf(collect(float64(100 * i)))).Should(BeZero())
+		}
+	})
+
+	Context("once warmed up", func() {
+		JustBeforeEach(func() {
+			for i := 0; i < 50; i++ {
+				collect(float64(10 + i%3))
+			}
+		})
+
+		It("scores values near the baseline low", func() {
+			Ω(scoreOf(collect(11))).Should(BeNumerically("<", 1))
+		})
+
+		It("scores values far above the baseline highly", func() {
+			Ω(scoreOf(collect(40))).Should(BeNumerically(">", 10))
+		})
+
+		It("scores values far below the baseline negatively", func() {
+			Ω(scoreOf(collect(0))).Should(BeNumerically("<", -3))
+		})
+	})
+
+	It("follows a baseline that changes gradually", func() {
+		value := 10.0
+		for i := 0; i < 200; i++ {
+			value *= 1.01
+			Ω(scoreOf(collect(value))).Should(BeNumerically("<", 3))
+		}
+	})
+
+	It("doesn't score an unreadable BBS", func() {
+		Ω(collect(-1).Metrics).Should(BeEmpty())
+	})
+
+	Context("when the metric has been constant", func() {
+		It("scores changes relative to the minimum deviation", func() {
+			for i := 0; i < 10; i++ {
+				collect(0)
+			}
+
+			Ω(scoreOf(collect(2))).Should(BeNumerically("~", 2, 0.001))
+		})
+	})
+
+	Context("when seasonal", func() {
+		BeforeEach(func() {
+			config.Seasonal = true
+		})
+
+		It("keeps a separate baseline for every hour of the day", func() {
+			for day := 0; day < 10; day++ {
+				timeProvider.Increment(12 * time.Hour)
+				collect(1000)
+				timeProvider.Increment(12 * time.Hour)
+				collect(100)
+			}
+
+			timeProvider.Increment(12 * time.Hour)
+			Ω(scoreOf(collect(1000))).Should(BeNumerically("~", 0, 0.001))
+			timeProvider.Increment(12 * time.Hour)
+			Ω(scoreOf(collect(100))).Should(BeNumerically("~", 0, 0.001))
+			Ω(scoreOf(collect(1000))).Should(BeNumerically(">", 100))
+		})
+	})
+
+	Describe("New", func() {
+		It("requires an alpha greater than 0 and at most 1", func() {
+			config.Alpha = 0
+			_, err := New(config, timeProvider)
+			Ω(err).Should(HaveOccurred())
+
+			config.Alpha = 1.5
+			_, err = New(config, timeProvider)
+			Ω(err).Should(HaveOccurred())
+
+			config.Alpha = 1
+			_, err = New(config, timeProvider)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("requires a warm up that isn't negative", func() {
+			config.WarmUp = -1
+			_, err := New(config, timeProvider)
+			Ω(err).Should(HaveOccurred())
+
+			config.WarmUp = 0
+			_, err = New(config, timeProvider)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Describe("ParseWatches", func() {
+		It("parses a comma separated list of Context.Metric", func() {
+			watches, err := ParseWatches("Tasks.Pending, ServiceRegistrations.Executor,")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(watches).Should(Equal([]Watch{
+				{Context: "Tasks", Metric: "Pending"},
+				{Context: "ServiceRegistrations", Metric: "Executor"},
+			}))
+		})
+
+		It("rejects metrics without a context", func() {
+			_, err := ParseWatches("Pending")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+// Server is a metrics server to query, given on the command line or
+// discovered through NATS
+type Server struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// Scrape is the /varz of a server at the time it was read
+type Scrape struct {
+	Server string
+	At     time.Time
+	Varz   instrumentation.VarzMessage
+}
+
+type Client struct {
+	httpClient   *http.Client
+	timeProvider timeprovider.TimeProvider
+}
+
+func New(timeout time.Duration, timeProvider timeprovider.TimeProvider) *Client {
+	return &Client{
+		httpClient:   &http.Client{Timeout: timeout},
+		timeProvider: timeProvider,
+	}
+}
+
+// Get reads a path from the server with its basic auth, failing unless the
+// server answers 200
+func (c *Client) Get(server Server, path string) ([]byte, error) {
+	request, err := http.NewRequest("GET", server.URL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(server.Username, server.Password)
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s%s returned %d: %s", server.URL, path, response.StatusCode, body)
+	}
+
+	return body, nil
+}
+
+func (c *Client) Scrape(server Server) (Scrape, error) {
+	body, err := c.Get(server, "/varz")
+	if err != nil {
+		return Scrape{}, err
+	}
+
+	scrape := Scrape{Server: server.URL, At: c.timeProvider.Time()}
+	err = json.Unmarshal(body, &scrape.Varz)
+	if err != nil {
+		return Scrape{}, fmt.Errorf("invalid varz from %s: %s", server.URL, err)
+	}
+
+	return scrape, nil
+}
+
+// LoadScrape reads a /varz saved with the json command, as of the time the
+// file was last modified
+func LoadScrape(path string) (Scrape, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Scrape{}, err
+	}
+
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Scrape{}, err
+	}
+
+	scrape := Scrape{Server: path, At: info.ModTime()}
+	err = json.Unmarshal(body, &scrape.Varz)
+	if err != nil {
+		return Scrape{}, fmt.Errorf("invalid varz in %s: %s", path, err)
+	}
+
+	return scrape, nil
+}
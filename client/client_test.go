@@ -0,0 +1,136 @@
+package client_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/client"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+const varz = `{
+	"name": "runtime",
+	"contexts": [
+		{
+			"name": "Tasks",
+			"metrics": [
+				{"name": "Pending", "value": 3, "tags": {"domain": "cf-apps"}},
+				{"name": "Running", "value": 12}
+			]
+		},
+		{
+			"name": "MetricsServer",
+			"metrics": [
+				{"name": "CollectionDuration", "value": {"count": 4, "sum": 0.5, "buckets": []}}
+			]
+		}
+	]
+}`
+
+var _ = Describe("Client", func() {
+	var (
+		server  *ghttp.Server
+		now     time.Time
+		metrics *Client
+		target  Server
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		server = ghttp.NewServer()
+		metrics = New(time.Second, faketimeprovider.New(now))
+		target = Server{URL: server.URL(), Username: "the-username", Password: "the-password"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Scrape", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("GET", "/varz"),
+				ghttp.VerifyBasicAuth("the-username", "the-password"),
+				ghttp.RespondWith(http.StatusOK, varz),
+			))
+		})
+
+		It("reads /varz with basic auth", func() {
+			scrape, err := metrics.Scrape(target)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(scrape.Server).Should(Equal(server.URL()))
+			Ω(scrape.At).Should(Equal(now))
+			Ω(scrape.Varz.Name).Should(Equal("runtime"))
+			Ω(scrape.Varz.Contexts).Should(HaveLen(2))
+		})
+
+		It("flattens into samples", func() {
+			scrape, err := metrics.Scrape(target)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(Samples(scrape.Varz, nil)).Should(Equal([]Sample{
+				{Context: "Tasks", Name: "Pending", Tags: "domain=cf-apps", Value: 3},
+				{Context: "Tasks", Name: "Running", Value: 12},
+				{Context: "MetricsServer", Name: "CollectionDuration", Value: 4, Sum: 0.5, Histogram: true},
+			}))
+
+			Ω(Samples(scrape.Varz, []string{"MetricsServer"})).Should(HaveLen(1))
+		})
+	})
+
+	Context("when the server doesn't answer 200", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(ghttp.RespondWith(http.StatusUnauthorized, "no"))
+		})
+
+		It("returns an error", func() {
+			_, err := metrics.Get(target, "/v1/tasks")
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("/v1/tasks returned 401"))
+		})
+	})
+
+	Describe("LoadScrape", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "runtime-metrics")
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("reads a saved /varz as of when it was written", func() {
+			path := filepath.Join(dir, "varz.json")
+			err := ioutil.WriteFile(path, []byte(varz), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			err = os.Chtimes(path, now, now)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			scrape, err := LoadScrape(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(scrape.At.Equal(now)).Should(BeTrue())
+			Ω(scrape.Varz.Contexts).Should(HaveLen(2))
+		})
+
+		It("fails on anything else", func() {
+			path := filepath.Join(dir, "varz.json")
+			err := ioutil.WriteFile(path, []byte("not json"), 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = LoadScrape(path)
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Change is a metric that differs between two scrapes; Before or After is
+// nil when the metric is only in one of them
+type Change struct {
+	Sample Sample
+	Before *Sample
+	After  *Sample
+}
+
+// Diff lists the metrics that were added, removed or changed between two
+// scrapes, in the order of the later one followed by the removed ones
+func Diff(before Scrape, after Scrape, contexts []string) []Change {
+	previous := map[string]Sample{}
+	for _, sample := range Samples(before.Varz, contexts) {
+		previous[sample.Key()] = sample
+	}
+
+	changes := []Change{}
+	seen := map[string]bool{}
+	for _, sample := range Samples(after.Varz, contexts) {
+		sample := sample
+		seen[sample.Key()] = true
+
+		old, found := previous[sample.Key()]
+		if !found {
+			changes = append(changes, Change{Sample: sample, After: &sample})
+			continue
+		}
+
+		if old.Value != sample.Value || old.Sum != sample.Sum {
+			changes = append(changes, Change{Sample: sample, Before: &old, After: &sample})
+		}
+	}
+
+	for _, sample := range Samples(before.Varz, contexts) {
+		sample := sample
+		if !seen[sample.Key()] {
+			changes = append(changes, Change{Sample: sample, Before: &sample})
+		}
+	}
+
+	return changes
+}
+
+func WriteDiff(w io.Writer, before Scrape, after Scrape, contexts []string) error {
+	elapsed := after.At.Sub(before.At)
+	fmt.Fprintf(w, "%s -> %s over %s\n\n", before.Server, after.Server, elapsed)
+
+	changes := Diff(before, after, contexts)
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "no changes")
+		return nil
+	}
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(table, "CONTEXT\tMETRIC\tTAGS\tBEFORE\tAFTER\tCHANGE")
+
+	for _, change := range changes {
+		beforeValue, afterValue, delta := "-", "-", ""
+		switch {
+		case change.Before == nil:
+			afterValue = formatValue(*change.After)
+			delta = "added"
+		case change.After == nil:
+			beforeValue = formatValue(*change.Before)
+			delta = "removed"
+		default:
+			beforeValue = formatValue(*change.Before)
+			afterValue = formatValue(*change.After)
+			delta = formatChange(*change.Before, *change.After, elapsed)
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			change.Sample.Context, change.Sample.Name, change.Sample.Tags, beforeValue, afterValue, delta)
+	}
+
+	return table.Flush()
+}
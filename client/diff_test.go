@@ -0,0 +1,67 @@
+package client_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	var before, after Scrape
+
+	BeforeEach(func() {
+		now := time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+
+		before = scrapeAt(now.Add(-10*time.Second), `{"contexts": [{"name": "Tasks", "metrics": [
+			{"name": "Pending", "value": 3, "tags": {"domain": "cf-apps"}},
+			{"name": "Running", "value": 12},
+			{"name": "Resolving", "value": 1}
+		]}]}`)
+
+		after = scrapeAt(now, `{"contexts": [{"name": "Tasks", "metrics": [
+			{"name": "Pending", "value": 23, "tags": {"domain": "cf-apps"}},
+			{"name": "Running", "value": 12},
+			{"name": "Claimed", "value": 2}
+		]}]}`)
+	})
+
+	It("lists what was changed, added and removed", func() {
+		changes := Diff(before, after, nil)
+		Ω(changes).Should(HaveLen(3))
+
+		Ω(changes[0].Sample.Name).Should(Equal("Pending"))
+		Ω(changes[0].Before.Value).Should(Equal(float64(3)))
+		Ω(changes[0].After.Value).Should(Equal(float64(23)))
+
+		Ω(changes[1].Sample.Name).Should(Equal("Claimed"))
+		Ω(changes[1].Before).Should(BeNil())
+
+		Ω(changes[2].Sample.Name).Should(Equal("Resolving"))
+		Ω(changes[2].After).Should(BeNil())
+	})
+
+	It("writes them as a table", func() {
+		output := &bytes.Buffer{}
+		err := WriteDiff(output, before, after, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(output.String()).Should(ContainSubstring("CONTEXT  METRIC     TAGS            BEFORE  AFTER  CHANGE\n"))
+		Ω(output.String()).Should(ContainSubstring("Tasks    Pending    domain=cf-apps  3       23     +20 (+2/s)\n"))
+		Ω(output.String()).Should(ContainSubstring("Tasks    Claimed                    -       2      added\n"))
+		Ω(output.String()).Should(ContainSubstring("Tasks    Resolving                  1       -      removed\n"))
+	})
+
+	Context("when nothing changed", func() {
+		It("says so", func() {
+			output := &bytes.Buffer{}
+			err := WriteDiff(output, before, before, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(output.String()).Should(ContainSubstring("no changes"))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/nu7hatch/gouuid"
+)
+
+// Discover asks every component on NATS to announce itself, like the
+// collector does, and returns the servers of the given type that answer
+// within the timeout, by index
+func Discover(natsClient yagnats.NATSClient, componentType string, timeout time.Duration) ([]Server, error) {
+	guid, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	inbox := "runtime-metrics." + guid.String()
+
+	lock := &sync.Mutex{}
+	announcements := map[string]collector_registrar.AnnounceComponentMessage{}
+
+	subscription, err := natsClient.Subscribe(inbox, func(msg *yagnats.Message) {
+		var announcement collector_registrar.AnnounceComponentMessage
+		err := json.Unmarshal(msg.Payload, &announcement)
+		if err != nil || announcement.Type != componentType {
+			return
+		}
+
+		lock.Lock()
+		announcements[announcement.UUID] = announcement
+		lock.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer natsClient.Unsubscribe(subscription)
+
+	err = natsClient.PublishWithReplyTo(collector_registrar.DiscoverComponentMessageSubject, inbox, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(timeout)
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	found := []collector_registrar.AnnounceComponentMessage{}
+	for _, announcement := range announcements {
+		found = append(found, announcement)
+	}
+	sort.Sort(byIndex(found))
+
+	servers := []Server{}
+	for _, announcement := range found {
+		server := Server{URL: "http://" + announcement.Host}
+		if len(announcement.Credentials) == 2 {
+			server.Username = announcement.Credentials[0]
+			server.Password = announcement.Credentials[1]
+		}
+		servers = append(servers, server)
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no %s components answered %s within %s", componentType, collector_registrar.DiscoverComponentMessageSubject, timeout)
+	}
+
+	return servers, nil
+}
+
+type byIndex []collector_registrar.AnnounceComponentMessage
+
+func (a byIndex) Len() int      { return len(a) }
+func (a byIndex) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byIndex) Less(i, j int) bool {
+	if a[i].Index != a[j].Index {
+		return a[i].Index < a[j].Index
+	}
+
+	return a[i].Host < a[j].Host
+}
@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/client"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Discover", func() {
+	var fakenats *fakeyagnats.FakeYagnats
+
+	announce := func(replyTo string, announcement collector_registrar.AnnounceComponentMessage) {
+		payload, err := json.Marshal(announcement)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		err = fakenats.Publish(replyTo, payload)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		fakenats = fakeyagnats.New()
+	})
+
+	Context("when components answer", func() {
+		BeforeEach(func() {
+			fakenats.WhenPublishing(collector_registrar.DiscoverComponentMessageSubject, func(msg *yagnats.Message) error {
+				go func() {
+					announce(msg.ReplyTo, collector_registrar.AnnounceComponentMessage{Type: "runtime", Index: 1, Host: "10.0.0.2:5678", UUID: "1-b", Credentials: []string{"user", "pass"}})
+					announce(msg.ReplyTo, collector_registrar.AnnounceComponentMessage{Type: "runtime", Index: 0, Host: "10.0.0.1:5678", UUID: "0-a", Credentials: []string{"user", "pass"}})
+					announce(msg.ReplyTo, collector_registrar.AnnounceComponentMessage{Type: "router", Index: 0, Host: "10.0.0.3:8080", UUID: "0-c"})
+				}()
+				return nil
+			})
+		})
+
+		It("returns the servers of the component type by index, with their credentials", func() {
+			servers, err := Discover(fakenats, "runtime", 50*time.Millisecond)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(servers).Should(Equal([]Server{
+				{URL: "http://10.0.0.1:5678", Username: "user", Password: "pass"},
+				{URL: "http://10.0.0.2:5678", Username: "user", Password: "pass"},
+			}))
+		})
+
+		It("asks once, for answers on an inbox of its own", func() {
+			_, err := Discover(fakenats, "runtime", 50*time.Millisecond)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			published := fakenats.PublishedMessages(collector_registrar.DiscoverComponentMessageSubject)
+			Ω(published).Should(HaveLen(1))
+			Ω(published[0].ReplyTo).Should(ContainSubstring("runtime-metrics."))
+		})
+	})
+
+	Context("when nothing answers", func() {
+		It("returns an error", func() {
+			_, err := Discover(fakenats, "runtime", 10*time.Millisecond)
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("no runtime components answered"))
+		})
+	})
+})
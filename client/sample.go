@@ -0,0 +1,86 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+)
+
+// Sample is a single metric of a scrape. Histograms are sampled by their
+// count, with their sum alongside.
+type Sample struct {
+	Context string
+	Name    string
+	Tags    string
+
+	Value     float64
+	Histogram bool
+	Sum       float64
+}
+
+// Key identifies the sample across scrapes
+func (s Sample) Key() string {
+	return s.Context + "." + s.Name + "{" + s.Tags + "}"
+}
+
+// Samples flattens the contexts of a scrape, keeping their order. Contexts
+// left out of the filter are skipped, unless the filter is empty.
+func Samples(varz instrumentation.VarzMessage, contexts []string) []Sample {
+	samples := []Sample{}
+	for _, context := range varz.Contexts {
+		if !included(context.Name, contexts) {
+			continue
+		}
+
+		for _, metric := range context.Metrics {
+			sample := Sample{
+				Context: context.Name,
+				Name:    metric.Name,
+				Tags:    formatTags(metric.Tags),
+			}
+
+			switch value := metric.Value.(type) {
+			case float64:
+				sample.Value = value
+			case map[string]interface{}:
+				count, _ := value["count"].(float64)
+				sum, _ := value["sum"].(float64)
+				sample.Value = count
+				sample.Sum = sum
+				sample.Histogram = true
+			default:
+				continue
+			}
+
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples
+}
+
+func included(context string, contexts []string) bool {
+	if len(contexts) == 0 {
+		return true
+	}
+
+	for _, name := range contexts {
+		if name == context {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatTags(tags map[string]interface{}) string {
+	pairs := []string{}
+	for name, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
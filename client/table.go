@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteTables writes a table per context of the scrape. When a previous
+// scrape is given, as when watching, the change since it is written
+// alongside every value.
+func WriteTables(w io.Writer, scrape Scrape, previous *Scrape, contexts []string) error {
+	fmt.Fprintf(w, "%s at %s\n", scrape.Server, scrape.At.UTC().Format("2006-01-02 15:04:05 MST"))
+
+	before := map[string]Sample{}
+	if previous != nil {
+		for _, sample := range Samples(previous.Varz, contexts) {
+			before[sample.Key()] = sample
+		}
+	}
+
+	table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	context := ""
+	for _, sample := range Samples(scrape.Varz, contexts) {
+		if sample.Context != context {
+			context = sample.Context
+			fmt.Fprintf(table, "\n%s\n", context)
+		}
+
+		fmt.Fprintf(table, "  %s\t%s\t%s", sample.Name, sample.Tags, formatValue(sample))
+		if previous != nil {
+			fmt.Fprintf(table, "\t%s", formatChange(before[sample.Key()], sample, scrape.At.Sub(previous.At)))
+		}
+		fmt.Fprint(table, "\n")
+	}
+
+	return table.Flush()
+}
+
+func formatValue(sample Sample) string {
+	if sample.Histogram {
+		return "count=" + formatNumber(sample.Value) + " sum=" + formatNumber(sample.Sum)
+	}
+
+	return formatNumber(sample.Value)
+}
+
+// formatChange is blank when nothing changed, so the changes stand out
+func formatChange(before Sample, after Sample, elapsed time.Duration) string {
+	delta := after.Value - before.Value
+	if delta == 0 {
+		return ""
+	}
+
+	change := formatDelta(delta)
+	if elapsed > 0 {
+		rate := delta / elapsed.Seconds()
+		change += " (" + formatDelta(roundRate(rate)) + "/s)"
+	}
+
+	return change
+}
+
+func formatDelta(delta float64) string {
+	if delta > 0 {
+		return "+" + formatNumber(delta)
+	}
+
+	return formatNumber(delta)
+}
+
+// roundRate keeps rates to 4 significant figures
+func roundRate(number float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(number, 'g', 4, 64), 64)
+	return rounded
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
@@ -0,0 +1,60 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func scrapeAt(at time.Time, body string) Scrape {
+	scrape := Scrape{Server: "http://10.0.0.1:5678", At: at}
+	err := json.Unmarshal([]byte(body), &scrape.Varz)
+	Ω(err).ShouldNot(HaveOccurred())
+	return scrape
+}
+
+var _ = Describe("Tables", func() {
+	var (
+		now    time.Time
+		output *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		output = &bytes.Buffer{}
+	})
+
+	It("writes a table per context", func() {
+		err := WriteTables(output, scrapeAt(now, varz), nil, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(output.String()).Should(Equal(`http://10.0.0.1:5678 at 2014-10-01 12:00:00 UTC
+
+Tasks
+  Pending  domain=cf-apps  3
+  Running                  12
+
+MetricsServer
+  CollectionDuration    count=4 sum=0.5
+`))
+	})
+
+	It("writes what changed since the previous scrape, when watching", func() {
+		previous := scrapeAt(now.Add(-2*time.Second), `{"contexts": [{"name": "Tasks", "metrics": [
+			{"name": "Pending", "value": 7, "tags": {"domain": "cf-apps"}},
+			{"name": "Running", "value": 12}
+		]}]}`)
+
+		err := WriteTables(output, scrapeAt(now, varz), &previous, []string{"Tasks"})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(output.String()).Should(ContainSubstring("Pending  domain=cf-apps  3   -4 (-2/s)\n"))
+		Ω(output.String()).Should(ContainSubstring("Running                  12  \n"))
+		Ω(output.String()).ShouldNot(ContainSubstring("MetricsServer"))
+	})
+})
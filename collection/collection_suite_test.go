@@ -0,0 +1,13 @@
+package collection_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Collection Suite")
+}
@@ -0,0 +1,140 @@
+package collection
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+// Conditional instrumentables are skipped while they are not enabled
+type Conditional interface {
+	Enabled() bool
+}
+
+type Loop struct {
+	instrumentables []instrumentation.Instrumentable
+	interval        time.Duration
+	timeProvider    timeprovider.TimeProvider
+	logger          lager.Logger
+
+	lock                *sync.RWMutex
+	latest              Snapshot
+	sequence            uint64
+	collectionStartedAt time.Time
+	subscribers         map[<-chan Snapshot]chan Snapshot
+}
+
+func New(
+	instrumentables []instrumentation.Instrumentable,
+	interval time.Duration,
+	timeProvider timeprovider.TimeProvider,
+	logger lager.Logger,
+) *Loop {
+	return &Loop{
+		instrumentables: instrumentables,
+		interval:        interval,
+		timeProvider:    timeProvider,
+		logger:          logger.Session("collection"),
+
+		lock:        &sync.RWMutex{},
+		subscribers: map[<-chan Snapshot]chan Snapshot{},
+	}
+}
+
+func (l *Loop) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	l.collect()
+
+	close(ready)
+
+	ticker := l.timeProvider.NewTickerChannel("collection", l.interval)
+
+	for {
+		select {
+		case <-ticker:
+			l.collect()
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+func (l *Loop) Latest() (Snapshot, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.latest, l.sequence > 0
+}
+
+func (l *Loop) InProgress() (time.Time, bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return l.collectionStartedAt, !l.collectionStartedAt.IsZero()
+}
+
+// Subscribe returns a channel that receives every snapshot collected from
+// now on. Collection never waits for subscribers: one that falls behind
+// only receives the latest snapshot.
+func (l *Loop) Subscribe() <-chan Snapshot {
+	snapshots := make(chan Snapshot, 1)
+
+	l.lock.Lock()
+	l.subscribers[snapshots] = snapshots
+	l.lock.Unlock()
+
+	return snapshots
+}
+
+func (l *Loop) Unsubscribe(snapshots <-chan Snapshot) {
+	l.lock.Lock()
+	delete(l.subscribers, snapshots)
+	l.lock.Unlock()
+}
+
+func (l *Loop) collect() {
+	l.lock.Lock()
+	l.collectionStartedAt = l.timeProvider.Time()
+	l.lock.Unlock()
+
+	contexts := make([]instrumentation.Context, 0, len(l.instrumentables))
+	for _, instrumentable := range l.instrumentables {
+		if conditional, ok := instrumentable.(Conditional); ok && !conditional.Enabled() {
+			continue
+		}
+
+		contexts = append(contexts, instrumentable.Emit())
+	}
+
+	l.lock.Lock()
+	l.sequence++
+	snapshot := Snapshot{
+		Sequence:  l.sequence,
+		Timestamp: l.timeProvider.Time(),
+		Contexts:  contexts,
+	}
+	l.latest = snapshot
+	l.collectionStartedAt = time.Time{}
+	for _, subscriber := range l.subscribers {
+		publish(subscriber, snapshot)
+	}
+	l.lock.Unlock()
+
+	l.logger.Debug("collected", lager.Data{"sequence": snapshot.Sequence})
+}
+
+func publish(subscriber chan Snapshot, snapshot Snapshot) {
+	select {
+	case subscriber <- snapshot:
+	default:
+		select {
+		case <-subscriber:
+		default:
+		}
+
+		subscriber <- snapshot
+	}
+}
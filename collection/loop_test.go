@@ -0,0 +1,195 @@
+package collection_test
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+type countingInstrument struct {
+	emits chan int
+	count int
+}
+
+func (i *countingInstrument) Emit() instrumentation.Context {
+	i.count++
+	i.emits <- i.count
+	return instrumentation.Context{
+		Name:    "Counting",
+		Metrics: []instrumentation.Metric{{Name: "Count", Value: i.count}},
+	}
+}
+
+type conditionalInstrument struct {
+	*countingInstrument
+	enabled bool
+}
+
+func (i *conditionalInstrument) Enabled() bool {
+	return i.enabled
+}
+
+var _ = Describe("Loop", func() {
+	var (
+		instrument   *countingInstrument
+		timeProvider *faketimeprovider.FakeTimeProvider
+		loop         *Loop
+		process      ifrit.Process
+	)
+
+	BeforeEach(func() {
+		instrument = &countingInstrument{emits: make(chan int, 10)}
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		timeProvider.ProvideFakeChannels = true
+
+		loop = New(
+			[]instrumentation.Instrumentable{instrument},
+			10*time.Second,
+			timeProvider,
+			lagertest.NewTestLogger("test"),
+		)
+	})
+
+	It("has no snapshot before it runs", func() {
+		_, ok := loop.Latest()
+		Ω(ok).Should(BeFalse())
+	})
+
+	Context("when running", func() {
+		BeforeEach(func() {
+			process = ifrit.Envoke(loop)
+		})
+
+		AfterEach(func() {
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("collects before becoming ready", func() {
+			snapshot, ok := loop.Latest()
+			Ω(ok).Should(BeTrue())
+			Ω(snapshot).Should(Equal(Snapshot{
+				Sequence:  1,
+				Timestamp: time.Unix(1000, 0),
+				Contexts: []instrumentation.Context{
+					{Name: "Counting", Metrics: []instrumentation.Metric{{Name: "Count", Value: 1}}},
+				},
+			}))
+		})
+
+		It("is not collecting between ticks", func() {
+			_, collecting := loop.InProgress()
+			Ω(collecting).Should(BeFalse())
+		})
+
+		It("collects on every tick of the interval", func() {
+			Ω(timeProvider.TickerDurationFor("collection")).Should(Equal(10 * time.Second))
+
+			timeProvider.Increment(10 * time.Second)
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+			Eventually(instrument.emits).Should(Receive(Equal(2)))
+
+			Eventually(func() uint64 {
+				snapshot, _ := loop.Latest()
+				return snapshot.Sequence
+			}).Should(Equal(uint64(2)))
+
+			snapshot, _ := loop.Latest()
+			Ω(snapshot.Timestamp).Should(Equal(time.Unix(1010, 0)))
+		})
+	})
+
+	Describe("Subscribe", func() {
+		var snapshots <-chan Snapshot
+
+		BeforeEach(func() {
+			snapshots = loop.Subscribe()
+			process = ifrit.Envoke(loop)
+		})
+
+		AfterEach(func() {
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("receives every collected snapshot", func() {
+			var snapshot Snapshot
+			Eventually(snapshots).Should(Receive(&snapshot))
+			Ω(snapshot.Sequence).Should(Equal(uint64(1)))
+
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+
+			Eventually(snapshots).Should(Receive(&snapshot))
+			Ω(snapshot.Sequence).Should(Equal(uint64(2)))
+		})
+
+		It("only keeps the latest snapshot for slow subscribers", func() {
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+
+			Eventually(func() uint64 {
+				snapshot, _ := loop.Latest()
+				return snapshot.Sequence
+			}).Should(Equal(uint64(3)))
+
+			var snapshot Snapshot
+			Ω(snapshots).Should(Receive(&snapshot))
+			Ω(snapshot.Sequence).Should(Equal(uint64(3)))
+			Ω(snapshots).ShouldNot(Receive())
+		})
+
+		It("stops receiving once unsubscribed", func() {
+			Eventually(snapshots).Should(Receive())
+
+			loop.Unsubscribe(snapshots)
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+
+			Eventually(instrument.emits).Should(Receive(Equal(2)))
+			Consistently(snapshots).ShouldNot(Receive())
+		})
+	})
+
+	Describe("conditional instrumentables", func() {
+		var conditional *conditionalInstrument
+
+		BeforeEach(func() {
+			conditional = &conditionalInstrument{
+				countingInstrument: &countingInstrument{emits: make(chan int, 10)},
+			}
+
+			loop = New(
+				[]instrumentation.Instrumentable{conditional, instrument},
+				10*time.Second,
+				timeProvider,
+				lagertest.NewTestLogger("test"),
+			)
+		})
+
+		It("skips them while they are disabled", func() {
+			process = ifrit.Envoke(loop)
+			defer func() {
+				process.Signal(syscall.SIGTERM)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+			}()
+
+			snapshot, _ := loop.Latest()
+			Ω(snapshot.Contexts).Should(HaveLen(1))
+			Ω(conditional.emits).ShouldNot(Receive())
+
+			conditional.enabled = true
+			timeProvider.TickerChannelFor("collection") <- timeProvider.Time()
+
+			Eventually(func() int {
+				snapshot, _ := loop.Latest()
+				return len(snapshot.Contexts)
+			}).Should(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,32 @@
+package collection
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+)
+
+type Source interface {
+	Latest() (Snapshot, bool)
+}
+
+type Snapshot struct {
+	Sequence  uint64                    `json:"sequence"`
+	Timestamp time.Time                 `json:"timestamp"`
+	Contexts  []instrumentation.Context `json:"contexts"`
+}
+
+func (s Snapshot) Instrumentables() []instrumentation.Instrumentable {
+	instrumentables := make([]instrumentation.Instrumentable, len(s.Contexts))
+	for i, context := range s.Contexts {
+		instrumentables[i] = collectedContext(context)
+	}
+
+	return instrumentables
+}
+
+type collectedContext instrumentation.Context
+
+func (c collectedContext) Emit() instrumentation.Context {
+	return instrumentation.Context(c)
+}
@@ -0,0 +1,24 @@
+package collection_test
+
+import (
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot", func() {
+	Describe("Instrumentables", func() {
+		It("re-emits the collected contexts", func() {
+			contexts := []instrumentation.Context{
+				{Name: "Tasks", Metrics: []instrumentation.Metric{{Name: "Pending", Value: 3}}},
+				{Name: "ServiceRegistrations"},
+			}
+
+			instrumentables := Snapshot{Contexts: contexts}.Instrumentables()
+			Ω(instrumentables).Should(HaveLen(2))
+			Ω(instrumentables[0].Emit()).Should(Equal(contexts[0]))
+			Ω(instrumentables[1].Emit()).Should(Equal(contexts[1]))
+		})
+	})
+})
@@ -0,0 +1,35 @@
+package collection
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+)
+
+// VarzHandler serves the latest snapshot of the source as the varz of the
+// named component
+func VarzHandler(name string, snapshots Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		snapshot, _ := snapshots.Latest()
+
+		message, err := instrumentation.NewVarzMessage(name, snapshot.Instrumentables())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		payload, err := json.Marshal(message)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(payload)
+	}
+}
@@ -0,0 +1,191 @@
+package dashboard
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs/shared"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/pivotal-golang/lager"
+)
+
+// SparklineWindow is how far back the sparklines go
+const SparklineWindow = time.Hour
+
+const sparklinePoints = 60
+
+// History is where the sparklines are drawn from
+type History interface {
+	Query(context string, name string, from time.Time, to time.Time, step time.Duration) []history.Series
+}
+
+// Dashboard is a single HTML page of the latest collection, the holders of
+// the locks in the store and, when history is kept, sparklines of the last
+// hour. Everything is inline so it works without access to the internet.
+type Dashboard struct {
+	name         string
+	snapshots    collection.Source
+	store        storeadapter.StoreAdapter
+	history      History
+	role         func() string
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+}
+
+// New returns a dashboard; history and role may be nil when no history is
+// kept or no leader is elected
+func New(
+	name string,
+	snapshots collection.Source,
+	store storeadapter.StoreAdapter,
+	history History,
+	role func() string,
+	timeProvider timeprovider.TimeProvider,
+	logger lager.Logger,
+) *Dashboard {
+	return &Dashboard{
+		name:         name,
+		snapshots:    snapshots,
+		store:        store,
+		history:      history,
+		role:         role,
+		timeProvider: timeProvider,
+		logger:       logger.Session("dashboard"),
+	}
+}
+
+type page struct {
+	Name        string
+	Role        string
+	Now         time.Time
+	Collected   bool
+	Sequence    uint64
+	CollectedAt time.Time
+	Sparklines  bool
+
+	Tasks                []row
+	ServiceRegistrations []row
+
+	Locks    []lock
+	LocksErr string
+}
+
+type row struct {
+	Name      string
+	Value     string
+	Sparkline template.HTML
+}
+
+type lock struct {
+	Name   string
+	Holder string
+	TTL    uint64
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	now := d.timeProvider.Time()
+	snapshot, collected := d.snapshots.Latest()
+
+	p := page{
+		Name:        d.name,
+		Now:         now,
+		Collected:   collected,
+		Sequence:    snapshot.Sequence,
+		CollectedAt: snapshot.Timestamp,
+		Sparklines:  d.history != nil,
+	}
+
+	if d.role != nil {
+		p.Role = d.role()
+	}
+
+	for _, context := range snapshot.Contexts {
+		switch context.Name {
+		case "Tasks":
+			p.Tasks = d.rows(context.Name, context.Metrics, now)
+		case "ServiceRegistrations":
+			p.ServiceRegistrations = d.rows(context.Name, context.Metrics, now)
+		}
+	}
+
+	p.Locks, p.LocksErr = d.locks()
+
+	body := &bytes.Buffer{}
+	err := pageTemplate.Execute(body, p)
+	if err != nil {
+		d.logger.Error("failed-to-render", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+func (d *Dashboard) rows(context string, metrics []instrumentation.Metric, now time.Time) []row {
+	rows := []row{}
+	for _, m := range metrics {
+		value, ok := metric.Of(m)
+		if !ok || value.Type == metric.Histogram {
+			continue
+		}
+
+		r := row{
+			Name:  m.Name,
+			Value: strconv.FormatFloat(value.Number, 'f', -1, 64),
+		}
+
+		if d.history != nil {
+			from := now.Add(-SparklineWindow)
+			series := d.history.Query(context, m.Name, from, now, SparklineWindow/sparklinePoints)
+			if len(series) > 0 {
+				r.Sparkline = sparkline(series[0].Points, from, now)
+			}
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows
+}
+
+func (d *Dashboard) locks() ([]lock, string) {
+	node, err := d.store.ListRecursively(shared.LockSchemaRoot)
+	if err == storeadapter.ErrorKeyNotFound {
+		return []lock{}, ""
+	}
+
+	if err != nil {
+		d.logger.Error("failed-to-list-locks", err)
+		return nil, err.Error()
+	}
+
+	locks := []lock{}
+	for _, child := range node.ChildNodes {
+		locks = append(locks, lock{
+			Name:   path.Base(child.Key),
+			Holder: string(child.Value),
+			TTL:    child.TTL,
+		})
+	}
+	sort.Sort(byName(locks))
+
+	return locks, ""
+}
+
+type byName []lock
+
+func (l byName) Len() int           { return len(l) }
+func (l byName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l byName) Less(i, j int) bool { return l[i].Name < l[j].Name }
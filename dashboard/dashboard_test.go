@@ -0,0 +1,209 @@
+package dashboard_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/dashboard"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/cloudfoundry/storeadapter/fakestoreadapter"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeSource struct {
+	snapshot  collection.Snapshot
+	collected bool
+}
+
+func (f *fakeSource) Latest() (collection.Snapshot, bool) {
+	return f.snapshot, f.collected
+}
+
+type fakeHistory struct {
+	queries []string
+	series  []history.Series
+}
+
+func (f *fakeHistory) Query(context string, name string, from time.Time, to time.Time, step time.Duration) []history.Series {
+	f.queries = append(f.queries, context+"."+name)
+	return f.series
+}
+
+var _ = Describe("Dashboard", func() {
+	var (
+		source       *fakeSource
+		store        *fakestoreadapter.FakeStoreAdapter
+		pastHistory  *fakeHistory
+		role         func() string
+		timeProvider *faketimeprovider.FakeTimeProvider
+		now          time.Time
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		timeProvider = faketimeprovider.New(now)
+		store = fakestoreadapter.New()
+		pastHistory = nil
+		role = nil
+
+		source = &fakeSource{
+			collected: true,
+			snapshot: collection.Snapshot{
+				Sequence:  7,
+				Timestamp: now.Add(-10 * time.Second),
+				Contexts: []instrumentation.Context{
+					{
+						Name: "Tasks",
+						Metrics: []instrumentation.Metric{
+							{Name: "Pending", Value: metric.NewGauge(3, "tasks", "")},
+							{Name: "Running", Value: metric.NewGauge(12, "tasks", "")},
+						},
+					},
+					{
+						Name: "ServiceRegistrations",
+						Metrics: []instrumentation.Metric{
+							{Name: "Executor", Value: metric.NewGauge(2, "services", "")},
+						},
+					},
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var dashboard *Dashboard
+		if pastHistory != nil {
+			dashboard = New("runtime", source, store, pastHistory, role, timeProvider, lagertest.NewTestLogger("test"))
+		} else {
+			dashboard = New("runtime", source, store, nil, role, timeProvider, lagertest.NewTestLogger("test"))
+		}
+
+		recorder = httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/dashboard", nil)
+		dashboard.ServeHTTP(recorder, request)
+	})
+
+	body := func() string {
+		return recorder.Body.String()
+	}
+
+	It("renders an HTML page", func() {
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+		Ω(recorder.HeaderMap.Get("Content-Type")).Should(Equal("text/html; charset=utf-8"))
+		Ω(body()).Should(ContainSubstring("<title>runtime metrics server</title>"))
+		Ω(body()).Should(ContainSubstring("Collection 7 at 2014-10-01 11:59:50 UTC"))
+	})
+
+	It("doesn't load any external assets", func() {
+		Ω(body()).ShouldNot(ContainSubstring("src="))
+		Ω(body()).ShouldNot(ContainSubstring("href="))
+		Ω(body()).ShouldNot(ContainSubstring("://"))
+	})
+
+	It("renders the task counts and service registrations", func() {
+		Ω(body()).Should(ContainSubstring(`<tr><td>Pending</td><td class="value">3</td>`))
+		Ω(body()).Should(ContainSubstring(`<tr><td>Running</td><td class="value">12</td>`))
+		Ω(body()).Should(ContainSubstring(`<tr><td>Executor</td><td class="value">2</td>`))
+	})
+
+	Context("when nothing has been collected", func() {
+		BeforeEach(func() {
+			source.snapshot = collection.Snapshot{}
+			source.collected = false
+		})
+
+		It("says so", func() {
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(body()).Should(ContainSubstring("Nothing has been collected yet"))
+		})
+	})
+
+	Describe("lock holders", func() {
+		Context("when locks are held", func() {
+			BeforeEach(func() {
+				err := store.SetMulti([]storeadapter.StoreNode{
+					{Key: "/v1/locks/metrics_server_lock", Value: []byte("10.0.0.1:5678"), TTL: 10},
+					{Key: "/v1/locks/converge_lock", Value: []byte("converger-<0>"), TTL: 30},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("lists them by name, escaping their holders", func() {
+				converge := strings.Index(body(), "<td>converge_lock</td><td>converger-&lt;0&gt;</td><td class=\"value\">30s</td>")
+				metricsServer := strings.Index(body(), "<td>metrics_server_lock</td><td>10.0.0.1:5678</td><td class=\"value\">10s</td>")
+
+				Ω(converge).Should(BeNumerically(">", 0))
+				Ω(metricsServer).Should(BeNumerically(">", converge))
+			})
+		})
+
+		Context("when no locks are held", func() {
+			It("says so", func() {
+				Ω(body()).Should(ContainSubstring("No locks are held"))
+			})
+		})
+
+		Context("when the locks can't be listed", func() {
+			BeforeEach(func() {
+				store.ListErrInjector = fakestoreadapter.NewFakeStoreAdapterErrorInjector("locks", errors.New("etcd is down"))
+			})
+
+			It("still renders the rest of the page", func() {
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(body()).Should(ContainSubstring("Failed to list the locks: etcd is down"))
+				Ω(body()).Should(ContainSubstring(`<td>Pending</td>`))
+			})
+		})
+	})
+
+	Context("when electing a leader", func() {
+		BeforeEach(func() {
+			role = func() string { return "standby" }
+		})
+
+		It("shows the role of the server", func() {
+			Ω(body()).Should(ContainSubstring("(standby)"))
+		})
+	})
+
+	Describe("sparklines", func() {
+		Context("when history is kept", func() {
+			BeforeEach(func() {
+				pastHistory = &fakeHistory{
+					series: []history.Series{{
+						Points: []history.Point{
+							{Timestamp: now.Add(-time.Hour), Avg: 0},
+							{Timestamp: now.Add(-30 * time.Minute), Avg: 10},
+							{Timestamp: now, Avg: 5},
+						},
+					}},
+				}
+			})
+
+			It("draws the last hour of every metric as an inline SVG", func() {
+				Ω(pastHistory.queries).Should(Equal([]string{"Tasks.Pending", "Tasks.Running", "ServiceRegistrations.Executor"}))
+				Ω(body()).Should(ContainSubstring(`<polyline points="0.0,23.0 60.0,1.0 120.0,12.0"/>`))
+			})
+		})
+
+		Context("when no history is kept", func() {
+			It("says so", func() {
+				Ω(body()).ShouldNot(ContainSubstring("<svg"))
+				Ω(body()).Should(ContainSubstring("History is not kept"))
+			})
+		})
+	})
+})
@@ -0,0 +1,50 @@
+package dashboard
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+)
+
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// sparkline draws the averages of the points as an inline SVG line between
+// from and to, scaled to the range of the values
+func sparkline(points []history.Point, from time.Time, to time.Time) template.HTML {
+	if len(points) == 0 || !to.After(from) {
+		return ""
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		low = math.Min(low, p.Avg)
+		high = math.Max(high, p.Avg)
+	}
+
+	coordinates := make([]string, len(points))
+	var x, y float64
+	for i, p := range points {
+		x = sparklineWidth * float64(p.Timestamp.Sub(from)) / float64(to.Sub(from))
+		y = sparklineHeight / 2
+		if high > low {
+			y = 1 + (sparklineHeight-2)*(high-p.Avg)/(high-low)
+		}
+
+		coordinates[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+
+	// the SVG is built from numbers only, so it is safe to inline
+	return template.HTML(fmt.Sprintf(
+		`<svg class="sparkline" width="%d" height="%d" viewBox="0 0 %d %d"><polyline points="%s"/><circle cx="%.1f" cy="%.1f" r="1.5"/></svg>`,
+		sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight,
+		strings.Join(coordinates, " "),
+		x, y,
+	))
+}
@@ -0,0 +1,52 @@
+package dashboard
+
+import "html/template"
+
+var pageTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>{{.Name}} metrics server</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.25em 1em 0.25em 0; border-bottom: 1px solid #ddd; }
+td.value { text-align: right; font-family: monospace; }
+.muted { color: #888; }
+.error { color: #b00; }
+svg.sparkline polyline { fill: none; stroke: #36c; stroke-width: 1; }
+svg.sparkline circle { fill: #36c; }
+</style>
+</head>
+<body>
+<h1>{{.Name}} metrics server{{if .Role}} <span class="muted">({{.Role}})</span>{{end}}</h1>
+{{if .Collected}}
+<p class="muted">Collection {{.Sequence}} at {{.CollectedAt.UTC.Format "2006-01-02 15:04:05 MST"}}, rendered at {{.Now.UTC.Format "2006-01-02 15:04:05 MST"}}</p>
+{{else}}
+<p class="error">Nothing has been collected yet</p>
+{{end}}
+
+<h2>Tasks</h2>
+{{with .Tasks}}{{template "table" .}}{{else}}<p class="muted">No task counts{{if $.Role}} on a standby{{end}}</p>{{end}}
+
+<h2>Service registrations</h2>
+{{with .ServiceRegistrations}}{{template "table" .}}{{else}}<p class="muted">No service registrations{{if $.Role}} on a standby{{end}}</p>{{end}}
+
+<h2>Lock holders</h2>
+{{if .LocksErr}}<p class="error">Failed to list the locks: {{.LocksErr}}</p>
+{{else if .Locks}}<table>
+<tr><th>Lock</th><th>Holder</th><th>TTL</th></tr>
+{{range .Locks}}<tr><td>{{.Name}}</td><td>{{.Holder}}</td><td class="value">{{.TTL}}s</td></tr>
+{{end}}</table>
+{{else}}<p class="muted">No locks are held</p>
+{{end}}
+{{if not .Sparklines}}<p class="muted">History is not kept, so there are no sparklines</p>{{end}}
+</body>
+</html>
+{{define "table"}}<table>
+{{range .}}<tr><td>{{.Name}}</td><td class="value">{{.Value}}</td><td>{{.Sparkline}}</td></tr>
+{{end}}</table>
+{{end}}`))
@@ -0,0 +1,13 @@
+package detail_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDetail(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Detail Suite")
+}
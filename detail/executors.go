@@ -0,0 +1,225 @@
+package detail
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+type ExecutorBBS interface {
+	GetAllExecutors() ([]models.ExecutorPresence, error)
+	GetAllTasks() ([]models.Task, error)
+	GetAllDesiredLRPs() ([]models.DesiredLRP, error)
+	GetAllActualLRPs() ([]models.ActualLRP, error)
+}
+
+type executorsResponse struct {
+	Executors    []executorJSON `json:"executors"`
+	Idle         []string       `json:"idle"`
+	Unregistered []string       `json:"unregistered"`
+}
+
+type executorJSON struct {
+	ExecutorID string `json:"executor_id"`
+	Stack      string `json:"stack,omitempty"`
+	Registered bool   `json:"registered"`
+	Idle       bool   `json:"idle"`
+
+	// the guids of the tasks, and the actual LRPs, assigned to the executor
+	ClaimedTasks       []string          `json:"claimed_tasks"`
+	RunningTasks       []string          `json:"running_tasks"`
+	StartingActualLRPs []assignedLRPJSON `json:"starting_actual_lrps"`
+	RunningActualLRPs  []assignedLRPJSON `json:"running_actual_lrps"`
+
+	MemoryMB int `json:"memory_mb"`
+	DiskMB   int `json:"disk_mb"`
+
+	NewestAssignmentAge *float64 `json:"newest_assignment_age,omitempty"`
+
+	newestAssignment time.Time
+}
+
+type assignedLRPJSON struct {
+	ProcessGuid  string `json:"process_guid"`
+	Index        int    `json:"index"`
+	InstanceGuid string `json:"instance_guid"`
+}
+
+type executorsHandler struct {
+	bbs          ExecutorBBS
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+}
+
+// NewExecutorsHandler lists the registered executors with the claimed and
+// running tasks and the actual LRPs assigned to them, the memory and disk
+// they add up to, and the age in seconds of the newest assignment. Idle
+// executors are the ones safe to drain; work assigned to executors that
+// aren't registered is listed under executors of its own.
+func NewExecutorsHandler(bbs ExecutorBBS, timeProvider timeprovider.TimeProvider, logger lager.Logger) http.Handler {
+	return &executorsHandler{
+		bbs:          bbs,
+		timeProvider: timeProvider,
+		logger:       logger.Session("executors-handler"),
+	}
+}
+
+func (h *executorsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	presences, err := h.bbs.GetAllExecutors()
+	if err != nil {
+		h.logger.Error("failed-to-get-executors", err)
+		unavailable(w, "failed to get executors: "+err.Error())
+		return
+	}
+
+	tasks, err := h.bbs.GetAllTasks()
+	if err != nil {
+		h.logger.Error("failed-to-get-tasks", err)
+		unavailable(w, "failed to get tasks: "+err.Error())
+		return
+	}
+
+	desiredLRPs, err := h.bbs.GetAllDesiredLRPs()
+	if err != nil {
+		h.logger.Error("failed-to-get-desired-lrps", err)
+		unavailable(w, "failed to get desired lrps: "+err.Error())
+		return
+	}
+
+	actualLRPs, err := h.bbs.GetAllActualLRPs()
+	if err != nil {
+		h.logger.Error("failed-to-get-actual-lrps", err)
+		unavailable(w, "failed to get actual lrps: "+err.Error())
+		return
+	}
+
+	executors := map[string]*executorJSON{}
+	for _, presence := range presences {
+		executor := newExecutorJSON(presence.ExecutorID)
+		executor.Stack = presence.Stack
+		executor.Registered = true
+		executors[presence.ExecutorID] = executor
+	}
+
+	executorFor := func(executorID string) *executorJSON {
+		executor, found := executors[executorID]
+		if !found {
+			executor = newExecutorJSON(executorID)
+			executors[executorID] = executor
+		}
+		return executor
+	}
+
+	for _, task := range tasks {
+		if task.State != models.TaskStateClaimed && task.State != models.TaskStateRunning {
+			continue
+		}
+
+		executor := executorFor(task.ExecutorID)
+		if task.State == models.TaskStateClaimed {
+			executor.ClaimedTasks = append(executor.ClaimedTasks, task.Guid)
+		} else {
+			executor.RunningTasks = append(executor.RunningTasks, task.Guid)
+		}
+		executor.assign(task.MemoryMB, task.DiskMB, time.Unix(0, task.UpdatedAt))
+	}
+
+	desired := map[string]models.DesiredLRP{}
+	for _, desiredLRP := range desiredLRPs {
+		desired[desiredLRP.ProcessGuid] = desiredLRP
+	}
+
+	for _, actual := range actualLRPs {
+		executor := executorFor(actual.ExecutorID)
+		assigned := assignedLRPJSON{
+			ProcessGuid:  actual.ProcessGuid,
+			Index:        actual.Index,
+			InstanceGuid: actual.InstanceGuid,
+		}
+		if actual.State == models.ActualLRPStateRunning {
+			executor.RunningActualLRPs = append(executor.RunningActualLRPs, assigned)
+		} else {
+			executor.StartingActualLRPs = append(executor.StartingActualLRPs, assigned)
+		}
+
+		// an actual LRP takes up what its desired LRP asks for, which is
+		// unknown once the LRP is no longer desired
+		desiredLRP := desired[actual.ProcessGuid]
+		executor.assign(desiredLRP.MemoryMB, desiredLRP.DiskMB, time.Unix(0, actual.Since))
+	}
+
+	now := h.timeProvider.Time()
+
+	response := executorsResponse{
+		Executors:    []executorJSON{},
+		Idle:         []string{},
+		Unregistered: []string{},
+	}
+
+	for _, executor := range executors {
+		sort.Strings(executor.ClaimedTasks)
+		sort.Strings(executor.RunningTasks)
+		sort.Sort(byProcessGuidAndIndex(executor.StartingActualLRPs))
+		sort.Sort(byProcessGuidAndIndex(executor.RunningActualLRPs))
+
+		executor.Idle = len(executor.ClaimedTasks)+len(executor.RunningTasks)+len(executor.StartingActualLRPs)+len(executor.RunningActualLRPs) == 0
+		if !executor.Idle {
+			age := now.Sub(executor.newestAssignment).Seconds()
+			executor.NewestAssignmentAge = &age
+		}
+
+		response.Executors = append(response.Executors, *executor)
+	}
+	sort.Sort(byExecutorID(response.Executors))
+
+	for _, executor := range response.Executors {
+		if executor.Idle {
+			response.Idle = append(response.Idle, executor.ExecutorID)
+		}
+		if !executor.Registered {
+			response.Unregistered = append(response.Unregistered, executor.ExecutorID)
+		}
+	}
+
+	writeJSON(w, response)
+}
+
+func newExecutorJSON(executorID string) *executorJSON {
+	return &executorJSON{
+		ExecutorID:         executorID,
+		ClaimedTasks:       []string{},
+		RunningTasks:       []string{},
+		StartingActualLRPs: []assignedLRPJSON{},
+		RunningActualLRPs:  []assignedLRPJSON{},
+	}
+}
+
+func (e *executorJSON) assign(memoryMB int, diskMB int, at time.Time) {
+	e.MemoryMB += memoryMB
+	e.DiskMB += diskMB
+
+	if at.After(e.newestAssignment) {
+		e.newestAssignment = at
+	}
+}
+
+type byExecutorID []executorJSON
+
+func (e byExecutorID) Len() int           { return len(e) }
+func (e byExecutorID) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byExecutorID) Less(i, j int) bool { return e[i].ExecutorID < e[j].ExecutorID }
+
+type byProcessGuidAndIndex []assignedLRPJSON
+
+func (l byProcessGuidAndIndex) Len() int      { return len(l) }
+func (l byProcessGuidAndIndex) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byProcessGuidAndIndex) Less(i, j int) bool {
+	if l[i].ProcessGuid != l[j].ProcessGuid {
+		return l[i].ProcessGuid < l[j].ProcessGuid
+	}
+	return l[i].Index < l[j].Index
+}
@@ -0,0 +1,170 @@
+package detail_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/detail"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeExecutorBBS struct {
+	executors   []models.ExecutorPresence
+	tasks       []models.Task
+	desiredLRPs []models.DesiredLRP
+	actualLRPs  []models.ActualLRP
+
+	err error
+}
+
+func (f *fakeExecutorBBS) GetAllExecutors() ([]models.ExecutorPresence, error) {
+	return f.executors, f.err
+}
+
+func (f *fakeExecutorBBS) GetAllTasks() ([]models.Task, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeExecutorBBS) GetAllDesiredLRPs() ([]models.DesiredLRP, error) {
+	return f.desiredLRPs, nil
+}
+
+func (f *fakeExecutorBBS) GetAllActualLRPs() ([]models.ActualLRP, error) {
+	return f.actualLRPs, nil
+}
+
+var _ = Describe("Executors", func() {
+	var (
+		bbs     *fakeExecutorBBS
+		now     time.Time
+		handler http.Handler
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	get := func() map[string]interface{} {
+		recorder = httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/v1/executors", nil)
+		handler.ServeHTTP(recorder, request)
+
+		response := map[string]interface{}{}
+		if recorder.Code == http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		return response
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		bbs = &fakeExecutorBBS{
+			executors: []models.ExecutorPresence{
+				{ExecutorID: "executor-2", Stack: "lucid64"},
+				{ExecutorID: "executor-1", Stack: "lucid64"},
+			},
+			tasks: []models.Task{
+				{Guid: "claimed", State: models.TaskStateClaimed, ExecutorID: "executor-1", MemoryMB: 128, DiskMB: 512, UpdatedAt: now.Add(-time.Minute).UnixNano()},
+				{Guid: "running", State: models.TaskStateRunning, ExecutorID: "executor-1", MemoryMB: 64, DiskMB: 256, UpdatedAt: now.Add(-time.Hour).UnixNano()},
+				{Guid: "also-claimed", State: models.TaskStateClaimed, ExecutorID: "executor-1", UpdatedAt: now.Add(-time.Hour).UnixNano()},
+				{Guid: "pending", State: models.TaskStatePending, MemoryMB: 1024},
+				{Guid: "completed", State: models.TaskStateCompleted, ExecutorID: "executor-2", MemoryMB: 1024},
+				{Guid: "orphaned", State: models.TaskStateRunning, ExecutorID: "executor-3", MemoryMB: 32, UpdatedAt: now.Add(-2 * time.Minute).UnixNano()},
+			},
+			desiredLRPs: []models.DesiredLRP{
+				{ProcessGuid: "web", MemoryMB: 256, DiskMB: 1024},
+			},
+			actualLRPs: []models.ActualLRP{
+				{ProcessGuid: "web", Index: 0, InstanceGuid: "web-0", ExecutorID: "executor-1", State: models.ActualLRPStateRunning, Since: now.Add(-10 * time.Second).UnixNano()},
+				{ProcessGuid: "web", Index: 1, InstanceGuid: "web-1", ExecutorID: "executor-1", State: models.ActualLRPStateStarting, Since: now.Add(-time.Hour).UnixNano()},
+			},
+		}
+
+		handler = NewExecutorsHandler(bbs, faketimeprovider.New(now), lagertest.NewTestLogger("test"))
+	})
+
+	It("rolls up the work assigned to every executor", func() {
+		response := get()
+
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+		Ω(recorder.HeaderMap.Get("Content-Type")).Should(Equal("application/json"))
+
+		executors := response["executors"].([]interface{})
+		Ω(executors).Should(HaveLen(3))
+
+		Ω(executors[0]).Should(Equal(map[string]interface{}{
+			"executor_id":   "executor-1",
+			"stack":         "lucid64",
+			"registered":    true,
+			"idle":          false,
+			"claimed_tasks": []interface{}{"also-claimed", "claimed"},
+			"running_tasks": []interface{}{"running"},
+			"starting_actual_lrps": []interface{}{
+				map[string]interface{}{"process_guid": "web", "index": float64(1), "instance_guid": "web-1"},
+			},
+			"running_actual_lrps": []interface{}{
+				map[string]interface{}{"process_guid": "web", "index": float64(0), "instance_guid": "web-0"},
+			},
+			"memory_mb":             float64(128 + 64 + 256 + 256),
+			"disk_mb":               float64(512 + 256 + 1024 + 1024),
+			"newest_assignment_age": float64(10),
+		}))
+
+		Ω(executors[1]).Should(Equal(map[string]interface{}{
+			"executor_id":          "executor-2",
+			"stack":                "lucid64",
+			"registered":           true,
+			"idle":                 true,
+			"claimed_tasks":        []interface{}{},
+			"running_tasks":        []interface{}{},
+			"starting_actual_lrps": []interface{}{},
+			"running_actual_lrps":  []interface{}{},
+			"memory_mb":            float64(0),
+			"disk_mb":              float64(0),
+		}))
+	})
+
+	It("flags the idle executors, and the work assigned to executors that aren't registered", func() {
+		response := get()
+
+		Ω(response["idle"]).Should(Equal([]interface{}{"executor-2"}))
+		Ω(response["unregistered"]).Should(Equal([]interface{}{"executor-3"}))
+
+		orphaned := response["executors"].([]interface{})[2].(map[string]interface{})
+		Ω(orphaned["executor_id"]).Should(Equal("executor-3"))
+		Ω(orphaned["registered"]).Should(BeFalse())
+		Ω(orphaned["running_tasks"]).Should(Equal([]interface{}{"orphaned"}))
+		Ω(orphaned["newest_assignment_age"]).Should(Equal(float64(120)))
+	})
+
+	Context("when an actual LRP is no longer desired", func() {
+		BeforeEach(func() {
+			bbs.desiredLRPs = []models.DesiredLRP{}
+		})
+
+		It("still lists it, without its memory and disk", func() {
+			executor := get()["executors"].([]interface{})[0].(map[string]interface{})
+			Ω(executor["running_actual_lrps"]).Should(HaveLen(1))
+			Ω(executor["memory_mb"]).Should(Equal(float64(128 + 64)))
+		})
+	})
+
+	Context("when the BBS can't be read", func() {
+		BeforeEach(func() {
+			bbs.err = errors.New("etcd is down")
+		})
+
+		It("returns service unavailable", func() {
+			get()
+			Ω(recorder.Code).Should(Equal(http.StatusServiceUnavailable))
+			Ω(recorder.Body.String()).Should(ContainSubstring("etcd is down"))
+		})
+	})
+})
@@ -0,0 +1,45 @@
+package detail
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+func badRequest(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprint(w, message)
+}
+
+// unavailable is written when the BBS can't be read
+func unavailable(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	fmt.Fprint(w, message)
+}
+
+// list splits a comma separated query parameter
+func list(param string) []string {
+	values := []string{}
+	for _, value := range strings.Split(param, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
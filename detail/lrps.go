@@ -0,0 +1,255 @@
+package detail
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/delta_force/delta_force"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/pivotal-golang/lager"
+)
+
+type LRPBBS interface {
+	GetDesiredLRPByProcessGuid(processGuid string) (models.DesiredLRP, error)
+	GetActualLRPsByProcessGuid(processGuid string) ([]models.ActualLRP, error)
+	GetAllLRPStartAuctions() ([]models.LRPStartAuction, error)
+	GetAllLRPStopAuctions() ([]models.LRPStopAuction, error)
+	GetAllStopLRPInstances() ([]models.StopLRPInstance, error)
+}
+
+var actualLRPStates = map[models.ActualLRPState]string{
+	models.ActualLRPStateStarting: "starting",
+	models.ActualLRPStateRunning:  "running",
+}
+
+var startAuctionStates = map[models.LRPStartAuctionState]string{
+	models.LRPStartAuctionStatePending: "pending",
+	models.LRPStartAuctionStateClaimed: "claimed",
+}
+
+var stopAuctionStates = map[models.LRPStopAuctionState]string{
+	models.LRPStopAuctionStatePending: "pending",
+	models.LRPStopAuctionStateClaimed: "claimed",
+}
+
+type lrpResponse struct {
+	ProcessGuid    string             `json:"process_guid"`
+	Desired        *desiredLRPJSON    `json:"desired"`
+	Actual         []actualLRPJSON    `json:"actual"`
+	StartAuctions  []startAuctionJSON `json:"start_auctions"`
+	StopAuctions   []stopAuctionJSON  `json:"stop_auctions"`
+	StopInstances  []stopInstanceJSON `json:"stop_instances"`
+	Reconciliation reconciliationJSON `json:"reconciliation"`
+}
+
+type desiredLRPJSON struct {
+	Instances int      `json:"instances"`
+	Domain    string   `json:"domain"`
+	Stack     string   `json:"stack"`
+	Routes    []string `json:"routes"`
+	MemoryMB  int      `json:"memory_mb"`
+	DiskMB    int      `json:"disk_mb"`
+}
+
+type actualLRPJSON struct {
+	Index        int                  `json:"index"`
+	InstanceGuid string               `json:"instance_guid"`
+	ExecutorID   string               `json:"executor_id"`
+	Host         string               `json:"host"`
+	Ports        []models.PortMapping `json:"ports"`
+	State        string               `json:"state"`
+	Since        time.Time            `json:"since"`
+}
+
+type startAuctionJSON struct {
+	Index        int       `json:"index"`
+	InstanceGuid string    `json:"instance_guid"`
+	State        string    `json:"state"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type stopAuctionJSON struct {
+	Index     int       `json:"index"`
+	State     string    `json:"state"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type stopInstanceJSON struct {
+	Index        int    `json:"index"`
+	InstanceGuid string `json:"instance_guid"`
+}
+
+type reconciliationJSON struct {
+	InSync                 bool     `json:"in_sync"`
+	IndicesToStart         []int    `json:"indices_to_start"`
+	GuidsToStop            []string `json:"guids_to_stop"`
+	IndicesToStopAllButOne []int    `json:"indices_to_stop_all_but_one"`
+}
+
+type lrpsHandler struct {
+	bbs    LRPBBS
+	logger lager.Logger
+}
+
+// NewLRPsHandler describes a single LRP at /v1/lrps/<process_guid>: what is
+// desired, the actual instances by index, the auctions and stop requests
+// pending for it, and what the converger would do to reconcile them
+func NewLRPsHandler(bbs LRPBBS, logger lager.Logger) http.Handler {
+	return &lrpsHandler{
+		bbs:    bbs,
+		logger: logger.Session("lrps-handler"),
+	}
+}
+
+func (h *lrpsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	processGuid := strings.Trim(strings.TrimPrefix(req.URL.Path, "/v1/lrps"), "/")
+	if processGuid == "" || strings.Contains(processGuid, "/") {
+		badRequest(w, "expected /v1/lrps/<process_guid>")
+		return
+	}
+
+	logger := h.logger.Session("describe", lager.Data{"process-guid": processGuid})
+
+	response := lrpResponse{
+		ProcessGuid:   processGuid,
+		Actual:        []actualLRPJSON{},
+		StartAuctions: []startAuctionJSON{},
+		StopAuctions:  []stopAuctionJSON{},
+		StopInstances: []stopInstanceJSON{},
+	}
+
+	desiredInstances := 0
+	desired, err := h.bbs.GetDesiredLRPByProcessGuid(processGuid)
+	switch err {
+	case nil:
+		desiredInstances = desired.Instances
+		response.Desired = &desiredLRPJSON{
+			Instances: desired.Instances,
+			Domain:    desired.Domain,
+			Stack:     desired.Stack,
+			Routes:    desired.Routes,
+			MemoryMB:  desired.MemoryMB,
+			DiskMB:    desired.DiskMB,
+		}
+	case storeadapter.ErrorKeyNotFound:
+	default:
+		logger.Error("failed-to-get-desired-lrp", err)
+		unavailable(w, "failed to get desired lrp: "+err.Error())
+		return
+	}
+
+	actuals, err := h.bbs.GetActualLRPsByProcessGuid(processGuid)
+	if err != nil {
+		logger.Error("failed-to-get-actual-lrps", err)
+		unavailable(w, "failed to get actual lrps: "+err.Error())
+		return
+	}
+
+	startAuctions, err := h.bbs.GetAllLRPStartAuctions()
+	if err != nil {
+		logger.Error("failed-to-get-start-auctions", err)
+		unavailable(w, "failed to get start auctions: "+err.Error())
+		return
+	}
+
+	stopAuctions, err := h.bbs.GetAllLRPStopAuctions()
+	if err != nil {
+		logger.Error("failed-to-get-stop-auctions", err)
+		unavailable(w, "failed to get stop auctions: "+err.Error())
+		return
+	}
+
+	stopInstances, err := h.bbs.GetAllStopLRPInstances()
+	if err != nil {
+		logger.Error("failed-to-get-stop-instances", err)
+		unavailable(w, "failed to get stop instances: "+err.Error())
+		return
+	}
+
+	sort.Sort(actualsByIndex(actuals))
+
+	instances := delta_force.ActualInstances{}
+	for _, actual := range actuals {
+		instances = append(instances, delta_force.ActualInstance{
+			Index: actual.Index,
+			Guid:  actual.InstanceGuid,
+		})
+
+		response.Actual = append(response.Actual, actualLRPJSON{
+			Index:        actual.Index,
+			InstanceGuid: actual.InstanceGuid,
+			ExecutorID:   actual.ExecutorID,
+			Host:         actual.Host,
+			Ports:        actual.Ports,
+			State:        actualLRPStates[actual.State],
+			Since:        time.Unix(0, actual.Since).UTC(),
+		})
+	}
+
+	for _, auction := range startAuctions {
+		if auction.DesiredLRP.ProcessGuid != processGuid {
+			continue
+		}
+
+		response.StartAuctions = append(response.StartAuctions, startAuctionJSON{
+			Index:        auction.Index,
+			InstanceGuid: auction.InstanceGuid,
+			State:        startAuctionStates[auction.State],
+			UpdatedAt:    time.Unix(0, auction.UpdatedAt).UTC(),
+		})
+	}
+
+	for _, auction := range stopAuctions {
+		if auction.ProcessGuid != processGuid {
+			continue
+		}
+
+		response.StopAuctions = append(response.StopAuctions, stopAuctionJSON{
+			Index:     auction.Index,
+			State:     stopAuctionStates[auction.State],
+			UpdatedAt: time.Unix(0, auction.UpdatedAt).UTC(),
+		})
+	}
+
+	for _, stop := range stopInstances {
+		if stop.ProcessGuid != processGuid {
+			continue
+		}
+
+		response.StopInstances = append(response.StopInstances, stopInstanceJSON{
+			Index:        stop.Index,
+			InstanceGuid: stop.InstanceGuid,
+		})
+	}
+
+	if response.Desired == nil && len(response.Actual) == 0 && len(response.StartAuctions) == 0 &&
+		len(response.StopAuctions) == 0 && len(response.StopInstances) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	result := delta_force.Reconcile(desiredInstances, instances)
+	response.Reconciliation = reconciliationJSON{
+		InSync:                 result.Empty(),
+		IndicesToStart:         append([]int{}, result.IndicesToStart...),
+		GuidsToStop:            append([]string{}, result.GuidsToStop...),
+		IndicesToStopAllButOne: append([]int{}, result.IndicesToStopAllButOne...),
+	}
+
+	writeJSON(w, response)
+}
+
+type actualsByIndex []models.ActualLRP
+
+func (a actualsByIndex) Len() int      { return len(a) }
+func (a actualsByIndex) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a actualsByIndex) Less(i, j int) bool {
+	if a[i].Index != a[j].Index {
+		return a[i].Index < a[j].Index
+	}
+
+	return a[i].InstanceGuid < a[j].InstanceGuid
+}
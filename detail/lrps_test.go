@@ -0,0 +1,232 @@
+package detail_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/detail"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeLRPBBS struct {
+	desired       map[string]models.DesiredLRP
+	actuals       []models.ActualLRP
+	startAuctions []models.LRPStartAuction
+	stopAuctions  []models.LRPStopAuction
+	stopInstances []models.StopLRPInstance
+
+	err error
+}
+
+func (f *fakeLRPBBS) GetDesiredLRPByProcessGuid(processGuid string) (models.DesiredLRP, error) {
+	if f.err != nil {
+		return models.DesiredLRP{}, f.err
+	}
+
+	desired, found := f.desired[processGuid]
+	if !found {
+		return models.DesiredLRP{}, storeadapter.ErrorKeyNotFound
+	}
+
+	return desired, nil
+}
+
+func (f *fakeLRPBBS) GetActualLRPsByProcessGuid(processGuid string) ([]models.ActualLRP, error) {
+	actuals := []models.ActualLRP{}
+	for _, actual := range f.actuals {
+		if actual.ProcessGuid == processGuid {
+			actuals = append(actuals, actual)
+		}
+	}
+
+	return actuals, nil
+}
+
+func (f *fakeLRPBBS) GetAllLRPStartAuctions() ([]models.LRPStartAuction, error) {
+	return f.startAuctions, nil
+}
+
+func (f *fakeLRPBBS) GetAllLRPStopAuctions() ([]models.LRPStopAuction, error) {
+	return f.stopAuctions, nil
+}
+
+func (f *fakeLRPBBS) GetAllStopLRPInstances() ([]models.StopLRPInstance, error) {
+	return f.stopInstances, nil
+}
+
+var _ = Describe("LRPs", func() {
+	var (
+		bbs     *fakeLRPBBS
+		since   time.Time
+		handler http.Handler
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	actual := func(guid string, index int, state models.ActualLRPState) models.ActualLRP {
+		return models.ActualLRP{
+			ProcessGuid:  "web",
+			InstanceGuid: guid,
+			ExecutorID:   "executor-1",
+			Index:        index,
+			Host:         "10.0.0.1",
+			Ports:        []models.PortMapping{{ContainerPort: 8080, HostPort: 61000}},
+			State:        state,
+			Since:        since.UnixNano(),
+		}
+	}
+
+	get := func(path string) map[string]interface{} {
+		recorder = httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", path, nil)
+		handler.ServeHTTP(recorder, request)
+
+		response := map[string]interface{}{}
+		if recorder.Code == http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		return response
+	}
+
+	BeforeEach(func() {
+		since = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		bbs = &fakeLRPBBS{
+			desired: map[string]models.DesiredLRP{
+				"web": {
+					ProcessGuid: "web",
+					Domain:      "cf-apps",
+					Stack:       "lucid64",
+					Instances:   3,
+					MemoryMB:    256,
+					DiskMB:      1024,
+					Routes:      []string{"web.example.com"},
+				},
+			},
+			actuals: []models.ActualLRP{
+				actual("instance-1", 1, models.ActualLRPStateStarting),
+				actual("instance-0", 0, models.ActualLRPStateRunning),
+				actual("other", 0, models.ActualLRPStateRunning),
+			},
+			startAuctions: []models.LRPStartAuction{
+				{DesiredLRP: models.DesiredLRP{ProcessGuid: "web"}, InstanceGuid: "instance-2", Index: 2, State: models.LRPStartAuctionStatePending, UpdatedAt: since.UnixNano()},
+				{DesiredLRP: models.DesiredLRP{ProcessGuid: "worker"}, InstanceGuid: "worker-0", Index: 0, State: models.LRPStartAuctionStatePending},
+			},
+			stopAuctions: []models.LRPStopAuction{
+				{ProcessGuid: "worker", Index: 3, State: models.LRPStopAuctionStateClaimed},
+			},
+			stopInstances: []models.StopLRPInstance{
+				{ProcessGuid: "web", InstanceGuid: "instance-9", Index: 9},
+			},
+		}
+		bbs.actuals[2].ProcessGuid = "worker"
+
+		handler = NewLRPsHandler(bbs, lagertest.NewTestLogger("test"))
+	})
+
+	It("describes what is desired, the actual instances by index, and what is pending", func() {
+		response := get("/v1/lrps/web")
+
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+		Ω(recorder.HeaderMap.Get("Content-Type")).Should(Equal("application/json"))
+		Ω(response["process_guid"]).Should(Equal("web"))
+
+		Ω(response["desired"]).Should(Equal(map[string]interface{}{
+			"instances": float64(3),
+			"domain":    "cf-apps",
+			"stack":     "lucid64",
+			"routes":    []interface{}{"web.example.com"},
+			"memory_mb": float64(256),
+			"disk_mb":   float64(1024),
+		}))
+
+		Ω(response["actual"]).Should(Equal([]interface{}{
+			map[string]interface{}{
+				"index":         float64(0),
+				"instance_guid": "instance-0",
+				"executor_id":   "executor-1",
+				"host":          "10.0.0.1",
+				"ports":         []interface{}{map[string]interface{}{"container_port": float64(8080), "host_port": float64(61000)}},
+				"state":         "running",
+				"since":         "2014-10-01T12:00:00Z",
+			},
+			map[string]interface{}{
+				"index":         float64(1),
+				"instance_guid": "instance-1",
+				"executor_id":   "executor-1",
+				"host":          "10.0.0.1",
+				"ports":         []interface{}{map[string]interface{}{"container_port": float64(8080), "host_port": float64(61000)}},
+				"state":         "starting",
+				"since":         "2014-10-01T12:00:00Z",
+			},
+		}))
+
+		Ω(response["start_auctions"]).Should(Equal([]interface{}{
+			map[string]interface{}{
+				"index":         float64(2),
+				"instance_guid": "instance-2",
+				"state":         "pending",
+				"updated_at":    "2014-10-01T12:00:00Z",
+			},
+		}))
+		Ω(response["stop_auctions"]).Should(BeEmpty())
+		Ω(response["stop_instances"]).Should(Equal([]interface{}{
+			map[string]interface{}{"index": float64(9), "instance_guid": "instance-9"},
+		}))
+	})
+
+	It("reconciles the actual instances with the desired ones", func() {
+		Ω(get("/v1/lrps/web")["reconciliation"]).Should(Equal(map[string]interface{}{
+			"in_sync":                     false,
+			"indices_to_start":            []interface{}{float64(2)},
+			"guids_to_stop":               []interface{}{},
+			"indices_to_stop_all_but_one": []interface{}{},
+		}))
+	})
+
+	Context("when the lrp is no longer desired", func() {
+		It("reconciles by stopping every instance", func() {
+			response := get("/v1/lrps/worker")
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(response["desired"]).Should(BeNil())
+			Ω(response["stop_auctions"]).Should(HaveLen(1))
+			Ω(response["reconciliation"].(map[string]interface{})["guids_to_stop"]).Should(Equal([]interface{}{"other"}))
+		})
+	})
+
+	Context("when nothing is known about the lrp", func() {
+		It("returns not found", func() {
+			get("/v1/lrps/unknown")
+			Ω(recorder.Code).Should(Equal(http.StatusNotFound))
+		})
+	})
+
+	It("rejects paths without a single process guid", func() {
+		get("/v1/lrps/")
+		Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+
+		get("/v1/lrps/web/instances")
+		Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+	})
+
+	Context("when the BBS can't be read", func() {
+		BeforeEach(func() {
+			bbs.err = errors.New("etcd is down")
+		})
+
+		It("returns service unavailable", func() {
+			get("/v1/lrps/web")
+			Ω(recorder.Code).Should(Equal(http.StatusServiceUnavailable))
+			Ω(recorder.Body.String()).Should(ContainSubstring("etcd is down"))
+		})
+	})
+})
@@ -0,0 +1,291 @@
+package detail
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type TaskBBS interface {
+	GetAllTasks() ([]models.Task, error)
+}
+
+var taskStates = map[models.TaskState]string{
+	models.TaskStatePending:   "pending",
+	models.TaskStateClaimed:   "claimed",
+	models.TaskStateRunning:   "running",
+	models.TaskStateCompleted: "completed",
+	models.TaskStateResolving: "resolving",
+}
+
+// the payloads of tasks that are only returned when included explicitly,
+// since actions can carry credentials in their environment
+var taskPayloads = map[string]bool{
+	"actions":    true,
+	"result":     true,
+	"annotation": true,
+}
+
+var taskOrders = map[string]func(a, b models.Task) bool{
+	"created_at": func(a, b models.Task) bool { return a.CreatedAt < b.CreatedAt },
+	"updated_at": func(a, b models.Task) bool { return a.UpdatedAt < b.UpdatedAt },
+	"guid":       func(a, b models.Task) bool { return a.Guid < b.Guid },
+	"domain":     func(a, b models.Task) bool { return a.Domain < b.Domain },
+	"executor":   func(a, b models.Task) bool { return a.ExecutorID < b.ExecutorID },
+	"state":      func(a, b models.Task) bool { return a.State < b.State },
+}
+
+type tasksResponse struct {
+	Total      int        `json:"total"`
+	Offset     int        `json:"offset"`
+	Limit      int        `json:"limit"`
+	NextOffset *int       `json:"next_offset,omitempty"`
+	Tasks      []taskJSON `json:"tasks"`
+}
+
+type taskJSON struct {
+	Guid          string    `json:"guid"`
+	Domain        string    `json:"domain"`
+	Stack         string    `json:"stack"`
+	State         string    `json:"state"`
+	ExecutorID    string    `json:"executor_id,omitempty"`
+	MemoryMB      int       `json:"memory_mb"`
+	DiskMB        int       `json:"disk_mb"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Age           float64   `json:"age"`
+	SinceUpdate   float64   `json:"since_update"`
+	Failed        bool      `json:"failed"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+
+	Actions    []models.ExecutorAction `json:"actions,omitempty"`
+	Result     string                  `json:"result,omitempty"`
+	Annotation string                  `json:"annotation,omitempty"`
+}
+
+type tasksHandler struct {
+	bbs          TaskBBS
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+}
+
+// NewTasksHandler lists the tasks in the BBS. It answers
+//
+//	?state=pending,claimed&domain=&stack=&executor=&older_than=5m
+//	&sort=-created_at&limit=100&offset=0&include=actions,result,annotation
+//
+// where every filter is optional, ages are in seconds since the task was
+// created or last updated, and the actions, result and annotation of the
+// tasks are left out unless included.
+func NewTasksHandler(bbs TaskBBS, timeProvider timeprovider.TimeProvider, logger lager.Logger) http.Handler {
+	return &tasksHandler{
+		bbs:          bbs,
+		timeProvider: timeProvider,
+		logger:       logger.Session("tasks-handler"),
+	}
+}
+
+func (h *tasksHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	states := map[models.TaskState]bool{}
+	for _, name := range list(query.Get("state")) {
+		state, found := taskStateNamed(name)
+		if !found {
+			badRequest(w, "invalid state: "+name)
+			return
+		}
+		states[state] = true
+	}
+
+	var olderThan time.Duration
+	if query.Get("older_than") != "" {
+		var err error
+		olderThan, err = time.ParseDuration(query.Get("older_than"))
+		if err != nil {
+			badRequest(w, "invalid older_than: "+err.Error())
+			return
+		}
+	}
+
+	include := map[string]bool{}
+	for _, payload := range list(query.Get("include")) {
+		if !taskPayloads[payload] {
+			badRequest(w, "invalid include: "+payload)
+			return
+		}
+		include[payload] = true
+	}
+
+	order := query.Get("sort")
+	if order == "" {
+		order = "created_at"
+	}
+	less, found := taskOrders[strings.TrimPrefix(order, "-")]
+	if !found {
+		badRequest(w, "invalid sort: "+order)
+		return
+	}
+
+	offset, limit, ok := page(w, query.Get("offset"), query.Get("limit"))
+	if !ok {
+		return
+	}
+
+	tasks, err := h.bbs.GetAllTasks()
+	if err != nil {
+		h.logger.Error("failed-to-get-tasks", err)
+		unavailable(w, "failed to get tasks: "+err.Error())
+		return
+	}
+
+	now := h.timeProvider.Time()
+
+	matching := []models.Task{}
+	for _, task := range tasks {
+		if len(states) > 0 && !states[task.State] {
+			continue
+		}
+		if query.Get("domain") != "" && task.Domain != query.Get("domain") {
+			continue
+		}
+		if query.Get("stack") != "" && task.Stack != query.Get("stack") {
+			continue
+		}
+		if query.Get("executor") != "" && task.ExecutorID != query.Get("executor") {
+			continue
+		}
+		if olderThan > 0 && now.Sub(time.Unix(0, task.CreatedAt)) <= olderThan {
+			continue
+		}
+
+		matching = append(matching, task)
+	}
+
+	descending := strings.HasPrefix(order, "-")
+	sort.Sort(byTaskOrder{tasks: matching, less: less, descending: descending})
+
+	response := tasksResponse{
+		Total:  len(matching),
+		Offset: offset,
+		Limit:  limit,
+		Tasks:  []taskJSON{},
+	}
+
+	for i := offset; i < len(matching) && i < offset+limit; i++ {
+		response.Tasks = append(response.Tasks, newTaskJSON(matching[i], now, include))
+	}
+
+	if offset+limit < len(matching) {
+		next := offset + limit
+		response.NextOffset = &next
+	}
+
+	writeJSON(w, response)
+}
+
+func newTaskJSON(task models.Task, now time.Time, include map[string]bool) taskJSON {
+	createdAt := time.Unix(0, task.CreatedAt).UTC()
+	updatedAt := time.Unix(0, task.UpdatedAt).UTC()
+
+	t := taskJSON{
+		Guid:          task.Guid,
+		Domain:        task.Domain,
+		Stack:         task.Stack,
+		State:         taskStates[task.State],
+		ExecutorID:    task.ExecutorID,
+		MemoryMB:      task.MemoryMB,
+		DiskMB:        task.DiskMB,
+		CreatedAt:     createdAt,
+		UpdatedAt:     updatedAt,
+		Age:           now.Sub(createdAt).Seconds(),
+		SinceUpdate:   now.Sub(updatedAt).Seconds(),
+		Failed:        task.Failed,
+		FailureReason: task.FailureReason,
+	}
+
+	if include["actions"] {
+		t.Actions = task.Actions
+	}
+	if include["result"] {
+		t.Result = task.Result
+	}
+	if include["annotation"] {
+		t.Annotation = task.Annotation
+	}
+
+	return t
+}
+
+func taskStateNamed(name string) (models.TaskState, bool) {
+	for state, stateName := range taskStates {
+		if stateName == name {
+			return state, true
+		}
+	}
+
+	return models.TaskStateInvalid, false
+}
+
+// page parses the offset and limit of a page, writing a bad request if
+// either is invalid
+func page(w http.ResponseWriter, offsetParam string, limitParam string) (int, int, bool) {
+	offset := 0
+	if offsetParam != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			badRequest(w, "invalid offset: "+offsetParam)
+			return 0, 0, false
+		}
+	}
+
+	limit := DefaultLimit
+	if limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 || limit > MaxLimit {
+			badRequest(w, "invalid limit: "+limitParam+", expected 1 to "+strconv.Itoa(MaxLimit))
+			return 0, 0, false
+		}
+	}
+
+	return offset, limit, true
+}
+
+type byTaskOrder struct {
+	tasks      []models.Task
+	less       func(a, b models.Task) bool
+	descending bool
+}
+
+func (o byTaskOrder) Len() int      { return len(o.tasks) }
+func (o byTaskOrder) Swap(i, j int) { o.tasks[i], o.tasks[j] = o.tasks[j], o.tasks[i] }
+
+// Less breaks ties by guid, so pages are stable between requests
+func (o byTaskOrder) Less(i, j int) bool {
+	a, b := o.tasks[i], o.tasks[j]
+	if o.descending {
+		a, b = b, a
+	}
+
+	if o.less(a, b) {
+		return true
+	}
+	if o.less(b, a) {
+		return false
+	}
+
+	return a.Guid < b.Guid
+}
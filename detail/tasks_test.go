@@ -0,0 +1,175 @@
+package detail_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/detail"
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs/fake_bbs"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tasks", func() {
+	var (
+		bbs     *fake_bbs.FakeMetricsBBS
+		now     time.Time
+		handler http.Handler
+
+		recorder *httptest.ResponseRecorder
+	)
+
+	task := func(guid string, state models.TaskState, domain string, executor string, age time.Duration) models.Task {
+		return models.Task{
+			Guid:       guid,
+			Domain:     domain,
+			Stack:      "lucid64",
+			State:      state,
+			ExecutorID: executor,
+			CreatedAt:  now.Add(-age).UnixNano(),
+			UpdatedAt:  now.Add(-age / 2).UnixNano(),
+			Actions: []models.ExecutorAction{
+				{Action: models.RunAction{Path: "run", Env: []models.EnvironmentVariable{{Name: "PASSWORD", Value: "secret"}}}},
+			},
+			Result:     "the-result",
+			Annotation: "the-annotation",
+		}
+	}
+
+	get := func(query string) map[string]interface{} {
+		recorder = httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/v1/tasks?"+query, nil)
+		handler.ServeHTTP(recorder, request)
+
+		response := map[string]interface{}{}
+		if recorder.Code == http.StatusOK {
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		return response
+	}
+
+	guids := func(response map[string]interface{}) []string {
+		guids := []string{}
+		for _, task := range response["tasks"].([]interface{}) {
+			guids = append(guids, task.(map[string]interface{})["guid"].(string))
+		}
+		return guids
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2014, 10, 1, 12, 0, 0, 0, time.UTC)
+		bbs = fake_bbs.NewFakeMetricsBBS()
+		bbs.GetAllTasksReturns.Models = []models.Task{
+			task("a", models.TaskStatePending, "cf-apps", "", 10*time.Minute),
+			task("b", models.TaskStatePending, "cf-apps", "", time.Minute),
+			task("c", models.TaskStateRunning, "cf-apps", "executor-1", 20*time.Minute),
+			task("d", models.TaskStateClaimed, "other", "executor-2", 5*time.Minute),
+			task("e", models.TaskStateCompleted, "other", "executor-1", 30*time.Minute),
+		}
+
+		handler = NewTasksHandler(bbs, faketimeprovider.New(now), lagertest.NewTestLogger("test"))
+	})
+
+	It("lists every task, oldest first, without its payloads", func() {
+		response := get("")
+
+		Ω(recorder.Code).Should(Equal(http.StatusOK))
+		Ω(recorder.HeaderMap.Get("Content-Type")).Should(Equal("application/json"))
+		Ω(response["total"]).Should(Equal(float64(5)))
+		Ω(guids(response)).Should(Equal([]string{"e", "c", "a", "d", "b"}))
+
+		Ω(response["tasks"].([]interface{})[1]).Should(Equal(map[string]interface{}{
+			"guid":         "c",
+			"domain":       "cf-apps",
+			"stack":        "lucid64",
+			"state":        "running",
+			"executor_id":  "executor-1",
+			"memory_mb":    float64(0),
+			"disk_mb":      float64(0),
+			"created_at":   "2014-10-01T11:40:00Z",
+			"updated_at":   "2014-10-01T11:50:00Z",
+			"age":          float64(1200),
+			"since_update": float64(600),
+			"failed":       false,
+		}))
+
+		Ω(recorder.Body.String()).ShouldNot(ContainSubstring("secret"))
+		Ω(recorder.Body.String()).ShouldNot(ContainSubstring("the-result"))
+		Ω(recorder.Body.String()).ShouldNot(ContainSubstring("the-annotation"))
+	})
+
+	It("includes the payloads that are asked for", func() {
+		response := get("include=actions,result")
+
+		task := response["tasks"].([]interface{})[0].(map[string]interface{})
+		Ω(task).Should(HaveKey("actions"))
+		Ω(task["result"]).Should(Equal("the-result"))
+		Ω(task).ShouldNot(HaveKey("annotation"))
+		Ω(recorder.Body.String()).Should(ContainSubstring("secret"))
+	})
+
+	It("filters by state, domain and executor", func() {
+		Ω(guids(get("state=pending"))).Should(Equal([]string{"a", "b"}))
+		Ω(guids(get("state=claimed,running"))).Should(Equal([]string{"c", "d"}))
+		Ω(guids(get("domain=other"))).Should(Equal([]string{"e", "d"}))
+		Ω(guids(get("executor=executor-1&domain=cf-apps"))).Should(Equal([]string{"c"}))
+	})
+
+	It("filters by age", func() {
+		Ω(guids(get("state=pending&older_than=5m"))).Should(Equal([]string{"a"}))
+	})
+
+	It("sorts, descending when prefixed with -", func() {
+		Ω(guids(get("sort=guid"))).Should(Equal([]string{"a", "b", "c", "d", "e"}))
+		Ω(guids(get("sort=-created_at"))).Should(Equal([]string{"b", "d", "a", "c", "e"}))
+		Ω(guids(get("sort=state"))).Should(Equal([]string{"a", "b", "d", "c", "e"}))
+	})
+
+	It("pages", func() {
+		response := get("sort=guid&limit=2")
+		Ω(guids(response)).Should(Equal([]string{"a", "b"}))
+		Ω(response["next_offset"]).Should(Equal(float64(2)))
+
+		response = get("sort=guid&limit=2&offset=4")
+		Ω(guids(response)).Should(Equal([]string{"e"}))
+		Ω(response).ShouldNot(HaveKey("next_offset"))
+		Ω(response["total"]).Should(Equal(float64(5)))
+
+		Ω(guids(get("offset=10"))).Should(BeEmpty())
+	})
+
+	It("rejects invalid queries", func() {
+		for _, query := range []string{
+			"state=stuck",
+			"older_than=a-while",
+			"sort=size",
+			"include=everything",
+			"limit=0",
+			"limit=1001",
+			"offset=-1",
+		} {
+			get(query)
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest), query)
+		}
+	})
+
+	Context("when the BBS can't be read", func() {
+		BeforeEach(func() {
+			bbs.GetAllTasksReturns.Err = errors.New("etcd is down")
+		})
+
+		It("returns service unavailable", func() {
+			get("")
+			Ω(recorder.Code).Should(Equal(http.StatusServiceUnavailable))
+			Ω(recorder.Body.String()).Should(ContainSubstring("etcd is down"))
+		})
+	})
+})
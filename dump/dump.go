@@ -0,0 +1,117 @@
+package dump
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+var ErrUnknownFormat = errors.New("expected a storeadapter tree from ListRecursively, or an etcd response to a recursive GET of /v2/keys")
+
+// Parse reads an exported keyspace. Either format may be given:
+//
+//	{"Key": "/", "Dir": true, "ChildNodes": [{"Key": "/v1/task/guid", "Value": "<base64>"}]}
+//	{"action": "get", "node": {"key": "/", "dir": true, "nodes": [{"key": "/v1/task/guid", "value": "{...}"}]}}
+//
+// the second with or without the response around its node, as saved by
+// etcdctl or curl.
+func Parse(reader io.Reader) (*Store, error) {
+	payload, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	// the field names tell the formats apart, since decoding into either
+	// would match the other's case insensitively
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(payload, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	leaves := []storeadapter.StoreNode{}
+
+	switch {
+	case fields["Key"] != nil || fields["ChildNodes"] != nil:
+		var root storeadapter.StoreNode
+		err = json.Unmarshal(payload, &root)
+		if err != nil {
+			return nil, err
+		}
+		leaves = storeLeaves(root, leaves)
+
+	case fields["node"] != nil:
+		var response etcd.Response
+		err = json.Unmarshal(payload, &response)
+		if err != nil {
+			return nil, err
+		}
+		leaves = etcdLeaves(response.Node, leaves)
+
+	case fields["key"] != nil || fields["nodes"] != nil:
+		var root etcd.Node
+		err = json.Unmarshal(payload, &root)
+		if err != nil {
+			return nil, err
+		}
+		leaves = etcdLeaves(&root, leaves)
+
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	return NewStore(leaves), nil
+}
+
+func Load(path string) (*Store, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+func storeLeaves(node storeadapter.StoreNode, leaves []storeadapter.StoreNode) []storeadapter.StoreNode {
+	if !node.Dir {
+		return append(leaves, storeadapter.StoreNode{
+			Key:   node.Key,
+			Value: node.Value,
+			TTL:   node.TTL,
+			Index: node.Index,
+		})
+	}
+
+	for _, child := range node.ChildNodes {
+		leaves = storeLeaves(child, leaves)
+	}
+
+	return leaves
+}
+
+func etcdLeaves(node *etcd.Node, leaves []storeadapter.StoreNode) []storeadapter.StoreNode {
+	if node == nil {
+		return leaves
+	}
+
+	if !node.Dir {
+		return append(leaves, storeadapter.StoreNode{
+			Key:   node.Key,
+			Value: []byte(node.Value),
+			TTL:   uint64(node.TTL),
+			Index: node.ModifiedIndex,
+		})
+	}
+
+	for _, child := range node.Nodes {
+		leaves = etcdLeaves(child, leaves)
+	}
+
+	return leaves
+}
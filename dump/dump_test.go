@@ -0,0 +1,126 @@
+package dump_test
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/dump"
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parse", func() {
+	var task models.Task
+
+	BeforeEach(func() {
+		task = models.Task{
+			Guid:  "task-guid",
+			Stack: "lucid64",
+			State: models.TaskStatePending,
+			Actions: []models.ExecutorAction{
+				{Action: models.RunAction{Path: "run"}},
+			},
+		}
+	})
+
+	itLoadsTheKeyspace := func(dump func() string) {
+		It("loads the leaves of the keyspace", func() {
+			store, err := Parse(strings.NewReader(dump()))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(store.Keys()).Should(Equal(2))
+
+			node, err := store.Get("/v1/executor/executor-1")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(node.Value)).Should(Equal("lucid64"))
+			Ω(node.TTL).Should(Equal(uint64(30)))
+		})
+
+		It("reads like the store it was exported from", func() {
+			store, err := Parse(strings.NewReader(dump()))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			runtimeBBS := bbs.NewBBS(store, faketimeprovider.New(time.Now()), lagertest.NewTestLogger("test"))
+
+			tasks, err := runtimeBBS.GetAllTasks()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(tasks).Should(HaveLen(1))
+			Ω(tasks[0].Guid).Should(Equal("task-guid"))
+
+			registrations, err := runtimeBBS.GetServiceRegistrations()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(registrations.FilterByName(models.ExecutorServiceName)).Should(HaveLen(1))
+		})
+	}
+
+	Context("with a tree from ListRecursively", func() {
+		itLoadsTheKeyspace(func() string {
+			root := storeadapter.StoreNode{
+				Key: "/",
+				Dir: true,
+				ChildNodes: []storeadapter.StoreNode{
+					{
+						Key: "/v1",
+						Dir: true,
+						ChildNodes: []storeadapter.StoreNode{
+							{Key: "/v1/executor", Dir: true, ChildNodes: []storeadapter.StoreNode{
+								{Key: "/v1/executor/executor-1", Value: []byte("lucid64"), TTL: 30},
+							}},
+							{Key: "/v1/task", Dir: true, ChildNodes: []storeadapter.StoreNode{
+								{Key: "/v1/task/task-guid", Value: task.ToJSON()},
+							}},
+						},
+					},
+				},
+			}
+
+			payload, err := json.Marshal(root)
+			Ω(err).ShouldNot(HaveOccurred())
+			return string(payload)
+		})
+	})
+
+	etcdNode := func() string {
+		return `{"key": "/", "dir": true, "nodes": [
+			{"key": "/v1", "dir": true, "nodes": [
+				{"key": "/v1/executor", "dir": true, "nodes": [
+					{"key": "/v1/executor/executor-1", "value": "lucid64", "ttl": 30, "modifiedIndex": 7}
+				]},
+				{"key": "/v1/task", "dir": true, "nodes": [
+					{"key": "/v1/task/task-guid", "value": ` + quote(string(task.ToJSON())) + `}
+				]}
+			]}
+		]}`
+	}
+
+	Context("with an etcd response", func() {
+		itLoadsTheKeyspace(func() string {
+			return `{"action": "get", "node": ` + etcdNode() + `}`
+		})
+	})
+
+	Context("with an etcd node", func() {
+		itLoadsTheKeyspace(etcdNode)
+	})
+
+	Context("with anything else", func() {
+		It("fails", func() {
+			_, err := Parse(strings.NewReader(`{"tasks": []}`))
+			Ω(err).Should(Equal(ErrUnknownFormat))
+
+			_, err = Parse(strings.NewReader(`not json`))
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
+
+func quote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
@@ -0,0 +1,158 @@
+package dump
+
+import (
+	"errors"
+	"path"
+	"sort"
+
+	"github.com/cloudfoundry/storeadapter"
+)
+
+var ErrReadOnly = errors.New("the dump is read only")
+
+// Store is a read only storeadapter over the keys of a dump. It answers Get
+// and ListRecursively like etcd would, and fails everything that would
+// change the store or wait on it.
+type Store struct {
+	leaves   map[string]storeadapter.StoreNode
+	children map[string][]string
+}
+
+// NewStore builds the directories of a store from its leaves
+func NewStore(leaves []storeadapter.StoreNode) *Store {
+	store := &Store{
+		leaves:   map[string]storeadapter.StoreNode{},
+		children: map[string][]string{"/": []string{}},
+	}
+
+	for _, leaf := range leaves {
+		leaf.Key = path.Clean("/" + leaf.Key)
+		if _, seen := store.leaves[leaf.Key]; !seen {
+			store.addChild(leaf.Key)
+		}
+		store.leaves[leaf.Key] = leaf
+	}
+
+	for _, children := range store.children {
+		sort.Strings(children)
+	}
+
+	return store
+}
+
+func (s *Store) addChild(key string) {
+	parent := path.Dir(key)
+	if key == parent {
+		return
+	}
+
+	_, known := s.children[parent]
+	s.children[parent] = append(s.children[parent], key)
+
+	if !known {
+		s.addChild(parent)
+	}
+}
+
+// Keys is the number of leaves in the store
+func (s *Store) Keys() int {
+	return len(s.leaves)
+}
+
+func (s *Store) Get(key string) (storeadapter.StoreNode, error) {
+	key = path.Clean("/" + key)
+
+	leaf, found := s.leaves[key]
+	if found {
+		return leaf, nil
+	}
+
+	if _, isDir := s.children[key]; isDir {
+		return storeadapter.StoreNode{}, storeadapter.ErrorNodeIsDirectory
+	}
+
+	return storeadapter.StoreNode{}, storeadapter.ErrorKeyNotFound
+}
+
+func (s *Store) ListRecursively(key string) (storeadapter.StoreNode, error) {
+	key = path.Clean("/" + key)
+
+	if _, isLeaf := s.leaves[key]; isLeaf {
+		return storeadapter.StoreNode{}, storeadapter.ErrorNodeIsNotDirectory
+	}
+
+	if _, isDir := s.children[key]; !isDir {
+		return storeadapter.StoreNode{}, storeadapter.ErrorKeyNotFound
+	}
+
+	return s.dir(key), nil
+}
+
+func (s *Store) dir(key string) storeadapter.StoreNode {
+	node := storeadapter.StoreNode{
+		Key:        key,
+		Dir:        true,
+		Value:      []byte{},
+		ChildNodes: []storeadapter.StoreNode{},
+	}
+
+	for _, child := range s.children[key] {
+		if leaf, isLeaf := s.leaves[child]; isLeaf {
+			node.ChildNodes = append(node.ChildNodes, leaf)
+		} else {
+			node.ChildNodes = append(node.ChildNodes, s.dir(child))
+		}
+	}
+
+	return node
+}
+
+func (s *Store) Connect() error {
+	return nil
+}
+
+func (s *Store) Disconnect() error {
+	return nil
+}
+
+func (s *Store) Create(storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) Update(storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) CompareAndSwap(storeadapter.StoreNode, storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) CompareAndSwapByIndex(uint64, storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) SetMulti([]storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) Delete(...string) error {
+	return ErrReadOnly
+}
+
+func (s *Store) CompareAndDelete(storeadapter.StoreNode) error {
+	return ErrReadOnly
+}
+
+func (s *Store) UpdateDirTTL(string, uint64) error {
+	return ErrReadOnly
+}
+
+func (s *Store) Watch(string) (<-chan storeadapter.WatchEvent, chan<- bool, <-chan error) {
+	errs := make(chan error, 1)
+	errs <- ErrReadOnly
+	return nil, nil, errs
+}
+
+func (s *Store) MaintainNode(storeadapter.StoreNode) (<-chan bool, chan chan bool, error) {
+	return nil, nil, ErrReadOnly
+}
@@ -0,0 +1,86 @@
+package dump_test
+
+import (
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/dump"
+	"github.com/cloudfoundry/storeadapter"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Store", func() {
+	var store *Store
+
+	BeforeEach(func() {
+		store = NewStore([]storeadapter.StoreNode{
+			{Key: "/v1/actual/web/1/instance-b", Value: []byte("b")},
+			{Key: "/v1/actual/web/0/instance-a", Value: []byte("a")},
+			{Key: "v1/task/guid", Value: []byte("task")},
+		})
+	})
+
+	Describe("Get", func() {
+		It("gets leaves", func() {
+			node, err := store.Get("/v1/task/guid")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(node).Should(Equal(storeadapter.StoreNode{Key: "/v1/task/guid", Value: []byte("task")}))
+		})
+
+		It("fails on directories and missing keys", func() {
+			_, err := store.Get("/v1/task")
+			Ω(err).Should(Equal(storeadapter.ErrorNodeIsDirectory))
+
+			_, err = store.Get("/v1/task/other")
+			Ω(err).Should(Equal(storeadapter.ErrorKeyNotFound))
+		})
+	})
+
+	Describe("ListRecursively", func() {
+		It("lists directories in key order", func() {
+			node, err := store.ListRecursively("/v1/actual")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(node).Should(Equal(storeadapter.StoreNode{
+				Key:   "/v1/actual",
+				Dir:   true,
+				Value: []byte{},
+				ChildNodes: []storeadapter.StoreNode{
+					{Key: "/v1/actual/web", Dir: true, Value: []byte{}, ChildNodes: []storeadapter.StoreNode{
+						{Key: "/v1/actual/web/0", Dir: true, Value: []byte{}, ChildNodes: []storeadapter.StoreNode{
+							{Key: "/v1/actual/web/0/instance-a", Value: []byte("a")},
+						}},
+						{Key: "/v1/actual/web/1", Dir: true, Value: []byte{}, ChildNodes: []storeadapter.StoreNode{
+							{Key: "/v1/actual/web/1/instance-b", Value: []byte("b")},
+						}},
+					}},
+				},
+			}))
+		})
+
+		It("lists the root", func() {
+			node, err := store.ListRecursively("/")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(node.ChildNodes).Should(HaveLen(1))
+			Ω(node.ChildNodes[0].Key).Should(Equal("/v1"))
+		})
+
+		It("fails on leaves and missing keys", func() {
+			_, err := store.ListRecursively("/v1/task/guid")
+			Ω(err).Should(Equal(storeadapter.ErrorNodeIsNotDirectory))
+
+			_, err = store.ListRecursively("/v1/executor")
+			Ω(err).Should(Equal(storeadapter.ErrorKeyNotFound))
+		})
+	})
+
+	It("is read only", func() {
+		Ω(store.SetMulti([]storeadapter.StoreNode{{Key: "/v1/task/other"}})).Should(Equal(ErrReadOnly))
+		Ω(store.Delete("/v1/task/guid")).Should(Equal(ErrReadOnly))
+
+		_, _, err := store.MaintainNode(storeadapter.StoreNode{Key: "/v1/locks/lock"})
+		Ω(err).Should(Equal(ErrReadOnly))
+
+		_, _, errs := store.Watch("/v1")
+		Ω(errs).Should(Receive(Equal(ErrReadOnly)))
+	})
+})
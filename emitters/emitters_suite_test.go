@@ -0,0 +1,13 @@
+package emitters_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEmitters(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Emitters Suite")
+}
@@ -0,0 +1,185 @@
+package emitters
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/pivotal-golang/lager"
+)
+
+// graphiteTimeout bounds connecting to carbon and each write, so an
+// unresponsive carbon can't hold up the emitter
+const graphiteTimeout = 10 * time.Second
+
+type GraphiteConfig struct {
+	Address string
+	Prefix  string
+
+	// BufferSize is the number of lines kept while carbon is unreachable;
+	// the oldest lines are dropped first
+	BufferSize int
+
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+type Graphite struct {
+	snapshots <-chan collection.Snapshot
+	config    GraphiteConfig
+	logger    lager.Logger
+
+	conn    net.Conn
+	buffer  []string
+	backoff time.Duration
+}
+
+func NewGraphite(snapshots <-chan collection.Snapshot, config GraphiteConfig, logger lager.Logger) (*Graphite, error) {
+	if config.BufferSize <= 0 {
+		return nil, errors.New("the graphite buffer size must be positive")
+	}
+
+	if config.MinBackoff <= 0 {
+		return nil, errors.New("the graphite minimum backoff must be positive")
+	}
+
+	if config.MaxBackoff < config.MinBackoff {
+		return nil, errors.New("the graphite maximum backoff must be at least the minimum backoff")
+	}
+
+	return &Graphite{
+		snapshots: snapshots,
+		config:    config,
+		logger:    logger.Session("graphite", lager.Data{"address": config.Address}),
+
+		backoff: config.MinBackoff,
+	}, nil
+}
+
+func (e *Graphite) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	var retry <-chan time.Time
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			e.enqueue(e.lines(snapshot))
+			if retry == nil {
+				retry = e.flush()
+			}
+
+		case <-retry:
+			retry = e.flush()
+
+		case <-signals:
+			if e.conn != nil {
+				e.conn.Close()
+			}
+			return nil
+		}
+	}
+}
+
+func (e *Graphite) enqueue(lines []string) {
+	e.buffer = append(e.buffer, lines...)
+
+	if overflow := len(e.buffer) - e.config.BufferSize; overflow > 0 {
+		e.logger.Info("dropped-lines", lager.Data{"count": overflow})
+		e.buffer = e.buffer[overflow:]
+	}
+}
+
+// flush writes out the buffer, returning when to retry if carbon could not
+// be reached
+func (e *Graphite) flush() <-chan time.Time {
+	if len(e.buffer) == 0 {
+		return nil
+	}
+
+	if e.conn == nil {
+		conn, err := net.DialTimeout("tcp", e.config.Address, graphiteTimeout)
+		if err != nil {
+			e.logger.Error("failed-to-connect", err, lager.Data{"retry-in": e.backoff.String()})
+			return e.retry()
+		}
+
+		e.conn = conn
+	}
+
+	e.conn.SetWriteDeadline(time.Now().Add(graphiteTimeout))
+
+	written, err := e.conn.Write([]byte(strings.Join(e.buffer, "")))
+	if err != nil {
+		// the lines written in full were sent; a line cut short is sent
+		// again whole on the next connection
+		e.buffer = e.buffer[completeLines(e.buffer, written):]
+
+		e.logger.Error("failed-to-emit", err, lager.Data{"retry-in": e.backoff.String()})
+		e.conn.Close()
+		e.conn = nil
+		return e.retry()
+	}
+
+	e.buffer = nil
+	e.backoff = e.config.MinBackoff
+	return nil
+}
+
+// completeLines is the number of lines in the first written bytes of them
+func completeLines(lines []string, written int) int {
+	for i, line := range lines {
+		if written < len(line) {
+			return i
+		}
+		written -= len(line)
+	}
+
+	return len(lines)
+}
+
+func (e *Graphite) retry() <-chan time.Time {
+	retry := time.After(e.backoff)
+
+	e.backoff *= 2
+	if e.backoff > e.config.MaxBackoff {
+		e.backoff = e.config.MaxBackoff
+	}
+
+	return retry
+}
+
+func (e *Graphite) lines(snapshot collection.Snapshot) []string {
+	timestamp := snapshot.Timestamp.Unix()
+	lines := []string{}
+
+	for _, s := range samplesOf(snapshot) {
+		parts := []string{s.context, s.name}
+		for _, key := range sortedTagKeys(s.tags) {
+			parts = append(parts, tagValue(s.tags[key]))
+		}
+		name := path(e.config.Prefix, parts...)
+
+		if s.value.Type == metric.Histogram {
+			lines = append(lines,
+				graphiteLine(name+".count", float64(s.value.Count), timestamp),
+				graphiteLine(name+".sum", s.value.Sum, timestamp),
+			)
+			continue
+		}
+
+		lines = append(lines, graphiteLine(name, s.value.Number, timestamp))
+	}
+
+	return lines
+}
+
+func graphiteLine(name string, value float64, timestamp int64) string {
+	return fmt.Sprintf("%s %s %d\n", name, strconv.FormatFloat(value, 'f', -1, 64), timestamp)
+}
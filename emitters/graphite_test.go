@@ -0,0 +1,135 @@
+package emitters_test
+
+import (
+	"bufio"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Graphite", func() {
+	var (
+		listener  net.Listener
+		address   string
+		snapshots chan collection.Snapshot
+		process   ifrit.Process
+	)
+
+	snapshotAt := func(seconds int64, pending float64) collection.Snapshot {
+		return collection.Snapshot{
+			Timestamp: time.Unix(seconds, 0),
+			Contexts: []instrumentation.Context{
+				{
+					Name: "Tasks",
+					Metrics: []instrumentation.Metric{
+						{Name: "Pending", Value: metric.NewGauge(pending, "tasks", "Pending tasks")},
+					},
+				},
+				{
+					Name: "MetricsServer",
+					Metrics: []instrumentation.Metric{
+						{
+							Name:  "Scrapes",
+							Value: metric.NewCounter(5, "requests", "Requests"),
+							Tags:  map[string]interface{}{"endpoint": "/varz"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	accept := func() *bufio.Reader {
+		conn, err := listener.Accept()
+		Ω(err).ShouldNot(HaveOccurred())
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		return bufio.NewReader(conn)
+	}
+
+	readLine := func(reader *bufio.Reader) string {
+		line, err := reader.ReadString('\n')
+		Ω(err).ShouldNot(HaveOccurred())
+		return line
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		address = listener.Addr().String()
+
+		snapshots = make(chan collection.Snapshot)
+	})
+
+	JustBeforeEach(func() {
+		graphite, err := NewGraphite(snapshots, GraphiteConfig{
+			Address:    address,
+			Prefix:     "diego.runtime",
+			BufferSize: 2,
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 50 * time.Millisecond,
+		}, lagertest.NewTestLogger("test"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		process = ifrit.Envoke(graphite)
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		listener.Close()
+	})
+
+	It("writes a plaintext line per metric, with tag values appended to the name", func() {
+		snapshots <- snapshotAt(1000, 3)
+
+		reader := accept()
+		Ω(readLine(reader)).Should(Equal("diego.runtime.Tasks.Pending 3 1000\n"))
+		Ω(readLine(reader)).Should(Equal("diego.runtime.MetricsServer.Scrapes._varz 5 1000\n"))
+
+		snapshots <- snapshotAt(1010, 4)
+		Ω(readLine(reader)).Should(Equal("diego.runtime.Tasks.Pending 4 1010\n"))
+	})
+
+	It("requires a positive buffer size", func() {
+		_, err := NewGraphite(snapshots, GraphiteConfig{Address: address, MinBackoff: time.Second, MaxBackoff: time.Second}, lagertest.NewTestLogger("test"))
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("requires a positive minimum backoff, and a maximum backoff at least as long", func() {
+		_, err := NewGraphite(snapshots, GraphiteConfig{Address: address, BufferSize: 2, MaxBackoff: time.Second}, lagertest.NewTestLogger("test"))
+		Ω(err).Should(HaveOccurred())
+
+		_, err = NewGraphite(snapshots, GraphiteConfig{Address: address, BufferSize: 2, MinBackoff: time.Second, MaxBackoff: time.Millisecond}, lagertest.NewTestLogger("test"))
+		Ω(err).Should(HaveOccurred())
+	})
+
+	Context("when carbon is unreachable", func() {
+		BeforeEach(func() {
+			listener.Close()
+		})
+
+		It("keeps the latest lines until it reconnects", func() {
+			snapshots <- snapshotAt(1000, 1)
+			snapshots <- snapshotAt(1010, 2)
+
+			var err error
+			listener, err = net.Listen("tcp", address)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			reader := accept()
+			Ω(readLine(reader)).Should(Equal("diego.runtime.Tasks.Pending 2 1010\n"))
+			Ω(readLine(reader)).Should(Equal("diego.runtime.MetricsServer.Scrapes._varz 5 1010\n"))
+		})
+	})
+})
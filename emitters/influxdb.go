@@ -0,0 +1,212 @@
+package emitters
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/http_post"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/pivotal-golang/lager"
+)
+
+type InfluxDBConfig struct {
+	// URL of the /write endpoint, including the database, e.g.
+	// http://influxdb:8086/write?db=diego; File is used instead when empty
+	URL  string
+	File string
+
+	Deployment string
+	Index      uint
+
+	BatchSize     int
+	MaxRetries    int
+	RetryInterval time.Duration
+}
+
+type InfluxDB struct {
+	snapshots  <-chan collection.Snapshot
+	config     InfluxDBConfig
+	httpClient *http.Client
+	logger     lager.Logger
+}
+
+func NewInfluxDB(snapshots <-chan collection.Snapshot, config InfluxDBConfig, logger lager.Logger) *InfluxDB {
+	return &InfluxDB{
+		snapshots:  snapshots,
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger.Session("influxdb"),
+	}
+}
+
+func (e *InfluxDB) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	var file *os.File
+	if e.config.URL == "" {
+		var err error
+		file, err = os.OpenFile(e.config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			e.logger.Error("failed-to-open-file", err, lager.Data{"file": e.config.File})
+			return err
+		}
+		defer file.Close()
+	}
+
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			lines := e.lines(snapshot)
+
+			if file != nil {
+				_, err := io.WriteString(file, strings.Join(lines, ""))
+				if err != nil {
+					e.logger.Error("failed-to-write-file", err, lager.Data{"sequence": snapshot.Sequence})
+				}
+				continue
+			}
+
+			batchSize := e.config.BatchSize
+			if batchSize <= 0 {
+				batchSize = len(lines)
+			}
+
+			for start := 0; start < len(lines); start += batchSize {
+				end := start + batchSize
+				if end > len(lines) {
+					end = len(lines)
+				}
+
+				if !e.post(lines[start:end], signals) {
+					return nil
+				}
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// post sends a batch, retrying failed requests and 429s; it returns false
+// if it was signalled while waiting to retry
+func (e *InfluxDB) post(batch []string, signals <-chan os.Signal) bool {
+	body := []byte(strings.Join(batch, ""))
+
+	signalled, err := http_post.Retry(e.config.MaxRetries, e.config.RetryInterval, signals, func() error {
+		return http_post.Post(e.httpClient, e.config.URL, http.Header{"Content-Type": {"text/plain; charset=utf-8"}}, body)
+	})
+	if err != nil {
+		e.logger.Error("failed-to-write", err, lager.Data{"lines": len(batch)})
+	}
+
+	return !signalled
+}
+
+type influxPoint struct {
+	tags   map[string]string
+	fields map[string]float64
+}
+
+// lines makes a measurement of every context, with a point per set of tags
+func (e *InfluxDB) lines(snapshot collection.Snapshot) []string {
+	timestamp := snapshot.Timestamp.UnixNano()
+	lines := []string{}
+
+	for _, context := range snapshot.Contexts {
+		points := []*influxPoint{}
+		byTags := map[string]*influxPoint{}
+
+		for _, m := range context.Metrics {
+			value, ok := metric.Of(m)
+			if !ok {
+				continue
+			}
+
+			tags := map[string]string{
+				"deployment": e.config.Deployment,
+				"index":      strconv.FormatUint(uint64(e.config.Index), 10),
+			}
+			for key, tag := range m.Tags {
+				tags[key] = tagValue(tag)
+			}
+
+			key := renderInfluxTags(tags)
+			point, found := byTags[key]
+			if !found {
+				point = &influxPoint{tags: tags, fields: map[string]float64{}}
+				byTags[key] = point
+				points = append(points, point)
+			}
+
+			if value.Type == metric.Histogram {
+				point.fields[m.Name+"_count"] = float64(value.Count)
+				point.fields[m.Name+"_sum"] = value.Sum
+			} else {
+				point.fields[m.Name] = value.Number
+			}
+		}
+
+		for _, point := range points {
+			lines = append(lines, fmt.Sprintf(
+				"%s%s %s %d\n",
+				escapeInflux(context.Name, ", "),
+				renderInfluxTags(point.tags),
+				renderInfluxFields(point.fields),
+				timestamp,
+			))
+		}
+	}
+
+	return lines
+}
+
+func renderInfluxTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key, value := range tags {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	rendered := ""
+	for _, key := range keys {
+		rendered += "," + escapeInflux(key, ",= ") + "=" + escapeInflux(tags[key], ",= ")
+	}
+
+	return rendered
+}
+
+func renderInfluxFields(fields map[string]float64) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rendered := make([]string, len(keys))
+	for i, key := range keys {
+		rendered[i] = escapeInflux(key, ",= ") + "=" + strconv.FormatFloat(fields[key], 'f', -1, 64)
+	}
+
+	return strings.Join(rendered, ",")
+}
+
+// escapeInflux escapes backslashes first, so a value ending in one doesn't
+// escape the comma or equals sign after it
+func escapeInflux(s string, special string) string {
+	escaped := strings.Replace(s, `\`, `\\`, -1)
+	for _, r := range special {
+		escaped = strings.Replace(escaped, string(r), `\`+string(r), -1)
+	}
+
+	return escaped
+}
@@ -0,0 +1,208 @@
+package emitters_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("InfluxDB", func() {
+	var (
+		snapshots chan collection.Snapshot
+		config    InfluxDBConfig
+		process   ifrit.Process
+	)
+
+	snapshot := collection.Snapshot{
+		Timestamp: time.Unix(1000, 5),
+		Contexts: []instrumentation.Context{
+			{
+				Name: "Tasks",
+				Metrics: []instrumentation.Metric{
+					{Name: "Pending", Value: metric.NewGauge(3, "tasks", "Pending tasks")},
+					{Name: "Running", Value: metric.NewGauge(1, "tasks", "Running tasks")},
+				},
+			},
+			{
+				Name: "MetricsServer",
+				Metrics: []instrumentation.Metric{
+					{Name: "Scrapes", Value: metric.NewCounter(5, "requests", "Requests"), Tags: map[string]interface{}{"endpoint": "/varz"}},
+					{Name: "ScrapeLatencyMS", Value: metric.NewGauge(1.5, "milliseconds", "Latency"), Tags: map[string]interface{}{"endpoint": "/varz"}},
+					{Name: "Scrapes", Value: metric.NewCounter(2, "requests", "Requests"), Tags: map[string]interface{}{"endpoint": "/healthz"}},
+				},
+			},
+		},
+	}
+
+	expectedLines := "Tasks,deployment=cf-diego,index=3 Pending=3,Running=1 1000000000005\n" +
+		"MetricsServer,deployment=cf-diego,endpoint=/varz,index=3 ScrapeLatencyMS=1.5,Scrapes=5 1000000000005\n" +
+		"MetricsServer,deployment=cf-diego,endpoint=/healthz,index=3 Scrapes=2 1000000000005\n"
+
+	BeforeEach(func() {
+		snapshots = make(chan collection.Snapshot)
+		config = InfluxDBConfig{
+			Deployment:    "cf-diego",
+			Index:         3,
+			BatchSize:     100,
+			MaxRetries:    2,
+			RetryInterval: 10 * time.Millisecond,
+		}
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Envoke(NewInfluxDB(snapshots, config, lagertest.NewTestLogger("test")))
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	Context("when writing to InfluxDB over HTTP", func() {
+		var (
+			influxDB *ghttp.Server
+			bodies   chan string
+		)
+
+		receiveBody := func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			bodies <- string(body)
+		}
+
+		BeforeEach(func() {
+			influxDB = ghttp.NewServer()
+			bodies = make(chan string, 10)
+			config.URL = influxDB.URL() + "/write?db=diego"
+		})
+
+		AfterEach(func() {
+			influxDB.Close()
+		})
+
+		It("posts a measurement per context, with the tags and global tags", func() {
+			influxDB.AppendHandlers(ghttp.CombineHandlers(
+				ghttp.VerifyRequest("POST", "/write", "db=diego"),
+				receiveBody,
+				ghttp.RespondWith(http.StatusNoContent, nil),
+			))
+
+			snapshots <- snapshot
+
+			Eventually(bodies).Should(Receive(Equal(expectedLines)))
+		})
+
+		It("escapes backslashes before the other special characters", func() {
+			influxDB.AppendHandlers(ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusNoContent, nil)))
+
+			snapshots <- collection.Snapshot{
+				Timestamp: time.Unix(1000, 5),
+				Contexts: []instrumentation.Context{
+					{
+						Name: "Cells",
+						Metrics: []instrumentation.Metric{
+							{Name: `Free\`, Value: metric.NewGauge(2, "cells", "Free cells"), Tags: map[string]interface{}{"path": `C:\, D:\`}},
+						},
+					},
+				},
+			}
+
+			Eventually(bodies).Should(Receive(Equal(`Cells,deployment=cf-diego,index=3,path=C:\\\,\ D:\\ Free\\=2 1000000000005` + "\n")))
+		})
+
+		Context("with a small batch size", func() {
+			BeforeEach(func() {
+				config.BatchSize = 2
+			})
+
+			It("splits the lines across requests", func() {
+				influxDB.AppendHandlers(
+					ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusNoContent, nil)),
+					ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusNoContent, nil)),
+				)
+
+				snapshots <- snapshot
+
+				var first, second string
+				Eventually(bodies).Should(Receive(&first))
+				Eventually(bodies).Should(Receive(&second))
+				Ω(first + second).Should(Equal(expectedLines))
+			})
+		})
+
+		It("retries failed writes", func() {
+			influxDB.AppendHandlers(
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusServiceUnavailable, nil)),
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusServiceUnavailable, nil)),
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusNoContent, nil)),
+			)
+
+			snapshots <- snapshot
+
+			Eventually(bodies).Should(Receive(Equal(expectedLines)))
+			Eventually(bodies).Should(Receive(Equal(expectedLines)))
+			Eventually(bodies).Should(Receive(Equal(expectedLines)))
+			Eventually(influxDB.ReceivedRequests).Should(HaveLen(3))
+		})
+
+		It("retries writes InfluxDB asks to send later", func() {
+			influxDB.AppendHandlers(
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusTooManyRequests, nil)),
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusNoContent, nil)),
+			)
+
+			snapshots <- snapshot
+
+			Eventually(influxDB.ReceivedRequests).Should(HaveLen(2))
+		})
+
+		It("does not retry points that were rejected", func() {
+			influxDB.AppendHandlers(
+				ghttp.CombineHandlers(receiveBody, ghttp.RespondWith(http.StatusBadRequest, "unable to parse")),
+			)
+
+			snapshots <- snapshot
+
+			Eventually(bodies).Should(Receive())
+			Consistently(influxDB.ReceivedRequests).Should(HaveLen(1))
+		})
+	})
+
+	Context("when writing to a file", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "influxdb")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			config.File = filepath.Join(dir, "metrics.influx")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("appends the lines of every collection", func() {
+			snapshots <- snapshot
+			snapshots <- snapshot
+
+			Eventually(func() string {
+				contents, _ := ioutil.ReadFile(config.File)
+				return string(contents)
+			}).Should(Equal(expectedLines + expectedLines))
+		})
+	})
+})
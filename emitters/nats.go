@@ -0,0 +1,72 @@
+package emitters
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/pivotal-golang/lager"
+)
+
+// NATS publishes every snapshot on a subject, tagged with the name and index
+// of the component so subscribers can tell metrics servers apart
+type NATS struct {
+	snapshots  <-chan collection.Snapshot
+	natsClient yagnats.NATSClient
+	subject    string
+	name       string
+	index      uint
+	logger     lager.Logger
+}
+
+type natsSnapshot struct {
+	Name  string `json:"name"`
+	Index uint   `json:"index"`
+	collection.Snapshot
+}
+
+func NewNATS(
+	snapshots <-chan collection.Snapshot,
+	natsClient yagnats.NATSClient,
+	subject string,
+	name string,
+	index uint,
+	logger lager.Logger,
+) *NATS {
+	return &NATS{
+		snapshots:  snapshots,
+		natsClient: natsClient,
+		subject:    subject,
+		name:       name,
+		index:      index,
+		logger:     logger.Session("nats-publisher", lager.Data{"subject": subject}),
+	}
+}
+
+func (e *NATS) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			payload, err := json.Marshal(natsSnapshot{
+				Name:     e.name,
+				Index:    e.index,
+				Snapshot: snapshot,
+			})
+			if err != nil {
+				e.logger.Error("failed-to-marshal", err)
+				continue
+			}
+
+			err = e.natsClient.Publish(e.subject, payload)
+			if err != nil {
+				e.logger.Error("failed-to-publish", err, lager.Data{"sequence": snapshot.Sequence})
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
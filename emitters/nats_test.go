@@ -0,0 +1,88 @@
+package emitters_test
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("NATS", func() {
+	var (
+		natsClient *fakeyagnats.FakeYagnats
+		snapshots  chan collection.Snapshot
+		logger     *lagertest.TestLogger
+		process    ifrit.Process
+	)
+
+	snapshot := collection.Snapshot{
+		Sequence:  7,
+		Timestamp: time.Unix(1000, 0).UTC(),
+		Contexts: []instrumentation.Context{
+			{
+				Name: "Tasks",
+				Metrics: []instrumentation.Metric{
+					{Name: "Pending", Value: metric.NewGauge(3, "tasks", "Pending tasks")},
+				},
+			},
+		},
+	}
+
+	BeforeEach(func() {
+		natsClient = fakeyagnats.New()
+		snapshots = make(chan collection.Snapshot)
+		logger = lagertest.NewTestLogger("test")
+
+		process = ifrit.Envoke(NewNATS(snapshots, natsClient, "diego.runtime.metrics", "runtime", 3, logger))
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("publishes every snapshot with its sequence and timestamp", func() {
+		snapshots <- snapshot
+
+		Eventually(func() []yagnats.Message {
+			return natsClient.PublishedMessages("diego.runtime.metrics")
+		}).Should(HaveLen(1))
+
+		Ω(natsClient.PublishedMessages("diego.runtime.metrics")[0].Payload).Should(MatchJSON(`{
+			"name": "runtime",
+			"index": 3,
+			"sequence": 7,
+			"timestamp": "1970-01-01T00:16:40Z",
+			"contexts": [
+				{"name": "Tasks", "metrics": [{"name": "Pending", "value": 3}]}
+			]
+		}`))
+	})
+
+	Context("when publishing fails", func() {
+		BeforeEach(func() {
+			natsClient.WhenPublishing("diego.runtime.metrics", func(*yagnats.Message) error {
+				return errors.New("nats is down")
+			})
+		})
+
+		It("logs and keeps publishing", func() {
+			snapshots <- snapshot
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-publish"))
+
+			snapshots <- snapshot
+			Eventually(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-publish"))
+		})
+	})
+})
@@ -0,0 +1,308 @@
+package emitters
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/http_post"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/pivotal-golang/lager"
+)
+
+// cumulative aggregation temporality of OTLP sums and histograms
+const otlpCumulative = 2
+
+type OTLPConfig struct {
+	// Endpoint of the OTLP/HTTP metrics receiver, e.g.
+	// http://otel-collector:4318/v1/metrics
+	Endpoint string
+
+	MaxRetries    int
+	RetryInterval time.Duration
+
+	// resource attributes, filled in by the metrics server
+	Type  string
+	Index uint
+	Host  string
+
+	// StartTime is when the process started, which counters and histograms
+	// are cumulative since; filled in by the metrics server
+	StartTime time.Time
+}
+
+// OTLP exports snapshots in the JSON encoding of OTLP/HTTP
+type OTLP struct {
+	snapshots  <-chan collection.Snapshot
+	config     OTLPConfig
+	httpClient *http.Client
+	logger     lager.Logger
+}
+
+func NewOTLP(snapshots <-chan collection.Snapshot, config OTLPConfig, logger lager.Logger) *OTLP {
+	return &OTLP{
+		snapshots:  snapshots,
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		logger:     logger.Session("otlp", lager.Data{"endpoint": config.Endpoint}),
+	}
+}
+
+func (e *OTLP) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			payload, err := json.Marshal(e.request(snapshot))
+			if err != nil {
+				e.logger.Error("failed-to-marshal", err)
+				continue
+			}
+
+			signalled, err := http_post.Retry(e.config.MaxRetries, e.config.RetryInterval, signals, func() error {
+				return http_post.Post(e.httpClient, e.config.Endpoint, http.Header{"Content-Type": {"application/json"}}, payload)
+			})
+			if err != nil {
+				e.logger.Error("failed-to-export", err, lager.Data{"sequence": snapshot.Sequence})
+			}
+			if signalled {
+				return nil
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+// 64 bit integers are strings in the JSON encoding of OTLP
+type otlpNumberDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          float64         `json:"asDouble"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func (e *OTLP) request(snapshot collection.Snapshot) otlpRequest {
+	timestamp := nanos(snapshot.Timestamp)
+	startTime := nanos(e.config.StartTime)
+
+	scopes := []otlpScopeMetrics{}
+	for _, context := range snapshot.Contexts {
+		metrics := []otlpMetric{}
+		byName := map[string]int{}
+
+		for _, m := range context.Metrics {
+			value, ok := metric.Of(m)
+			if !ok {
+				continue
+			}
+
+			i, found := byName[m.Name]
+			if !found {
+				i = len(metrics)
+				byName[m.Name] = i
+				metrics = append(metrics, otlpMetric{
+					Name:        m.Name,
+					Description: value.Description,
+					Unit:        otlpUnit(value.Unit),
+				})
+			}
+
+			attributes := otlpAttributes(m.Tags)
+
+			switch value.Type {
+			case metric.Counter:
+				if metrics[i].Sum == nil {
+					metrics[i].Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+				}
+				metrics[i].Sum.DataPoints = append(metrics[i].Sum.DataPoints, otlpNumberDataPoint{
+					Attributes:        attributes,
+					StartTimeUnixNano: startTime,
+					TimeUnixNano:      timestamp,
+					AsDouble:          value.Number,
+				})
+
+			case metric.Histogram:
+				if metrics[i].Histogram == nil {
+					metrics[i].Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+				}
+				metrics[i].Histogram.DataPoints = append(metrics[i].Histogram.DataPoints, otlpHistogramPoint(value, attributes, startTime, timestamp))
+
+			default:
+				if metrics[i].Gauge == nil {
+					metrics[i].Gauge = &otlpGauge{}
+				}
+				metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, otlpNumberDataPoint{
+					Attributes:   attributes,
+					TimeUnixNano: timestamp,
+					AsDouble:     value.Number,
+				})
+			}
+		}
+
+		scopes = append(scopes, otlpScopeMetrics{
+			Scope:   otlpScope{Name: context.Name},
+			Metrics: metrics,
+		})
+	}
+
+	return otlpRequest{
+		ResourceMetrics: []otlpResourceMetrics{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpAttribute{
+						stringAttribute("type", e.config.Type),
+						intAttribute("index", int64(e.config.Index)),
+						stringAttribute("host", e.config.Host),
+					},
+				},
+				ScopeMetrics: scopes,
+			},
+		},
+	}
+}
+
+// otlpHistogramPoint converts cumulative buckets to the per bucket counts of
+// OTLP, which has one more bucket for everything above the last bound
+func otlpHistogramPoint(value metric.Value, attributes []otlpAttribute, startTime string, timestamp string) otlpHistogramDataPoint {
+	bounds := make([]float64, len(value.Buckets))
+	counts := make([]string, len(value.Buckets)+1)
+
+	previous := uint64(0)
+	for i, bucket := range value.Buckets {
+		bounds[i] = bucket.UpperBound
+		counts[i] = strconv.FormatUint(bucket.Count-previous, 10)
+		previous = bucket.Count
+	}
+	counts[len(value.Buckets)] = strconv.FormatUint(value.Count-previous, 10)
+
+	return otlpHistogramDataPoint{
+		Attributes:        attributes,
+		StartTimeUnixNano: startTime,
+		TimeUnixNano:      timestamp,
+		Count:             strconv.FormatUint(value.Count, 10),
+		Sum:               value.Sum,
+		BucketCounts:      counts,
+		ExplicitBounds:    bounds,
+	}
+}
+
+func otlpAttributes(tags map[string]interface{}) []otlpAttribute {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]otlpAttribute, len(keys))
+	for i, key := range keys {
+		attributes[i] = stringAttribute(key, tagValue(tags[key]))
+	}
+
+	return attributes
+}
+
+func stringAttribute(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) otlpAttribute {
+	encoded := strconv.FormatInt(value, 10)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &encoded}}
+}
+
+// otlpUnit converts units to UCUM, as OpenTelemetry expects
+func otlpUnit(unit string) string {
+	switch unit {
+	case "":
+		return ""
+	case "seconds":
+		return "s"
+	case "milliseconds":
+		return "ms"
+	case "bytes":
+		return "By"
+	default:
+		return "{" + unit + "}"
+	}
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,168 @@
+package emitters_test
+
+import (
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("OTLP", func() {
+	var (
+		collector *ghttp.Server
+		snapshots chan collection.Snapshot
+		process   ifrit.Process
+	)
+
+	snapshot := collection.Snapshot{
+		Timestamp: time.Unix(1000, 0),
+		Contexts: []instrumentation.Context{
+			{
+				Name: "Tasks",
+				Metrics: []instrumentation.Metric{
+					{Name: "Pending", Value: metric.NewGauge(3, "tasks", "Pending tasks")},
+				},
+			},
+			{
+				Name: "MetricsServer",
+				Metrics: []instrumentation.Metric{
+					{Name: "Scrapes", Value: metric.NewCounter(5, "requests", "Requests"), Tags: map[string]interface{}{"endpoint": "/varz"}},
+					{Name: "Scrapes", Value: metric.NewCounter(2, "requests", "Requests"), Tags: map[string]interface{}{"endpoint": "/healthz"}},
+					{
+						Name: "ScrapeDurationMS",
+						Value: metric.NewHistogram(
+							[]metric.Bucket{{UpperBound: 1, Count: 2}, {UpperBound: 5, Count: 3}},
+							7.5, 4, "milliseconds", "Durations",
+						),
+					},
+				},
+			},
+		},
+	}
+
+	expectedPayload := `{
+		"resourceMetrics": [{
+			"resource": {
+				"attributes": [
+					{"key": "type", "value": {"stringValue": "runtime"}},
+					{"key": "index", "value": {"intValue": "3"}},
+					{"key": "host", "value": {"stringValue": "10.0.0.1:5678"}}
+				]
+			},
+			"scopeMetrics": [
+				{
+					"scope": {"name": "Tasks"},
+					"metrics": [{
+						"name": "Pending",
+						"description": "Pending tasks",
+						"unit": "{tasks}",
+						"gauge": {"dataPoints": [{"timeUnixNano": "1000000000000", "asDouble": 3}]}
+					}]
+				},
+				{
+					"scope": {"name": "MetricsServer"},
+					"metrics": [
+						{
+							"name": "Scrapes",
+							"description": "Requests",
+							"unit": "{requests}",
+							"sum": {
+								"aggregationTemporality": 2,
+								"isMonotonic": true,
+								"dataPoints": [
+									{
+										"attributes": [{"key": "endpoint", "value": {"stringValue": "/varz"}}],
+										"startTimeUnixNano": "900000000000",
+										"timeUnixNano": "1000000000000",
+										"asDouble": 5
+									},
+									{
+										"attributes": [{"key": "endpoint", "value": {"stringValue": "/healthz"}}],
+										"startTimeUnixNano": "900000000000",
+										"timeUnixNano": "1000000000000",
+										"asDouble": 2
+									}
+								]
+							}
+						},
+						{
+							"name": "ScrapeDurationMS",
+							"description": "Durations",
+							"unit": "ms",
+							"histogram": {
+								"aggregationTemporality": 2,
+								"dataPoints": [{
+									"startTimeUnixNano": "900000000000",
+									"timeUnixNano": "1000000000000",
+									"count": "4",
+									"sum": 7.5,
+									"bucketCounts": ["2", "1", "1"],
+									"explicitBounds": [1, 5]
+								}]
+							}
+						}
+					]
+				}
+			]
+		}]
+	}`
+
+	BeforeEach(func() {
+		collector = ghttp.NewServer()
+		snapshots = make(chan collection.Snapshot)
+
+		process = ifrit.Envoke(NewOTLP(snapshots, OTLPConfig{
+			Endpoint:      collector.URL() + "/v1/metrics",
+			MaxRetries:    2,
+			RetryInterval: 10 * time.Millisecond,
+			Type:          "runtime",
+			Index:         3,
+			Host:          "10.0.0.1:5678",
+			StartTime:     time.Unix(900, 0),
+		}, lagertest.NewTestLogger("test")))
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		collector.Close()
+	})
+
+	It("exports each context as a scope, with the resource attributes", func() {
+		collector.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/v1/metrics"),
+			ghttp.VerifyContentType("application/json"),
+			ghttp.VerifyJSON(expectedPayload),
+			ghttp.RespondWith(http.StatusOK, "{}"),
+		))
+
+		snapshots <- snapshot
+
+		Eventually(collector.ReceivedRequests).Should(HaveLen(1))
+	})
+
+	It("retries failed exports", func() {
+		collector.AppendHandlers(
+			ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+			ghttp.RespondWith(http.StatusTooManyRequests, nil),
+			ghttp.CombineHandlers(
+				ghttp.VerifyJSON(expectedPayload),
+				ghttp.RespondWith(http.StatusOK, "{}"),
+			),
+		)
+
+		snapshots <- snapshot
+
+		Eventually(collector.ReceivedRequests).Should(HaveLen(3))
+		Consistently(collector.ReceivedRequests).Should(HaveLen(3))
+	})
+})
@@ -0,0 +1,103 @@
+package emitters
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+)
+
+// sample is a single metric flattened out of a snapshot
+type sample struct {
+	context string
+	name    string
+	tags    map[string]interface{}
+	value   metric.Value
+}
+
+func samplesOf(snapshot collection.Snapshot) []sample {
+	samples := []sample{}
+	for _, context := range snapshot.Contexts {
+		for _, m := range context.Metrics {
+			value, ok := metric.Of(m)
+			if !ok {
+				continue
+			}
+
+			samples = append(samples, sample{
+				context: context.Name,
+				name:    m.Name,
+				tags:    m.Tags,
+				value:   value,
+			})
+		}
+	}
+
+	return samples
+}
+
+// key identifies the series of a sample across snapshots
+func (s sample) key() string {
+	parts := []string{s.context, s.name}
+	for _, tag := range sortedTagKeys(s.tags) {
+		parts = append(parts, tag+"="+tagValue(s.tags[tag]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// deltas turns cumulative counters into their change since the previous
+// snapshot; a counter that went backwards was reset and counts from zero
+type deltas map[string]float64
+
+func (d deltas) delta(key string, value float64) float64 {
+	previous, seen := d[key]
+	d[key] = value
+
+	if !seen || value < previous {
+		return value
+	}
+
+	return value - previous
+}
+
+func sortedTagKeys(tags map[string]interface{}) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// sanitize replaces anything but letters, digits, underscores and dashes,
+// which would otherwise be taken as separators by the receiving end
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// path joins the prefix and the non-empty parts with dots, sanitizing the
+// parts; the prefix is configured and may contain dots of its own
+func path(prefix string, parts ...string) string {
+	sanitized := []string{}
+	if prefix != "" {
+		sanitized = append(sanitized, prefix)
+	}
+
+	for _, part := range parts {
+		if part != "" {
+			sanitized = append(sanitized, sanitize(part))
+		}
+	}
+
+	return strings.Join(sanitized, ".")
+}
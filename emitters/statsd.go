@@ -0,0 +1,159 @@
+package emitters
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/pivotal-golang/lager"
+)
+
+// keeps packets within the MTU of most networks
+const maxStatsDPacketSize = 1432
+
+type StatsD struct {
+	snapshots     <-chan collection.Snapshot
+	address       string
+	prefix        string
+	dogStatsDTags bool
+	logger        lager.Logger
+
+	deltas deltas
+}
+
+func NewStatsD(
+	snapshots <-chan collection.Snapshot,
+	address string,
+	prefix string,
+	dogStatsDTags bool,
+	logger lager.Logger,
+) *StatsD {
+	return &StatsD{
+		snapshots:     snapshots,
+		address:       address,
+		prefix:        prefix,
+		dogStatsDTags: dogStatsDTags,
+		logger:        logger.Session("statsd", lager.Data{"address": address}),
+
+		deltas: deltas{},
+	}
+}
+
+func (e *StatsD) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	conn, err := net.Dial("udp", e.address)
+	if err != nil {
+		e.logger.Error("failed-to-dial", err)
+		return err
+	}
+	defer conn.Close()
+
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-e.snapshots:
+			for _, packet := range e.packets(snapshot) {
+				_, err := conn.Write([]byte(packet))
+				if err != nil {
+					e.logger.Error("failed-to-emit", err, lager.Data{"sequence": snapshot.Sequence})
+					break
+				}
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+func (e *StatsD) packets(snapshot collection.Snapshot) []string {
+	packets := []string{}
+	packet := ""
+
+	for _, line := range e.lines(snapshot) {
+		if packet != "" && len(packet)+1+len(line) > maxStatsDPacketSize {
+			packets = append(packets, packet)
+			packet = ""
+		}
+
+		if packet == "" {
+			packet = line
+		} else {
+			packet += "\n" + line
+		}
+	}
+
+	if packet != "" {
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+func (e *StatsD) lines(snapshot collection.Snapshot) []string {
+	lines := []string{}
+
+	for _, s := range samplesOf(snapshot) {
+		name, tags := e.name(s)
+
+		switch s.value.Type {
+		case metric.Counter:
+			lines = append(lines, e.line(name, e.deltas.delta(s.key(), s.value.Number), "c", tags))
+
+		case metric.Histogram:
+			lines = append(lines,
+				e.line(name+".count", e.deltas.delta(s.key()+",count", float64(s.value.Count)), "c", tags),
+				e.line(name+".sum", e.deltas.delta(s.key()+",sum", s.value.Sum), "c", tags),
+			)
+
+		default:
+			// a signed gauge value is taken as a change to the gauge, so
+			// negative values have to be set from zero
+			if s.value.Number < 0 {
+				lines = append(lines, e.line(name, 0, "g", tags))
+			}
+			lines = append(lines, e.line(name, s.value.Number, "g", tags))
+		}
+	}
+
+	return lines
+}
+
+// name is the dotted metric name and its DogStatsD tags; without DogStatsD
+// tags the tag values become part of the name to keep series apart
+func (e *StatsD) name(s sample) (string, string) {
+	keys := sortedTagKeys(s.tags)
+
+	if e.dogStatsDTags {
+		tags := make([]string, len(keys))
+		for i, key := range keys {
+			tags[i] = sanitize(key) + ":" + sanitize(tagValue(s.tags[key]))
+		}
+
+		return path(e.prefix, s.context, s.name), strings.Join(tags, ",")
+	}
+
+	parts := []string{s.context, s.name}
+	for _, key := range keys {
+		parts = append(parts, tagValue(s.tags[key]))
+	}
+
+	return path(e.prefix, parts...), ""
+}
+
+func (e *StatsD) line(name string, value float64, statsDType string, tags string) string {
+	line := fmt.Sprintf("%s:%s|%s", name, strconv.FormatFloat(value, 'f', -1, 64), statsDType)
+	if tags != "" {
+		line += "|#" + tags
+	}
+
+	return line
+}
+
+func tagValue(value interface{}) string {
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,153 @@
+package emitters_test
+
+import (
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("StatsD", func() {
+	var (
+		listener      net.PacketConn
+		snapshots     chan collection.Snapshot
+		dogStatsDTags bool
+		process       ifrit.Process
+	)
+
+	snapshotWith := func(scrapes uint64, pending float64) collection.Snapshot {
+		return collection.Snapshot{
+			Contexts: []instrumentation.Context{
+				{
+					Name: "Tasks",
+					Metrics: []instrumentation.Metric{
+						{Name: "Pending", Value: metric.NewGauge(pending, "tasks", "Pending tasks")},
+					},
+				},
+				{
+					Name: "MetricsServer",
+					Metrics: []instrumentation.Metric{
+						{
+							Name:  "Scrapes",
+							Value: metric.NewCounter(scrapes, "requests", "Requests"),
+							Tags:  map[string]interface{}{"endpoint": "/varz"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	readPacket := func() string {
+		buffer := make([]byte, 65536)
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(buffer)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return string(buffer[:n])
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		snapshots = make(chan collection.Snapshot)
+		dogStatsDTags = false
+	})
+
+	JustBeforeEach(func() {
+		emitter := NewStatsD(snapshots, listener.LocalAddr().String(), "diego.runtime", dogStatsDTags, lagertest.NewTestLogger("test"))
+		process = ifrit.Envoke(emitter)
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		listener.Close()
+	})
+
+	It("sends gauges as gauges and counters as deltas", func() {
+		snapshots <- snapshotWith(5, 3)
+		Ω(readPacket()).Should(Equal(strings.Join([]string{
+			"diego.runtime.Tasks.Pending:3|g",
+			"diego.runtime.MetricsServer.Scrapes._varz:5|c",
+		}, "\n")))
+
+		snapshots <- snapshotWith(8, 2)
+		Ω(readPacket()).Should(Equal(strings.Join([]string{
+			"diego.runtime.Tasks.Pending:2|g",
+			"diego.runtime.MetricsServer.Scrapes._varz:3|c",
+		}, "\n")))
+	})
+
+	It("sets negative gauges from zero", func() {
+		snapshots <- snapshotWith(0, -1)
+		Ω(readPacket()).Should(Equal(strings.Join([]string{
+			"diego.runtime.Tasks.Pending:0|g",
+			"diego.runtime.Tasks.Pending:-1|g",
+			"diego.runtime.MetricsServer.Scrapes._varz:0|c",
+		}, "\n")))
+	})
+
+	It("sends histograms as counts and sums", func() {
+		snapshots <- collection.Snapshot{
+			Contexts: []instrumentation.Context{
+				{
+					Name: "MetricsServer",
+					Metrics: []instrumentation.Metric{
+						{Name: "ScrapeDurationMS", Value: metric.NewHistogram(nil, 7.5, 3, "milliseconds", "Durations")},
+					},
+				},
+			},
+		}
+
+		Ω(readPacket()).Should(Equal(strings.Join([]string{
+			"diego.runtime.MetricsServer.ScrapeDurationMS.count:3|c",
+			"diego.runtime.MetricsServer.ScrapeDurationMS.sum:7.5|c",
+		}, "\n")))
+	})
+
+	It("splits large snapshots across packets", func() {
+		metrics := []instrumentation.Metric{}
+		for i := 0; i < 100; i++ {
+			metrics = append(metrics, instrumentation.Metric{Name: "SomeRatherLongMetricName", Value: i})
+		}
+
+		snapshots <- collection.Snapshot{
+			Contexts: []instrumentation.Context{{Name: "Lots", Metrics: metrics}},
+		}
+
+		lines := 0
+		for lines < 100 {
+			packet := readPacket()
+			Ω(len(packet)).Should(BeNumerically("<=", 1432))
+			lines += len(strings.Split(packet, "\n"))
+		}
+
+		Ω(lines).Should(Equal(100))
+	})
+
+	Context("with DogStatsD tags", func() {
+		BeforeEach(func() {
+			dogStatsDTags = true
+		})
+
+		It("sends the metric tags as DogStatsD tags", func() {
+			snapshots <- snapshotWith(5, 3)
+			Ω(readPacket()).Should(Equal(strings.Join([]string{
+				"diego.runtime.Tasks.Pending:3|g",
+				"diego.runtime.MetricsServer.Scrapes:5|c|#endpoint:_varz",
+			}, "\n")))
+		})
+	})
+})
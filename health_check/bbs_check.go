@@ -0,0 +1,107 @@
+package health_check
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+type bbsRead struct {
+	lastSuccessfulAt time.Time
+	lastErr          error
+}
+
+type BBSCheck struct {
+	bbs.MetricsBBS
+
+	maxReadAge   time.Duration
+	timeProvider timeprovider.TimeProvider
+
+	lock  *sync.Mutex
+	reads map[string]bbsRead
+}
+
+func NewBBSCheck(metricsBBS bbs.MetricsBBS, maxReadAge time.Duration, timeProvider timeprovider.TimeProvider) *BBSCheck {
+	return &BBSCheck{
+		MetricsBBS:   metricsBBS,
+		maxReadAge:   maxReadAge,
+		timeProvider: timeProvider,
+
+		lock:  &sync.Mutex{},
+		reads: map[string]bbsRead{},
+	}
+}
+
+func (c *BBSCheck) Name() string {
+	return "bbs"
+}
+
+func (c *BBSCheck) Check() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.reads) == 0 {
+		return errors.New("no reads from the BBS")
+	}
+
+	operations := []string{}
+	for operation := range c.reads {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	for _, operation := range operations {
+		read := c.reads[operation]
+
+		if read.lastSuccessfulAt.IsZero() {
+			return read.failure(fmt.Sprintf("no successful %s from the BBS", operation))
+		}
+
+		age := c.timeProvider.Time().Sub(read.lastSuccessfulAt)
+		if age > c.maxReadAge {
+			return read.failure(fmt.Sprintf("no successful %s from the BBS in %s", operation, age))
+		}
+	}
+
+	return nil
+}
+
+func (c *BBSCheck) GetAllTasks() ([]models.Task, error) {
+	tasks, err := c.MetricsBBS.GetAllTasks()
+	c.record("GetAllTasks", err)
+	return tasks, err
+}
+
+func (c *BBSCheck) GetServiceRegistrations() (models.ServiceRegistrations, error) {
+	registrations, err := c.MetricsBBS.GetServiceRegistrations()
+	c.record("GetServiceRegistrations", err)
+	return registrations, err
+}
+
+func (c *BBSCheck) record(operation string, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	read := c.reads[operation]
+	if err != nil {
+		read.lastErr = err
+	} else {
+		read.lastSuccessfulAt = c.timeProvider.Time()
+	}
+
+	c.reads[operation] = read
+}
+
+func (read bbsRead) failure(message string) error {
+	if read.lastErr != nil {
+		return fmt.Errorf("%s: %s", message, read.lastErr)
+	}
+
+	return errors.New(message)
+}
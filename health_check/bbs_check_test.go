@@ -0,0 +1,90 @@
+package health_check_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs/fake_bbs"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BBSCheck", func() {
+	var fakeBBS *fake_bbs.FakeMetricsBBS
+	var timeProvider *faketimeprovider.FakeTimeProvider
+	var check *BBSCheck
+
+	BeforeEach(func() {
+		fakeBBS = fake_bbs.NewFakeMetricsBBS()
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		check = NewBBSCheck(fakeBBS, time.Minute, timeProvider)
+	})
+
+	It("passes reads through to the BBS", func() {
+		fakeBBS.GetAllTasksReturns.Models = []models.Task{{Guid: "some-guid"}}
+
+		tasks, err := check.GetAllTasks()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tasks).Should(Equal([]models.Task{{Guid: "some-guid"}}))
+	})
+
+	Context("before any reads", func() {
+		It("fails", func() {
+			Ω(check.Check()).Should(MatchError("no reads from the BBS"))
+		})
+	})
+
+	Context("when the reads succeed", func() {
+		BeforeEach(func() {
+			check.GetAllTasks()
+			check.GetServiceRegistrations()
+		})
+
+		It("passes", func() {
+			Ω(check.Check()).ShouldNot(HaveOccurred())
+		})
+
+		Context("and no read succeeds within the window", func() {
+			BeforeEach(func() {
+				timeProvider.Increment(time.Minute + time.Second)
+			})
+
+			It("fails", func() {
+				Ω(check.Check()).Should(MatchError("no successful GetAllTasks from the BBS in 1m1s"))
+			})
+		})
+
+		Context("and a later read fails", func() {
+			BeforeEach(func() {
+				fakeBBS.GetServiceRegistrationsReturns.Err = errors.New("etcd is down")
+				timeProvider.Increment(30 * time.Second)
+				check.GetServiceRegistrations()
+			})
+
+			It("passes while within the window", func() {
+				Ω(check.Check()).ShouldNot(HaveOccurred())
+			})
+
+			It("fails with the last error once outside the window", func() {
+				timeProvider.Increment(31 * time.Second)
+				check.GetAllTasks()
+
+				Ω(check.Check()).Should(MatchError("no successful GetServiceRegistrations from the BBS in 1m1s: etcd is down"))
+			})
+		})
+	})
+
+	Context("when a read has never succeeded", func() {
+		BeforeEach(func() {
+			fakeBBS.GetAllTasksReturns.Err = errors.New("etcd is down")
+			check.GetAllTasks()
+		})
+
+		It("fails with the error", func() {
+			Ω(check.Check()).Should(MatchError("no successful GetAllTasks from the BBS: etcd is down"))
+		})
+	})
+})
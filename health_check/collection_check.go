@@ -0,0 +1,44 @@
+package health_check
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+var ErrNoCollection = errors.New("no collection has completed")
+
+type collectionCheck struct {
+	source       collection.Source
+	maxAge       time.Duration
+	timeProvider timeprovider.TimeProvider
+}
+
+func NewCollectionCheck(source collection.Source, maxAge time.Duration, timeProvider timeprovider.TimeProvider) Check {
+	return &collectionCheck{
+		source:       source,
+		maxAge:       maxAge,
+		timeProvider: timeProvider,
+	}
+}
+
+func (c *collectionCheck) Name() string {
+	return "collection"
+}
+
+func (c *collectionCheck) Check() error {
+	snapshot, ok := c.source.Latest()
+	if !ok {
+		return ErrNoCollection
+	}
+
+	age := c.timeProvider.Time().Sub(snapshot.Timestamp)
+	if age > c.maxAge {
+		return fmt.Errorf("last collection completed %s ago", age)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package health_check_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeSnapshotSource struct {
+	snapshot collection.Snapshot
+	ok       bool
+}
+
+func (s *fakeSnapshotSource) Latest() (collection.Snapshot, bool) {
+	return s.snapshot, s.ok
+}
+
+var _ = Describe("CollectionCheck", func() {
+	var source *fakeSnapshotSource
+	var timeProvider *faketimeprovider.FakeTimeProvider
+	var check Check
+
+	BeforeEach(func() {
+		source = &fakeSnapshotSource{}
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		check = NewCollectionCheck(source, time.Minute, timeProvider)
+	})
+
+	It("fails before the first collection", func() {
+		Ω(check.Check()).Should(Equal(ErrNoCollection))
+	})
+
+	Context("after a collection", func() {
+		BeforeEach(func() {
+			source.snapshot = collection.Snapshot{Sequence: 1, Timestamp: timeProvider.Time()}
+			source.ok = true
+		})
+
+		It("passes", func() {
+			Ω(check.Check()).ShouldNot(HaveOccurred())
+		})
+
+		It("fails when the collection is too old", func() {
+			timeProvider.Increment(2 * time.Minute)
+			Ω(check.Check()).Should(MatchError("last collection completed 2m0s ago"))
+		})
+	})
+})
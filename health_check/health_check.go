@@ -1,11 +1,63 @@
 package health_check
 
-type HealthCheck struct{}
+import "sync"
 
-func New() *HealthCheck {
-	return &HealthCheck{}
+type Check interface {
+	Name() string
+	Check() error
+}
+
+type Result struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type HealthCheck struct {
+	checks []Check
+
+	lock       *sync.Mutex
+	lastErrors map[string]string
+}
+
+func New(checks ...Check) *HealthCheck {
+	return &HealthCheck{
+		checks: checks,
+
+		lock:       &sync.Mutex{},
+		lastErrors: map[string]string{},
+	}
 }
 
 func (h *HealthCheck) Ok() bool {
+	for _, result := range h.Results() {
+		if !result.Healthy {
+			return false
+		}
+	}
+
 	return true
 }
+
+func (h *HealthCheck) Results() []Result {
+	results := make([]Result, len(h.checks))
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, check := range h.checks {
+		result := Result{Name: check.Name(), Healthy: true}
+
+		if err := check.Check(); err != nil {
+			result.Healthy = false
+			result.Error = err.Error()
+			h.lastErrors[result.Name] = result.Error
+		}
+
+		result.LastError = h.lastErrors[result.Name]
+		results[i] = result
+	}
+
+	return results
+}
@@ -0,0 +1,85 @@
+package health_check_test
+
+import (
+	"errors"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCheck struct {
+	name string
+	err  error
+}
+
+func (c *fakeCheck) Name() string {
+	return c.name
+}
+
+func (c *fakeCheck) Check() error {
+	return c.err
+}
+
+var _ = Describe("HealthCheck", func() {
+	var first, second *fakeCheck
+	var healthCheck *HealthCheck
+
+	BeforeEach(func() {
+		first = &fakeCheck{name: "first"}
+		second = &fakeCheck{name: "second"}
+		healthCheck = New(first, second)
+	})
+
+	Context("when every check passes", func() {
+		It("is ok", func() {
+			Ω(healthCheck.Ok()).Should(BeTrue())
+		})
+
+		It("reports each check as healthy", func() {
+			Ω(healthCheck.Results()).Should(Equal([]Result{
+				{Name: "first", Healthy: true},
+				{Name: "second", Healthy: true},
+			}))
+		})
+	})
+
+	Context("when a check fails", func() {
+		BeforeEach(func() {
+			second.err = errors.New("second is broken")
+		})
+
+		It("is not ok", func() {
+			Ω(healthCheck.Ok()).Should(BeFalse())
+		})
+
+		It("reports the error", func() {
+			Ω(healthCheck.Results()).Should(Equal([]Result{
+				{Name: "first", Healthy: true},
+				{Name: "second", Healthy: false, Error: "second is broken", LastError: "second is broken"},
+			}))
+		})
+
+		Context("and then recovers", func() {
+			BeforeEach(func() {
+				healthCheck.Ok()
+				second.err = nil
+			})
+
+			It("is ok again but remembers the last error", func() {
+				Ω(healthCheck.Ok()).Should(BeTrue())
+				Ω(healthCheck.Results()[1]).Should(Equal(Result{
+					Name:      "second",
+					Healthy:   true,
+					LastError: "second is broken",
+				}))
+			})
+		})
+	})
+
+	Context("with no checks", func() {
+		It("is ok", func() {
+			Ω(New().Ok()).Should(BeTrue())
+		})
+	})
+})
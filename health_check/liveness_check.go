@@ -0,0 +1,44 @@
+package health_check
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+type CollectionProgress interface {
+	InProgress() (startedAt time.Time, collecting bool)
+}
+
+type livenessCheck struct {
+	progress     CollectionProgress
+	maxDuration  time.Duration
+	timeProvider timeprovider.TimeProvider
+}
+
+func NewLivenessCheck(progress CollectionProgress, maxDuration time.Duration, timeProvider timeprovider.TimeProvider) Check {
+	return &livenessCheck{
+		progress:     progress,
+		maxDuration:  maxDuration,
+		timeProvider: timeProvider,
+	}
+}
+
+func (c *livenessCheck) Name() string {
+	return "liveness"
+}
+
+func (c *livenessCheck) Check() error {
+	startedAt, collecting := c.progress.InProgress()
+	if !collecting {
+		return nil
+	}
+
+	duration := c.timeProvider.Time().Sub(startedAt)
+	if duration > c.maxDuration {
+		return fmt.Errorf("collection has been running for %s", duration)
+	}
+
+	return nil
+}
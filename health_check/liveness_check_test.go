@@ -0,0 +1,52 @@
+package health_check_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeCollectionProgress struct {
+	startedAt  time.Time
+	collecting bool
+}
+
+func (p *fakeCollectionProgress) InProgress() (time.Time, bool) {
+	return p.startedAt, p.collecting
+}
+
+var _ = Describe("LivenessCheck", func() {
+	var progress *fakeCollectionProgress
+	var timeProvider *faketimeprovider.FakeTimeProvider
+	var check Check
+
+	BeforeEach(func() {
+		progress = &fakeCollectionProgress{}
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		check = NewLivenessCheck(progress, time.Minute, timeProvider)
+	})
+
+	It("passes between collections", func() {
+		Ω(check.Check()).ShouldNot(HaveOccurred())
+	})
+
+	Context("while collecting", func() {
+		BeforeEach(func() {
+			progress.startedAt = timeProvider.Time()
+			progress.collecting = true
+		})
+
+		It("passes", func() {
+			timeProvider.Increment(time.Minute)
+			Ω(check.Check()).ShouldNot(HaveOccurred())
+		})
+
+		It("fails when the collection has been running for too long", func() {
+			timeProvider.Increment(2 * time.Minute)
+			Ω(check.Check()).Should(MatchError("collection has been running for 2m0s"))
+		})
+	})
+})
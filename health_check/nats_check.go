@@ -0,0 +1,29 @@
+package health_check
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/yagnats"
+)
+
+var ErrNATSNotConnected = errors.New("the NATS client is not connected")
+
+type natsCheck struct {
+	natsClient yagnats.NATSClient
+}
+
+func NewNATSCheck(natsClient yagnats.NATSClient) Check {
+	return &natsCheck{natsClient: natsClient}
+}
+
+func (c *natsCheck) Name() string {
+	return "nats"
+}
+
+func (c *natsCheck) Check() error {
+	if !c.natsClient.Ping() {
+		return ErrNATSNotConnected
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package health_check_test
+
+import (
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NATSCheck", func() {
+	var fakenats *fakeyagnats.FakeYagnats
+	var check Check
+
+	BeforeEach(func() {
+		fakenats = fakeyagnats.New()
+		check = NewNATSCheck(fakenats)
+	})
+
+	It("passes when the client is connected", func() {
+		Ω(check.Check()).ShouldNot(HaveOccurred())
+	})
+
+	It("fails when the client cannot ping NATS", func() {
+		fakenats.OnPing(func() bool { return false })
+		Ω(check.Check()).Should(Equal(ErrNATSNotConnected))
+	})
+})
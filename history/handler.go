@@ -0,0 +1,111 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type response struct {
+	Context string    `json:"context"`
+	Metric  string    `json:"metric"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Step    float64   `json:"step"`
+	Series  []Series  `json:"series"`
+}
+
+// ServeHTTP answers ?context=&metric=&from=&to=&step= queries. from and to
+// are unix seconds or RFC 3339 times and default to the whole history; step
+// is a duration, like 5m, or seconds and defaults to the resolution.
+func (h *History) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	context := query.Get("context")
+	name := query.Get("metric")
+	if context == "" || name == "" {
+		badRequest(w, "context and metric are required")
+		return
+	}
+
+	to := h.timeProvider.Time()
+	if query.Get("to") != "" {
+		var err error
+		to, err = parseTime(query.Get("to"))
+		if err != nil {
+			badRequest(w, "invalid to: "+err.Error())
+			return
+		}
+	}
+
+	from := to.Add(-h.config.Retention)
+	if query.Get("from") != "" {
+		var err error
+		from, err = parseTime(query.Get("from"))
+		if err != nil {
+			badRequest(w, "invalid from: "+err.Error())
+			return
+		}
+	}
+
+	if from.After(to) {
+		badRequest(w, "from is after to")
+		return
+	}
+
+	step := h.config.Resolution
+	if query.Get("step") != "" {
+		var err error
+		step, err = parseDuration(query.Get("step"))
+		if err != nil {
+			badRequest(w, "invalid step: "+err.Error())
+			return
+		}
+	}
+
+	if step <= 0 {
+		badRequest(w, "step must be positive")
+		return
+	}
+
+	payload, err := json.Marshal(response{
+		Context: context,
+		Metric:  name,
+		From:    from,
+		To:      to,
+		Step:    step.Seconds(),
+		Series:  h.Query(context, name, from, to, step),
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func badRequest(w http.ResponseWriter, message string) {
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprint(w, message)
+}
@@ -0,0 +1,356 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+// MaxPoints bounds Retention / Resolution, as the ring buffer of points is
+// allocated up front
+const MaxPoints = 100000
+
+type Config struct {
+	// Retention is how far back the history goes
+	Retention time.Duration
+
+	// Resolution is the interval between the points kept; a snapshot
+	// collected within the same interval as the previous one replaces it
+	Resolution time.Duration
+
+	// MaxSeries bounds the number of distinct metrics kept, together with
+	// Retention / Resolution points this bounds the memory used
+	MaxSeries int
+}
+
+// History keeps the numeric metrics of past snapshots in a ring buffer of
+// Retention / Resolution points, each holding a value for at most MaxSeries
+// series. Histograms are not kept.
+type History struct {
+	snapshots    <-chan collection.Snapshot
+	config       Config
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+
+	lock *sync.RWMutex
+
+	// points is the ring buffer; the oldest point is at start
+	points []point
+	start  int
+
+	// series are the columns of the values of every point
+	series  []series
+	columns map[string]int
+}
+
+type point struct {
+	timestamp time.Time
+
+	// values are indexed by column; NaN or a missing column means the
+	// series had no value at the time
+	values []float64
+}
+
+type series struct {
+	key     string
+	context string
+	name    string
+	tags    map[string]interface{}
+}
+
+// Validate checks the retention keeps at most MaxPoints points at the
+// resolution
+func (config Config) Validate() error {
+	if points := capacity(config); points > MaxPoints {
+		return fmt.Errorf("a retention of %s at a resolution of %s keeps %d points, more than the %d allowed", config.Retention, config.Resolution, points, MaxPoints)
+	}
+
+	return nil
+}
+
+func New(
+	snapshots <-chan collection.Snapshot,
+	config Config,
+	timeProvider timeprovider.TimeProvider,
+	logger lager.Logger,
+) (*History, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &History{
+		snapshots:    snapshots,
+		config:       config,
+		timeProvider: timeProvider,
+		logger:       logger.Session("history"),
+
+		lock:    &sync.RWMutex{},
+		points:  make([]point, 0, capacity(config)),
+		columns: map[string]int{},
+	}, nil
+}
+
+func capacity(config Config) int {
+	if config.Resolution <= 0 || config.Retention < config.Resolution {
+		return 1
+	}
+
+	return int(config.Retention / config.Resolution)
+}
+
+func (h *History) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-h.snapshots:
+			h.Record(snapshot)
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// Size is the number of points and series currently kept
+func (h *History) Size() (points int, series int) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return len(h.points), len(h.series)
+}
+
+func (h *History) Record(snapshot collection.Snapshot) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	current := h.pointFor(snapshot.Timestamp)
+
+	dropped := 0
+	for _, context := range snapshot.Contexts {
+		for _, m := range context.Metrics {
+			value, ok := metric.Of(m)
+			if !ok || value.Type == metric.Histogram {
+				continue
+			}
+
+			column, ok := h.column(context.Name, m)
+			if !ok {
+				dropped++
+				continue
+			}
+
+			for len(current.values) <= column {
+				current.values = append(current.values, math.NaN())
+			}
+			current.values[column] = value.Number
+		}
+	}
+
+	if dropped > 0 {
+		h.logger.Info("too-many-series", lager.Data{
+			"dropped":    dropped,
+			"max-series": h.config.MaxSeries,
+		})
+	}
+}
+
+// pointFor returns the point to record a snapshot taken at timestamp in,
+// evicting the oldest point once the ring buffer is full
+func (h *History) pointFor(timestamp time.Time) *point {
+	if len(h.points) > 0 {
+		newest := &h.points[h.index(len(h.points)-1)]
+		if h.slot(newest.timestamp).Equal(h.slot(timestamp)) {
+			newest.timestamp = timestamp
+			newest.values = newest.values[:0]
+			return newest
+		}
+	}
+
+	if len(h.points) < cap(h.points) {
+		h.points = append(h.points, point{timestamp: timestamp})
+		return &h.points[len(h.points)-1]
+	}
+
+	// reuse the values of the evicted point rather than allocating
+	oldest := &h.points[h.start]
+	oldest.timestamp = timestamp
+	oldest.values = oldest.values[:0]
+	h.start = (h.start + 1) % len(h.points)
+
+	return oldest
+}
+
+func (h *History) slot(timestamp time.Time) time.Time {
+	if h.config.Resolution <= 0 {
+		return timestamp
+	}
+
+	return timestamp.Truncate(h.config.Resolution)
+}
+
+// index maps the position of a point, from the oldest, into the ring buffer
+func (h *History) index(i int) int {
+	return (h.start + i) % len(h.points)
+}
+
+func (h *History) column(context string, m instrumentation.Metric) (int, bool) {
+	key := seriesKey(context, m.Name, m.Tags)
+	if column, found := h.columns[key]; found {
+		return column, true
+	}
+
+	if len(h.series) >= h.config.MaxSeries {
+		h.reclaim()
+		if len(h.series) >= h.config.MaxSeries {
+			return 0, false
+		}
+	}
+
+	h.series = append(h.series, series{key: key, context: context, name: m.Name, tags: m.Tags})
+	h.columns[key] = len(h.series) - 1
+
+	return len(h.series) - 1, true
+}
+
+// reclaim drops the series without a value in any of the points kept,
+// compacting the columns of every point
+func (h *History) reclaim() {
+	used := make([]bool, len(h.series))
+	for _, p := range h.points {
+		for column, value := range p.values {
+			if !math.IsNaN(value) {
+				used[column] = true
+			}
+		}
+	}
+
+	columns := make([]int, len(h.series))
+	kept := h.series[:0]
+	for column, s := range h.series {
+		if used[column] {
+			columns[column] = len(kept)
+			h.columns[s.key] = len(kept)
+			kept = append(kept, s)
+		} else {
+			columns[column] = -1
+			delete(h.columns, s.key)
+		}
+	}
+
+	// columns only ever move down, so the values can be compacted in place
+	for i := range h.points {
+		values := h.points[i].values
+		compacted := 0
+		for column, value := range values {
+			if columns[column] >= 0 {
+				values[columns[column]] = value
+				compacted++
+			}
+		}
+		h.points[i].values = values[:compacted]
+	}
+
+	for i := len(kept); i < len(h.series); i++ {
+		h.series[i] = series{}
+	}
+	h.series = kept
+}
+
+type Series struct {
+	Tags   map[string]interface{} `json:"tags,omitempty"`
+	Points []Point                `json:"points"`
+}
+
+// Point summarises the values of a series within a step, starting at
+// Timestamp
+type Point struct {
+	Timestamp time.Time `json:"timestamp"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Avg       float64   `json:"avg"`
+	Count     int       `json:"count"`
+}
+
+// Query downsamples every series of the metric, one per set of tags, to a
+// point per step between from and to, inclusive; steps without values are
+// left out
+func (h *History) Query(context string, name string, from time.Time, to time.Time, step time.Duration) []Series {
+	if step <= 0 {
+		step = time.Nanosecond
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	results := []Series{}
+	for column, s := range h.series {
+		if s.context != context || s.name != name {
+			continue
+		}
+
+		result := Series{Tags: s.tags, Points: []Point{}}
+		for i := range h.points {
+			p := h.points[h.index(i)]
+			if p.timestamp.Before(from) || p.timestamp.After(to) || column >= len(p.values) || math.IsNaN(p.values[column]) {
+				continue
+			}
+
+			value := p.values[column]
+			timestamp := from.Add(p.timestamp.Sub(from) / step * step)
+
+			last := len(result.Points) - 1
+			if last >= 0 && result.Points[last].Timestamp.Equal(timestamp) {
+				summary := &result.Points[last]
+				summary.Min = math.Min(summary.Min, value)
+				summary.Max = math.Max(summary.Max, value)
+				summary.Avg += (value - summary.Avg) / float64(summary.Count+1)
+				summary.Count++
+			} else {
+				result.Points = append(result.Points, Point{
+					Timestamp: timestamp,
+					Min:       value,
+					Max:       value,
+					Avg:       value,
+					Count:     1,
+				})
+			}
+		}
+
+		if len(result.Points) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	sort.Sort(byTags(results))
+
+	return results
+}
+
+type byTags []Series
+
+func (s byTags) Len() int      { return len(s) }
+func (s byTags) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTags) Less(i, j int) bool {
+	return seriesKey("", "", s[i].Tags) < seriesKey("", "", s[j].Tags)
+}
+
+func seriesKey(context string, name string, tags map[string]interface{}) string {
+	parts := make([]string, 0, len(tags))
+	for tag, value := range tags {
+		parts = append(parts, tag+"="+fmt.Sprint(value))
+	}
+	sort.Strings(parts)
+
+	return context + "/" + name + "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,306 @@
+package history_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+func tasks(at time.Time, pending float64) collection.Snapshot {
+	return collection.Snapshot{
+		Timestamp: at,
+		Contexts: []instrumentation.Context{
+			{
+				Name:    "Tasks",
+				Metrics: []instrumentation.Metric{{Name: "Pending", Value: metric.NewGauge(pending, "tasks", "")}},
+			},
+		},
+	}
+}
+
+var _ = Describe("History", func() {
+	var (
+		timeProvider *faketimeprovider.FakeTimeProvider
+		config       Config
+		history      *History
+		start        time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Unix(60000, 0)
+		timeProvider = faketimeprovider.New(start)
+		config = Config{
+			Retention:  10 * time.Minute,
+			Resolution: time.Minute,
+			MaxSeries:  10,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		history, err = New(nil, config, timeProvider, lagertest.NewTestLogger("test"))
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	It("records a point per resolution", func() {
+		history.Record(tasks(at(0), 1))
+		history.Record(tasks(at(0).Add(10*time.Second), 2))
+		history.Record(tasks(at(1), 3))
+
+		series := history.Query("Tasks", "Pending", at(0), at(10), time.Minute)
+		Ω(series).Should(HaveLen(1))
+		Ω(series[0].Points).Should(Equal([]Point{
+			{Timestamp: at(0), Min: 2, Max: 2, Avg: 2, Count: 1},
+			{Timestamp: at(1), Min: 3, Max: 3, Avg: 3, Count: 1},
+		}))
+	})
+
+	It("downsamples to min, max and average per step", func() {
+		for minute, pending := range []float64{4, 2, 6, 10, 20} {
+			history.Record(tasks(at(minute), pending))
+		}
+
+		series := history.Query("Tasks", "Pending", at(0), at(4), 3*time.Minute)
+		Ω(series).Should(HaveLen(1))
+		Ω(series[0].Points).Should(Equal([]Point{
+			{Timestamp: at(0), Min: 2, Max: 6, Avg: 4, Count: 3},
+			{Timestamp: at(3), Min: 10, Max: 20, Avg: 15, Count: 2},
+		}))
+	})
+
+	It("only returns points between from and to", func() {
+		for minute := 0; minute < 5; minute++ {
+			history.Record(tasks(at(minute), float64(minute)))
+		}
+
+		series := history.Query("Tasks", "Pending", at(1), at(3), time.Minute)
+		Ω(series[0].Points).Should(HaveLen(3))
+		Ω(series[0].Points[0].Avg).Should(Equal(float64(1)))
+		Ω(series[0].Points[2].Avg).Should(Equal(float64(3)))
+	})
+
+	It("returns a series per set of tags", func() {
+		history.Record(collection.Snapshot{
+			Timestamp: at(0),
+			Contexts: []instrumentation.Context{
+				{
+					Name: "MetricsServer",
+					Metrics: []instrumentation.Metric{
+						{Name: "Scrapes", Value: 2, Tags: map[string]interface{}{"endpoint": "/varz"}},
+						{Name: "Scrapes", Value: 1, Tags: map[string]interface{}{"endpoint": "/metrics"}},
+					},
+				},
+			},
+		})
+
+		series := history.Query("MetricsServer", "Scrapes", at(0), at(1), time.Minute)
+		Ω(series).Should(HaveLen(2))
+		Ω(series[0].Tags).Should(Equal(map[string]interface{}{"endpoint": "/metrics"}))
+		Ω(series[1].Tags).Should(Equal(map[string]interface{}{"endpoint": "/varz"}))
+	})
+
+	It("does not keep histograms", func() {
+		history.Record(collection.Snapshot{
+			Timestamp: at(0),
+			Contexts: []instrumentation.Context{
+				{
+					Name:    "MetricsServer",
+					Metrics: []instrumentation.Metric{{Name: "ScrapeDurationMS", Value: metric.NewHistogram(nil, 1, 1, "", "")}},
+				},
+			},
+		})
+
+		Ω(history.Query("MetricsServer", "ScrapeDurationMS", at(0), at(1), time.Minute)).Should(BeEmpty())
+	})
+
+	Describe("memory use", func() {
+		It("keeps at most retention / resolution points", func() {
+			for minute := 0; minute < 100; minute++ {
+				history.Record(tasks(at(minute), float64(minute)))
+			}
+
+			points, _ := history.Size()
+			Ω(points).Should(Equal(10))
+
+			series := history.Query("Tasks", "Pending", at(0), at(100), time.Minute)
+			Ω(series[0].Points).Should(HaveLen(10))
+			Ω(series[0].Points[0].Avg).Should(Equal(float64(90)))
+			Ω(series[0].Points[9].Avg).Should(Equal(float64(99)))
+		})
+
+		It("refuses to keep more than the maximum number of points", func() {
+			config.Retention = 168 * time.Hour
+			config.Resolution = time.Millisecond
+
+			_, err := New(nil, config, timeProvider, lagertest.NewTestLogger("test"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("604800000 points"))
+		})
+
+		It("keeps at most the maximum number of series", func() {
+			snapshot := collection.Snapshot{Timestamp: at(0)}
+			context := instrumentation.Context{Name: "Many"}
+			for i := 0; i < 50; i++ {
+				context.Metrics = append(context.Metrics, instrumentation.Metric{Name: fmt.Sprintf("Metric%d", i), Value: i})
+			}
+			snapshot.Contexts = append(snapshot.Contexts, context)
+
+			history.Record(snapshot)
+
+			_, series := history.Size()
+			Ω(series).Should(Equal(10))
+			Ω(history.Query("Many", "Metric9", at(0), at(1), time.Minute)).Should(HaveLen(1))
+			Ω(history.Query("Many", "Metric10", at(0), at(1), time.Minute)).Should(BeEmpty())
+		})
+
+		It("makes room for new series once old ones age out", func() {
+			for minute := 0; minute < 100; minute++ {
+				history.Record(collection.Snapshot{
+					Timestamp: at(minute),
+					Contexts: []instrumentation.Context{
+						{
+							Name:    "Churning",
+							Metrics: []instrumentation.Metric{{Name: fmt.Sprintf("Metric%d", minute), Value: minute}},
+						},
+					},
+				})
+			}
+
+			points, series := history.Size()
+			Ω(points).Should(Equal(10))
+			Ω(series).Should(BeNumerically("<=", 10))
+
+			result := history.Query("Churning", "Metric99", at(0), at(100), time.Minute)
+			Ω(result).Should(HaveLen(1))
+			Ω(result[0].Points[0].Avg).Should(Equal(float64(99)))
+
+			result = history.Query("Churning", "Metric95", at(0), at(100), time.Minute)
+			Ω(result).Should(HaveLen(1))
+			Ω(result[0].Points[0].Avg).Should(Equal(float64(95)))
+		})
+	})
+
+	Describe("running", func() {
+		It("records every snapshot it receives", func() {
+			snapshots := make(chan collection.Snapshot, 1)
+			var err error
+			history, err = New(snapshots, config, timeProvider, lagertest.NewTestLogger("test"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			process := ifrit.Envoke(history)
+			defer func() {
+				process.Signal(syscall.SIGTERM)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+			}()
+
+			snapshots <- tasks(at(0), 5)
+
+			Eventually(func() int {
+				points, _ := history.Size()
+				return points
+			}).Should(Equal(1))
+		})
+	})
+
+	Describe("serving queries", func() {
+		var server *httptest.Server
+
+		JustBeforeEach(func() {
+			for minute := 0; minute < 4; minute++ {
+				history.Record(tasks(at(minute), float64(minute)))
+			}
+			timeProvider.Increment(4 * time.Minute)
+
+			server = httptest.NewServer(history)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		get := func(query string) (*http.Response, map[string]interface{}) {
+			response, err := http.Get(server.URL + query)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer response.Body.Close()
+
+			body := map[string]interface{}{}
+			json.NewDecoder(response.Body).Decode(&body)
+			return response, body
+		}
+
+		It("returns the downsampled series", func() {
+			response, body := get(fmt.Sprintf("/?context=Tasks&metric=Pending&from=%d&to=%d&step=2m", at(0).Unix(), at(3).Unix()))
+			Ω(response.StatusCode).Should(Equal(http.StatusOK))
+			Ω(response.Header.Get("Content-Type")).Should(Equal("application/json"))
+
+			Ω(body["context"]).Should(Equal("Tasks"))
+			Ω(body["metric"]).Should(Equal("Pending"))
+			Ω(body["step"]).Should(Equal(float64(120)))
+
+			series := body["series"].([]interface{})
+			Ω(series).Should(HaveLen(1))
+
+			points := series[0].(map[string]interface{})["points"].([]interface{})
+			Ω(points).Should(HaveLen(2))
+			Ω(points[1]).Should(Equal(map[string]interface{}{
+				"timestamp": at(2).Format(time.RFC3339),
+				"min":       float64(2),
+				"max":       float64(3),
+				"avg":       2.5,
+				"count":     float64(2),
+			}))
+		})
+
+		It("defaults to the whole history at the resolution", func() {
+			_, body := get("/?context=Tasks&metric=Pending")
+			Ω(body["step"]).Should(Equal(float64(60)))
+
+			series := body["series"].([]interface{})
+			Ω(series[0].(map[string]interface{})["points"]).Should(HaveLen(4))
+		})
+
+		It("accepts RFC 3339 times and steps in seconds", func() {
+			_, body := get("/?context=Tasks&metric=Pending&from=" + at(2).UTC().Format(time.RFC3339) + "&step=300")
+			Ω(body["step"]).Should(Equal(float64(300)))
+
+			series := body["series"].([]interface{})
+			Ω(series[0].(map[string]interface{})["points"]).Should(HaveLen(1))
+		})
+
+		It("returns no series for unknown metrics", func() {
+			response, body := get("/?context=Tasks&metric=Unknown")
+			Ω(response.StatusCode).Should(Equal(http.StatusOK))
+			Ω(body["series"]).Should(BeEmpty())
+		})
+
+		It("rejects invalid queries", func() {
+			for _, query := range []string{
+				"/?metric=Pending",
+				"/?context=Tasks",
+				"/?context=Tasks&metric=Pending&from=yesterday",
+				"/?context=Tasks&metric=Pending&step=0",
+				fmt.Sprintf("/?context=Tasks&metric=Pending&from=%d&to=%d", at(3).Unix(), at(1).Unix()),
+			} {
+				response, _ := get(query)
+				Ω(response.StatusCode).Should(Equal(http.StatusBadRequest), query)
+			}
+		})
+	})
+})
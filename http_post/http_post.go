@@ -0,0 +1,71 @@
+package http_post
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// PermanentError is a failure that retrying won't fix
+type PermanentError struct {
+	error
+}
+
+// Post sends the body with the given headers. It treats 4xx responses other
+// than 429 as permanent failures: the receiver rejected the body itself,
+// rather than asking for it later.
+func Post(client *http.Client, url string, header http.Header, body []byte) error {
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return PermanentError{err}
+	}
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode/100 == 2 {
+		return nil
+	}
+
+	message, _ := ioutil.ReadAll(response.Body)
+	err = fmt.Errorf("%s responded with %d: %s", url, response.StatusCode, strings.TrimSpace(string(message)))
+
+	if response.StatusCode/100 == 4 && response.StatusCode != http.StatusTooManyRequests {
+		return PermanentError{err}
+	}
+
+	return err
+}
+
+// Retry calls attempt until it succeeds, fails permanently or has been
+// retried maxRetries times; signalled is true if a signal arrived while
+// waiting to retry
+func Retry(maxRetries int, interval time.Duration, signals <-chan os.Signal, attempt func() error) (signalled bool, err error) {
+	for attempts := 0; ; attempts++ {
+		err = attempt()
+		if err == nil {
+			return false, nil
+		}
+
+		if _, permanent := err.(PermanentError); permanent || attempts >= maxRetries {
+			return false, err
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-signals:
+			return true, err
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package http_post_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHTTPPost(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP Post Suite")
+}
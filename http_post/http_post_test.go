@@ -0,0 +1,91 @@
+package http_post_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/http_post"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("HTTP Post", func() {
+	var (
+		receiver *ghttp.Server
+		signals  chan os.Signal
+		attempt  func() error
+	)
+
+	BeforeEach(func() {
+		receiver = ghttp.NewServer()
+		signals = make(chan os.Signal, 1)
+		attempt = func() error {
+			return Post(http.DefaultClient, receiver.URL()+"/in", http.Header{"Content-Type": {"text/plain"}}, []byte("body"))
+		}
+	})
+
+	AfterEach(func() {
+		receiver.Close()
+	})
+
+	It("posts the body with the headers", func() {
+		receiver.AppendHandlers(ghttp.CombineHandlers(
+			ghttp.VerifyRequest("POST", "/in"),
+			ghttp.VerifyContentType("text/plain"),
+			func(w http.ResponseWriter, req *http.Request) {
+				Ω(req.ContentLength).Should(Equal(int64(4)))
+			},
+		))
+
+		signalled, err := Retry(2, time.Millisecond, signals, attempt)
+		Ω(signalled).Should(BeFalse())
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	It("retries server errors and 429s", func() {
+		receiver.AppendHandlers(
+			ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+			ghttp.RespondWith(429, nil),
+			ghttp.RespondWith(http.StatusNoContent, nil),
+		)
+
+		_, err := Retry(2, time.Millisecond, signals, attempt)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(receiver.ReceivedRequests()).Should(HaveLen(3))
+	})
+
+	It("does not retry other 4xx responses", func() {
+		receiver.AppendHandlers(ghttp.RespondWith(http.StatusBadRequest, "no thanks"))
+
+		_, err := Retry(2, time.Millisecond, signals, attempt)
+		Ω(err).Should(BeAssignableToTypeOf(PermanentError{}))
+		Ω(err.Error()).Should(ContainSubstring("no thanks"))
+		Ω(receiver.ReceivedRequests()).Should(HaveLen(1))
+	})
+
+	It("gives up after the maximum number of retries", func() {
+		attempts := 0
+		_, err := Retry(2, time.Millisecond, signals, func() error {
+			attempts++
+			return errors.New("unreachable")
+		})
+
+		Ω(err).Should(HaveOccurred())
+		Ω(attempts).Should(Equal(3))
+	})
+
+	It("stops waiting to retry when signalled", func() {
+		signals <- syscall.SIGTERM
+
+		signalled, err := Retry(2, time.Hour, signals, func() error {
+			return errors.New("unreachable")
+		})
+
+		Ω(signalled).Should(BeTrue())
+		Ω(err).Should(HaveOccurred())
+	})
+})
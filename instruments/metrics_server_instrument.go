@@ -0,0 +1,152 @@
+package instruments
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+)
+
+type metricsServerInstrument struct {
+	stats *server_stats.Stats
+}
+
+func NewMetricsServerInstrument(stats *server_stats.Stats) instrumentation.Instrumentable {
+	return &metricsServerInstrument{stats: stats}
+}
+
+func (t *metricsServerInstrument) Emit() instrumentation.Context {
+	context := instrumentation.Context{
+		Name: "MetricsServer",
+		Metrics: []instrumentation.Metric{
+			{
+				Name:  "UptimeSeconds",
+				Value: metric.NewGauge(t.stats.Uptime().Seconds(), "seconds", "Time since the metrics server started"),
+			},
+			{
+				Name:  "BuildInfo",
+				Value: metric.NewGauge(1, "", "Always 1, tagged with the version of the metrics server"),
+				Tags:  map[string]interface{}{"version": t.stats.Version()},
+			},
+			{
+				Name:  "NATSPublishErrors",
+				Value: metric.NewCounter(t.stats.NATSPublishErrors(), "errors", "Failed publishes to NATS"),
+			},
+			{
+				Name:  "Announcements",
+				Value: metric.NewCounter(t.stats.Announcements(), "announcements", "Successful announcements to the collector"),
+			},
+			{
+				Name:  "RegistrationFailures",
+				Value: metric.NewCounter(t.stats.RegistrationFailures(), "errors", "Failed announcements to the collector"),
+			},
+		},
+	}
+
+	if leader, electing, changes := t.stats.Leadership(); electing {
+		context.Metrics = append(context.Metrics,
+			instrumentation.Metric{
+				Name:  "Leader",
+				Value: metric.NewGauge(boolToFloat(leader), "", "1 while holding the metrics server lock, 0 while standing by"),
+			},
+			instrumentation.Metric{
+				Name:  "LeadershipChanges",
+				Value: metric.NewCounter(changes, "changes", "Times the metrics server lock was acquired or lost"),
+			},
+		)
+	}
+
+	scrapes := t.stats.Scrapes()
+	for _, endpoint := range sortedTimingKeys(scrapes) {
+		tags := map[string]interface{}{"endpoint": endpoint}
+		context.Metrics = append(context.Metrics,
+			instrumentation.Metric{
+				Name:  "Scrapes",
+				Value: metric.NewCounter(scrapes[endpoint].Count, "requests", "Requests served per endpoint"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "ScrapeLatencyMS",
+				Value: metric.NewGauge(milliseconds(scrapes[endpoint].LastDuration), "milliseconds", "Duration of the last request per endpoint"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "ScrapeDurationMS",
+				Value: latencyHistogram(scrapes[endpoint], "Duration of requests per endpoint"),
+				Tags:  tags,
+			},
+		)
+	}
+
+	collections := t.stats.Collections()
+	for _, name := range sortedTimingKeys(collections) {
+		context.Metrics = append(context.Metrics, instrumentation.Metric{
+			Name:  "CollectionDurationMS",
+			Value: metric.NewGauge(milliseconds(collections[name].LastDuration), "milliseconds", "Duration of the last collection per context"),
+			Tags:  map[string]interface{}{"context": name},
+		})
+	}
+
+	storeCalls := t.stats.StoreCalls()
+	operations := []string{}
+	for operation := range storeCalls {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	for _, operation := range operations {
+		tags := map[string]interface{}{"operation": operation}
+		context.Metrics = append(context.Metrics,
+			instrumentation.Metric{
+				Name:  "StoreCalls",
+				Value: metric.NewCounter(storeCalls[operation].Count, "calls", "Calls to the store per operation"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "StoreErrors",
+				Value: metric.NewCounter(storeCalls[operation].Errors, "errors", "Failed calls to the store per operation"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "StoreLatencyMS",
+				Value: metric.NewGauge(milliseconds(storeCalls[operation].LastDuration), "milliseconds", "Duration of the last call to the store per operation"),
+				Tags:  tags,
+			},
+		)
+	}
+
+	return context
+}
+
+func latencyHistogram(timing server_stats.Timing, description string) metric.Value {
+	buckets := make([]metric.Bucket, len(server_stats.LatencyBuckets))
+	for i, upperBound := range server_stats.LatencyBuckets {
+		buckets[i] = metric.Bucket{UpperBound: milliseconds(upperBound), Count: timing.BucketCounts[i]}
+	}
+
+	return metric.NewHistogram(buckets, milliseconds(timing.TotalDuration), timing.Count, "milliseconds", description)
+}
+
+func sortedTimingKeys(timings map[string]server_stats.Timing) []string {
+	keys := []string{}
+	for key := range timings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
@@ -0,0 +1,145 @@
+package instruments_test
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetricsServerInstrument", func() {
+	var instrument instrumentation.Instrumentable
+	var timeProvider *faketimeprovider.FakeTimeProvider
+	var stats *server_stats.Stats
+
+	BeforeEach(func() {
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		stats = server_stats.New("some-version", timeProvider)
+		instrument = NewMetricsServerInstrument(stats)
+	})
+
+	Describe("Emit", func() {
+		var context instrumentation.Context
+
+		BeforeEach(func() {
+			timeProvider.Increment(90 * time.Second)
+
+			stats.RecordScrape("/varz", 2*time.Millisecond)
+			stats.RecordCollection("Tasks", 5*time.Millisecond)
+			stats.RecordStoreCall("ListRecursively", 3*time.Millisecond, nil)
+			stats.RecordStoreCall("ListRecursively", 4*time.Millisecond, errors.New("boom"))
+			stats.RecordNATSPublishError()
+			stats.RecordAnnouncement()
+			stats.RecordAnnouncement()
+			stats.RecordRegistrationFailure()
+		})
+
+		JustBeforeEach(func() {
+			context = instrument.Emit()
+		})
+
+		valueOf := func(name string, tags map[string]interface{}) metric.Value {
+			for _, m := range context.Metrics {
+				if m.Name == name && reflect.DeepEqual(m.Tags, tags) {
+					value, ok := metric.Of(m)
+					Ω(ok).Should(BeTrue())
+					return value
+				}
+			}
+
+			Fail("no metric named " + name)
+			return metric.Value{}
+		}
+
+		It("should have a name", func() {
+			Ω(context.Name).Should(Equal("MetricsServer"))
+		})
+
+		It("should emit the uptime", func() {
+			Ω(valueOf("UptimeSeconds", nil)).Should(Equal(metric.NewGauge(90, "seconds", "Time since the metrics server started")))
+		})
+
+		It("should emit the build version", func() {
+			Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{
+				Name:  "BuildInfo",
+				Value: metric.NewGauge(1, "", "Always 1, tagged with the version of the metrics server"),
+				Tags:  map[string]interface{}{"version": "some-version"},
+			}))
+		})
+
+		It("should emit the scrape count and latency per endpoint", func() {
+			tags := map[string]interface{}{"endpoint": "/varz"}
+
+			Ω(valueOf("Scrapes", tags).Type).Should(Equal(metric.Counter))
+			Ω(valueOf("Scrapes", tags).Number).Should(Equal(float64(1)))
+
+			Ω(valueOf("ScrapeLatencyMS", tags).Type).Should(Equal(metric.Gauge))
+			Ω(valueOf("ScrapeLatencyMS", tags).Number).Should(Equal(float64(2)))
+		})
+
+		It("should emit a histogram of the scrape durations per endpoint", func() {
+			histogram := valueOf("ScrapeDurationMS", map[string]interface{}{"endpoint": "/varz"})
+
+			Ω(histogram.Type).Should(Equal(metric.Histogram))
+			Ω(histogram.Unit).Should(Equal("milliseconds"))
+			Ω(histogram.Count).Should(Equal(uint64(1)))
+			Ω(histogram.Sum).Should(Equal(float64(2)))
+			Ω(histogram.Buckets[0]).Should(Equal(metric.Bucket{UpperBound: 1, Count: 0}))
+			Ω(histogram.Buckets[1]).Should(Equal(metric.Bucket{UpperBound: 5, Count: 1}))
+			Ω(histogram.Buckets).Should(HaveLen(len(server_stats.LatencyBuckets)))
+		})
+
+		It("should emit the collection duration per instrument", func() {
+			duration := valueOf("CollectionDurationMS", map[string]interface{}{"context": "Tasks"})
+			Ω(duration.Type).Should(Equal(metric.Gauge))
+			Ω(duration.Number).Should(Equal(float64(5)))
+		})
+
+		It("should emit the store call count, errors and latency per operation", func() {
+			tags := map[string]interface{}{"operation": "ListRecursively"}
+
+			Ω(valueOf("StoreCalls", tags).Type).Should(Equal(metric.Counter))
+			Ω(valueOf("StoreCalls", tags).Number).Should(Equal(float64(2)))
+
+			Ω(valueOf("StoreErrors", tags).Type).Should(Equal(metric.Counter))
+			Ω(valueOf("StoreErrors", tags).Number).Should(Equal(float64(1)))
+
+			Ω(valueOf("StoreLatencyMS", tags).Type).Should(Equal(metric.Gauge))
+			Ω(valueOf("StoreLatencyMS", tags).Number).Should(Equal(float64(4)))
+		})
+
+		It("should emit the announcements and registration failures", func() {
+			Ω(valueOf("Announcements", nil)).Should(Equal(metric.NewCounter(2, "announcements", "Successful announcements to the collector")))
+			Ω(valueOf("RegistrationFailures", nil)).Should(Equal(metric.NewCounter(1, "errors", "Failed announcements to the collector")))
+		})
+
+		It("should emit the NATS publish errors", func() {
+			Ω(valueOf("NATSPublishErrors", nil)).Should(Equal(metric.NewCounter(1, "errors", "Failed publishes to NATS")))
+		})
+
+		It("should not emit leadership when not electing a leader", func() {
+			for _, metric := range context.Metrics {
+				Ω(metric.Name).ShouldNot(Equal("Leader"))
+			}
+		})
+
+		Context("when electing a leader", func() {
+			BeforeEach(func() {
+				stats.RecordLeadership(true)
+			})
+
+			It("should emit whether it is the leader", func() {
+				Ω(valueOf("Leader", nil).Number).Should(Equal(float64(1)))
+				Ω(valueOf("LeadershipChanges", nil).Type).Should(Equal(metric.Counter))
+				Ω(valueOf("LeadershipChanges", nil).Number).Should(Equal(float64(0)))
+			})
+		})
+	})
+})
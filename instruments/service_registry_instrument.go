@@ -2,6 +2,7 @@ package instruments
 
 import (
 	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
 	"github.com/cloudfoundry-incubator/runtime-schema/bbs"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 )
@@ -29,17 +30,25 @@ func (t *serviceRegistryInstrument) Emit() instrumentation.Context {
 		for _, serviceName := range serviceNames {
 			context.Metrics = append(context.Metrics, instrumentation.Metric{
 				Name:  serviceName,
-				Value: -1,
+				Value: metric.NewGauge(-1, "registrations", registrationsDescription(serviceName)),
 			})
 		}
 	} else {
 		for _, serviceName := range serviceNames {
 			context.Metrics = append(context.Metrics, instrumentation.Metric{
-				Name:  serviceName,
-				Value: len(registrations.FilterByName(serviceName)),
+				Name: serviceName,
+				Value: metric.NewGauge(
+					float64(len(registrations.FilterByName(serviceName))),
+					"registrations",
+					registrationsDescription(serviceName),
+				),
 			})
 		}
 	}
 
 	return context
 }
+
+func registrationsDescription(serviceName string) string {
+	return "Number of registered " + serviceName + " services, or -1 if the BBS could not be read"
+}
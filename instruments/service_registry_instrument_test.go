@@ -5,12 +5,20 @@ import (
 
 	"github.com/cloudfoundry-incubator/metricz/instrumentation"
 	. "github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
 	"github.com/cloudfoundry-incubator/runtime-schema/bbs/fake_bbs"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+func registrations(serviceName string, count float64) instrumentation.Metric {
+	return instrumentation.Metric{
+		Name:  serviceName,
+		Value: metric.NewGauge(count, "registrations", "Number of registered "+serviceName+" services, or -1 if the BBS could not be read"),
+	}
+}
+
 var _ = Describe("ServiceRegistryInstrument", func() {
 	var instrument instrumentation.Instrumentable
 	var fakeBBS *fake_bbs.FakeMetricsBBS
@@ -42,12 +50,12 @@ var _ = Describe("ServiceRegistryInstrument", func() {
 
 			It("should emit the number of executors", func() {
 				Ω(context.Name).Should(Equal("ServiceRegistrations"))
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "Executor", Value: 2}))
+				Ω(context.Metrics).Should(ContainElement(registrations("Executor", 2)))
 			})
 
 			It("should emit the number of file servers", func() {
 				Ω(context.Name).Should(Equal("ServiceRegistrations"))
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "FileServer", Value: 1}))
+				Ω(context.Metrics).Should(ContainElement(registrations("FileServer", 1)))
 			})
 		})
 
@@ -57,11 +65,11 @@ var _ = Describe("ServiceRegistryInstrument", func() {
 			})
 
 			It("should emit 0 executors", func() {
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "Executor", Value: 0}))
+				Ω(context.Metrics).Should(ContainElement(registrations("Executor", 0)))
 			})
 
 			It("should emit 0 file servers", func() {
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "FileServer", Value: 0}))
+				Ω(context.Metrics).Should(ContainElement(registrations("FileServer", 0)))
 			})
 		})
 
@@ -71,11 +79,11 @@ var _ = Describe("ServiceRegistryInstrument", func() {
 			})
 
 			It("should emit -1 executors", func() {
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "Executor", Value: -1}))
+				Ω(context.Metrics).Should(ContainElement(registrations("Executor", -1)))
 			})
 
 			It("should emit 0 file servers", func() {
-				Ω(context.Metrics).Should(ContainElement(instrumentation.Metric{Name: "FileServer", Value: -1}))
+				Ω(context.Metrics).Should(ContainElement(registrations("FileServer", -1)))
 			})
 		})
 
@@ -2,6 +2,7 @@ package instruments
 
 import (
 	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
 	"github.com/cloudfoundry-incubator/runtime-schema/bbs"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
 )
@@ -51,23 +52,23 @@ func (t *taskInstrument) Emit() instrumentation.Context {
 		Metrics: []instrumentation.Metric{
 			{
 				Name:  "Pending",
-				Value: pendingCount,
+				Value: metric.NewGauge(float64(pendingCount), "tasks", "Number of pending tasks, or -1 if the BBS could not be read"),
 			},
 			{
 				Name:  "Claimed",
-				Value: claimedCount,
+				Value: metric.NewGauge(float64(claimedCount), "tasks", "Number of claimed tasks, or -1 if the BBS could not be read"),
 			},
 			{
 				Name:  "Running",
-				Value: runningCount,
+				Value: metric.NewGauge(float64(runningCount), "tasks", "Number of running tasks, or -1 if the BBS could not be read"),
 			},
 			{
 				Name:  "Completed",
-				Value: completedCount,
+				Value: metric.NewGauge(float64(completedCount), "tasks", "Number of completed tasks, or -1 if the BBS could not be read"),
 			},
 			{
 				Name:  "Resolving",
-				Value: resolvingCount,
+				Value: metric.NewGauge(float64(resolvingCount), "tasks", "Number of resolving tasks, or -1 if the BBS could not be read"),
 			},
 		},
 	}
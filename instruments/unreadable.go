@@ -0,0 +1,8 @@
+package instruments
+
+// IsUnreadable is whether a value is the -1 the task and service registry
+// instruments report when the BBS could not be read, rather than a count;
+// other contexts may be negative on their own
+func IsUnreadable(context string, value float64) bool {
+	return value == -1 && (context == "Tasks" || context == "ServiceRegistrations")
+}
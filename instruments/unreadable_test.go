@@ -0,0 +1,18 @@
+package instruments_test
+
+import (
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsUnreadable", func() {
+	It("is true for the -1 of the BBS instruments only", func() {
+		Ω(IsUnreadable("Tasks", -1)).Should(BeTrue())
+		Ω(IsUnreadable("ServiceRegistrations", -1)).Should(BeTrue())
+
+		Ω(IsUnreadable("Tasks", 0)).Should(BeFalse())
+		Ω(IsUnreadable("Anomalies", -1)).Should(BeFalse())
+	})
+})
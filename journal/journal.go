@@ -0,0 +1,168 @@
+// Package journal records collection snapshots in an append-only file.
+//
+// Each record is the length of the JSON encoded snapshot, as a big-endian
+// uint32, followed by the snapshot itself. The journal is rotated into
+// <path>.1, <path>.2, ... with the highest number being the most recent.
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+)
+
+// MaxRecordSize guards against reading a corrupt length prefix as a huge
+// record
+const MaxRecordSize = 64 * 1024 * 1024
+
+var ErrTruncated = errors.New("journal ends with a truncated record")
+var ErrCorrupt = errors.New("journal record is larger than the maximum record size")
+
+func encode(snapshot collection.Snapshot) ([]byte, error) {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	copy(record[4:], payload)
+
+	return record, nil
+}
+
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(reader io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(reader)}
+}
+
+// Next returns the next snapshot in the journal, io.EOF at its end, or
+// ErrTruncated when the last record was only partially written
+func (r *Reader) Next() (collection.Snapshot, error) {
+	var snapshot collection.Snapshot
+
+	header := make([]byte, 4)
+	_, err := io.ReadFull(r.reader, header)
+	if err == io.ErrUnexpectedEOF {
+		return snapshot, ErrTruncated
+	}
+	if err != nil {
+		return snapshot, err
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length > MaxRecordSize {
+		return snapshot, ErrCorrupt
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r.reader, payload)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return snapshot, ErrTruncated
+	}
+	if err != nil {
+		return snapshot, err
+	}
+
+	err = json.Unmarshal(payload, &snapshot)
+	return snapshot, err
+}
+
+// completeLength is the length of the complete records at the start of a
+// journal. A crash while writing leaves a partial record at its end, which
+// would make every record appended after it unreadable.
+func completeLength(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 4)
+
+	var length int64
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return length, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header)
+		if size > MaxRecordSize {
+			return length, nil
+		}
+
+		payload := make([]byte, size)
+		_, err = io.ReadFull(reader, payload)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return length, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// the file system may have extended the file before the record
+		// reached it, leaving zeroes
+		var raw json.RawMessage
+		if json.Unmarshal(payload, &raw) != nil {
+			return length, nil
+		}
+
+		length += 4 + int64(size)
+	}
+}
+
+// Files are the files of the journal at path, oldest first
+func Files(path string) ([]string, error) {
+	rotated, err := rotations(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(rotated)+1)
+	for _, n := range rotated {
+		files = append(files, rotatedPath(path, n))
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// rotations are the numbers of the rotated files of the journal at path, in
+// ascending order
+func rotations(path string) ([]int, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := []int{}
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, path+"."))
+		if err == nil && n > 0 {
+			rotated = append(rotated, n)
+		}
+	}
+	sort.Ints(rotated)
+
+	return rotated, nil
+}
+
+func rotatedPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
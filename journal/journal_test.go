@@ -0,0 +1,291 @@
+package journal_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+func snapshot(sequence uint64) collection.Snapshot {
+	return collection.Snapshot{
+		Sequence:  sequence,
+		Timestamp: time.Unix(int64(1000+sequence), 0).UTC(),
+		Contexts: []instrumentation.Context{
+			{
+				Name:    "Tasks",
+				Metrics: []instrumentation.Metric{{Name: "Pending", Value: metric.NewGauge(float64(sequence), "tasks", "")}},
+			},
+		},
+	}
+}
+
+func readAll(path string) []uint64 {
+	file, err := os.Open(path)
+	Ω(err).ShouldNot(HaveOccurred())
+	defer file.Close()
+
+	sequences := []uint64{}
+	reader := NewReader(file)
+	for {
+		snapshot, err := reader.Next()
+		if err == io.EOF {
+			return sequences
+		}
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sequences = append(sequences, snapshot.Sequence)
+	}
+}
+
+var _ = Describe("Journal", func() {
+	var (
+		dir       string
+		path      string
+		config    Config
+		snapshots chan collection.Snapshot
+		process   ifrit.Process
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "journal")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		path = filepath.Join(dir, "snapshots.journal")
+		config = Config{Path: path}
+		snapshots = make(chan collection.Snapshot)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Envoke(NewWriter(snapshots, config, lagertest.NewTestLogger("test")))
+	})
+
+	stop := func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	}
+
+	It("records every snapshot", func() {
+		snapshots <- snapshot(1)
+		snapshots <- snapshot(2)
+		stop()
+
+		file, err := os.Open(path)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer file.Close()
+
+		reader := NewReader(file)
+
+		recorded, err := reader.Next()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recorded.Sequence).Should(Equal(uint64(1)))
+		Ω(recorded.Timestamp).Should(Equal(snapshot(1).Timestamp))
+		Ω(recorded.Contexts).Should(Equal([]instrumentation.Context{
+			{Name: "Tasks", Metrics: []instrumentation.Metric{{Name: "Pending", Value: float64(1)}}},
+		}))
+
+		recorded, err = reader.Next()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(recorded.Sequence).Should(Equal(uint64(2)))
+
+		_, err = reader.Next()
+		Ω(err).Should(Equal(io.EOF))
+	})
+
+	Context("when the journal already exists", func() {
+		BeforeEach(func() {
+			previous := make(chan collection.Snapshot)
+			process := ifrit.Envoke(NewWriter(previous, config, lagertest.NewTestLogger("test")))
+			previous <- snapshot(1)
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("appends to it", func() {
+			snapshots <- snapshot(2)
+			stop()
+
+			Ω(readAll(path)).Should(Equal([]uint64{1, 2}))
+		})
+	})
+
+	Context("when the journal ends with a partial record", func() {
+		BeforeEach(func() {
+			previous := make(chan collection.Snapshot)
+			process := ifrit.Envoke(NewWriter(previous, config, lagertest.NewTestLogger("test")))
+			previous <- snapshot(1)
+			previous <- snapshot(2)
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			info, err := os.Stat(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			err = os.Truncate(path, info.Size()-5)
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("truncates it, so what is appended can still be read", func() {
+			snapshots <- snapshot(3)
+			snapshots <- snapshot(4)
+			stop()
+
+			Ω(readAll(path)).Should(Equal([]uint64{1, 3, 4}))
+		})
+	})
+
+	Context("when the journal ends with zeroes", func() {
+		BeforeEach(func() {
+			previous := make(chan collection.Snapshot)
+			process := ifrit.Envoke(NewWriter(previous, config, lagertest.NewTestLogger("test")))
+			previous <- snapshot(1)
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = file.Write(make([]byte, 16))
+			Ω(err).ShouldNot(HaveOccurred())
+			file.Close()
+		})
+
+		It("truncates them", func() {
+			snapshots <- snapshot(2)
+			stop()
+
+			Ω(readAll(path)).Should(Equal([]uint64{1, 2}))
+		})
+	})
+
+	Context("when the journal can't be opened", func() {
+		BeforeEach(func() {
+			config.Path = filepath.Join(dir, "missing", "snapshots.journal")
+		})
+
+		It("exits with the error", func() {
+			Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+		})
+	})
+
+	Context("with a maximum size", func() {
+		BeforeEach(func() {
+			config.MaxSize = 300
+		})
+
+		It("rotates before growing beyond it", func() {
+			for sequence := uint64(1); sequence <= 5; sequence++ {
+				snapshots <- snapshot(sequence)
+			}
+			stop()
+
+			files, err := Files(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(len(files)).Should(BeNumerically(">", 1))
+			Ω(files[len(files)-1]).Should(Equal(path))
+
+			sequences := []uint64{}
+			for _, file := range files {
+				info, err := os.Stat(file)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(info.Size()).Should(BeNumerically("<=", 300))
+
+				sequences = append(sequences, readAll(file)...)
+			}
+
+			Ω(sequences).Should(Equal([]uint64{1, 2, 3, 4, 5}))
+		})
+
+		Context("and a maximum number of files", func() {
+			BeforeEach(func() {
+				config.MaxSize = 1
+				config.MaxFiles = 2
+			})
+
+			It("removes the oldest rotated files", func() {
+				for sequence := uint64(1); sequence <= 5; sequence++ {
+					snapshots <- snapshot(sequence)
+				}
+				stop()
+
+				files, err := Files(path)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(files).Should(Equal([]string{path + ".3", path + ".4", path}))
+
+				Ω(readAll(files[0])).Should(Equal([]uint64{3}))
+				Ω(readAll(files[1])).Should(Equal([]uint64{4}))
+				Ω(readAll(files[2])).Should(Equal([]uint64{5}))
+			})
+		})
+	})
+
+	Context("with a maximum age", func() {
+		BeforeEach(func() {
+			config.MaxAge = time.Hour
+		})
+
+		It("rotates once its first snapshot is that old", func() {
+			snapshots <- snapshot(1)
+			snapshots <- snapshot(2)
+
+			late := snapshot(3)
+			late.Timestamp = snapshot(1).Timestamp.Add(time.Hour)
+			snapshots <- late
+			stop()
+
+			Ω(readAll(path + ".1")).Should(Equal([]uint64{1, 2}))
+			Ω(readAll(path)).Should(Equal([]uint64{3}))
+		})
+	})
+
+	Describe("reading", func() {
+		It("reports a truncated last record", func() {
+			record := make([]byte, 4)
+			binary.BigEndian.PutUint32(record, 100)
+			record = append(record, []byte(`{"sequence":`)...)
+
+			_, err := NewReader(bytes.NewReader(record)).Next()
+			Ω(err).Should(Equal(ErrTruncated))
+
+			_, err = NewReader(bytes.NewReader(record[:2])).Next()
+			Ω(err).Should(Equal(ErrTruncated))
+		})
+
+		It("rejects records larger than the maximum record size", func() {
+			record := make([]byte, 4)
+			binary.BigEndian.PutUint32(record, MaxRecordSize+1)
+
+			_, err := NewReader(bytes.NewReader(record)).Next()
+			Ω(err).Should(Equal(ErrCorrupt))
+		})
+	})
+
+	Describe("Files", func() {
+		It("lists the rotated files in order before the journal", func() {
+			for _, name := range []string{"snapshots.journal", "snapshots.journal.10", "snapshots.journal.9", "snapshots.journal.other", "other.journal"} {
+				err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+				Ω(err).ShouldNot(HaveOccurred())
+			}
+
+			files, err := Files(path)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(files).Should(Equal([]string{path + ".9", path + ".10", path}))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+package journal
+
+import (
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/pivotal-golang/lager"
+)
+
+type Config struct {
+	Path string
+
+	// the journal is rotated before growing beyond MaxSize bytes or once
+	// its first snapshot is older than MaxAge; zero disables either
+	MaxSize int64
+	MaxAge  time.Duration
+
+	// MaxFiles is the number of rotated files kept; zero keeps them all
+	MaxFiles int
+}
+
+// Writer appends every snapshot it receives to the journal
+type Writer struct {
+	snapshots <-chan collection.Snapshot
+	config    Config
+	logger    lager.Logger
+
+	file *os.File
+	size int64
+
+	// first is when the first snapshot in the file was collected; it is
+	// zero when the file was written to before the writer started
+	first time.Time
+}
+
+func NewWriter(
+	snapshots <-chan collection.Snapshot,
+	config Config,
+	logger lager.Logger,
+) *Writer {
+	return &Writer{
+		snapshots: snapshots,
+		config:    config,
+		logger:    logger.Session("journal", lager.Data{"path": config.Path}),
+	}
+}
+
+func (w *Writer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	err := w.open()
+	if err != nil {
+		w.logger.Error("failed-to-open", err)
+		return err
+	}
+	defer w.file.Close()
+
+	close(ready)
+
+	for {
+		select {
+		case snapshot := <-w.snapshots:
+			err := w.write(snapshot)
+			if err != nil {
+				w.logger.Error("failed-to-write", err, lager.Data{"sequence": snapshot.Sequence})
+			}
+
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// open appends to the journal, first truncating any partial record left at
+// its end
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.config.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	length, err := completeLength(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	if length < info.Size() {
+		w.logger.Info("truncating-partial-record", lager.Data{"size": info.Size(), "complete": length})
+
+		err := file.Truncate(length)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	w.file = file
+	w.size = length
+	w.first = time.Time{}
+
+	return nil
+}
+
+func (w *Writer) write(snapshot collection.Snapshot) error {
+	record, err := encode(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if w.size > 0 && w.full(len(record), snapshot.Timestamp) {
+		err := w.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.first.IsZero() {
+		w.first = snapshot.Timestamp
+	}
+
+	n, err := w.file.Write(record)
+	w.size += int64(n)
+
+	return err
+}
+
+func (w *Writer) full(recordSize int, timestamp time.Time) bool {
+	if w.config.MaxSize > 0 && w.size+int64(recordSize) > w.config.MaxSize {
+		return true
+	}
+
+	return w.config.MaxAge > 0 && !w.first.IsZero() && timestamp.Sub(w.first) >= w.config.MaxAge
+}
+
+func (w *Writer) rotate() error {
+	rotated, err := rotations(w.config.Path)
+	if err != nil {
+		return err
+	}
+
+	next := 1
+	if len(rotated) > 0 {
+		next = rotated[len(rotated)-1] + 1
+	}
+
+	w.file.Close()
+
+	err = os.Rename(w.config.Path, rotatedPath(w.config.Path, next))
+	if err != nil {
+		// keep appending to the journal rather than losing snapshots
+		reopenErr := w.open()
+		if reopenErr != nil {
+			return reopenErr
+		}
+		return err
+	}
+
+	w.logger.Info("rotated", lager.Data{"rotated-to": rotatedPath(w.config.Path, next)})
+
+	rotated = append(rotated, next)
+	if w.config.MaxFiles > 0 {
+		for len(rotated) > w.config.MaxFiles {
+			err := os.Remove(rotatedPath(w.config.Path, rotated[0]))
+			if err != nil && !os.IsNotExist(err) {
+				w.logger.Error("failed-to-remove", err, lager.Data{"file": rotatedPath(w.config.Path, rotated[0])})
+			}
+			rotated = rotated[1:]
+		}
+	}
+
+	return w.open()
+}
@@ -0,0 +1,136 @@
+package leader
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry-incubator/runtime-schema/bbs/shared"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/pivotal-golang/lager"
+)
+
+var LockSchemaPath = shared.LockSchemaPath("metrics_server_lock")
+
+var ErrLockMaintenanceStopped = errors.New("lock maintenance stopped")
+
+// ErrTTLTooShort is returned for TTLs under a second: etcd TTLs are whole
+// seconds, and a TTL of 0 would never expire, so the lock of a dead leader
+// would never be taken over
+var ErrTTLTooShort = errors.New("the lock TTL must be at least 1s")
+
+type Leadership interface {
+	IsLeader() bool
+}
+
+type Elector struct {
+	store  storeadapter.StoreAdapter
+	id     string
+	ttl    time.Duration
+	stats  *server_stats.Stats
+	logger lager.Logger
+
+	lock   *sync.RWMutex
+	leader bool
+}
+
+func NewElector(
+	store storeadapter.StoreAdapter,
+	id string,
+	ttl time.Duration,
+	stats *server_stats.Stats,
+	logger lager.Logger,
+) *Elector {
+	return &Elector{
+		store:  store,
+		id:     id,
+		ttl:    ttl,
+		stats:  stats,
+		logger: logger.Session("elector", lager.Data{"id": id}),
+
+		lock: &sync.RWMutex{},
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.leader
+}
+
+func (e *Elector) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	if e.ttl < time.Second {
+		return ErrTTLTooShort
+	}
+
+	status, releaseLock, err := e.store.MaintainNode(storeadapter.StoreNode{
+		Key:   LockSchemaPath,
+		Value: []byte(e.id),
+		TTL:   uint64(e.ttl.Seconds()),
+	})
+	if err != nil {
+		e.logger.Error("failed-to-maintain-lock", err)
+		return err
+	}
+
+	e.setLeader(false)
+
+	close(ready)
+
+	for {
+		select {
+		case owned, ok := <-status:
+			if !ok {
+				e.setLeader(false)
+				return ErrLockMaintenanceStopped
+			}
+
+			e.setLeader(owned)
+
+		case <-signals:
+			e.setLeader(false)
+			e.release(status, releaseLock)
+			return nil
+		}
+	}
+}
+
+// release keeps draining the status channel so that the maintainer is never
+// stuck reporting ownership while we wait for it to delete the lock
+func (e *Elector) release(status <-chan bool, releaseLock chan chan bool) {
+	released := make(chan bool)
+
+	for {
+		select {
+		case releaseLock <- released:
+			<-released
+			e.logger.Info("released-lock")
+			return
+
+		case _, ok := <-status:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.lock.Lock()
+	changed := e.leader != leader
+	e.leader = leader
+	e.lock.Unlock()
+
+	e.stats.RecordLeadership(leader)
+
+	if changed {
+		if leader {
+			e.logger.Info("acquired-lock")
+		} else {
+			e.logger.Info("lost-lock")
+		}
+	}
+}
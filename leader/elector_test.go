@@ -0,0 +1,114 @@
+package leader_test
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/leader"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/storeadapter/fakestoreadapter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Elector", func() {
+	var (
+		store   *fakestoreadapter.FakeStoreAdapter
+		stats   *server_stats.Stats
+		elector *Elector
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		store = fakestoreadapter.New()
+		stats = server_stats.New("some-version", faketimeprovider.New(time.Unix(1000, 0)))
+		elector = NewElector(store, "10.0.0.1:5678", 10*time.Second, stats, lagertest.NewTestLogger("test"))
+	})
+
+	Context("when running", func() {
+		BeforeEach(func() {
+			process = ifrit.Envoke(elector)
+		})
+
+		AfterEach(func() {
+			process.Signal(syscall.SIGTERM)
+
+			select {
+			case released := <-store.ReleaseNodeChannel:
+				close(released)
+			case <-process.Wait():
+			}
+		})
+
+		It("maintains the metrics server lock with its id", func() {
+			Ω(store.MaintainedNodeName).Should(Equal("/v1/locks/metrics_server_lock"))
+			Ω(string(store.MaintainedNodeValue)).Should(Equal("10.0.0.1:5678"))
+		})
+
+		It("stands by until the lock is acquired", func() {
+			Ω(elector.IsLeader()).Should(BeFalse())
+
+			_, electing, _ := stats.Leadership()
+			Ω(electing).Should(BeTrue())
+		})
+
+		It("leads while it holds the lock", func() {
+			store.MaintainNodeStatus <- true
+			Eventually(elector.IsLeader).Should(BeTrue())
+
+			store.MaintainNodeStatus <- false
+			Eventually(elector.IsLeader).Should(BeFalse())
+
+			_, _, changes := stats.Leadership()
+			Ω(changes).Should(Equal(uint64(2)))
+		})
+
+		It("releases the lock when signalled", func() {
+			store.MaintainNodeStatus <- true
+			Eventually(elector.IsLeader).Should(BeTrue())
+
+			process.Signal(syscall.SIGTERM)
+
+			var released chan bool
+			Eventually(store.ReleaseNodeChannel).Should(Receive(&released))
+			Ω(elector.IsLeader()).Should(BeFalse())
+
+			Consistently(process.Wait()).ShouldNot(Receive())
+			close(released)
+
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("exits with an error when the lock is no longer maintained", func() {
+			close(store.MaintainNodeStatus)
+			Eventually(process.Wait()).Should(Receive(Equal(ErrLockMaintenanceStopped)))
+		})
+	})
+
+	Context("with a TTL under a second", func() {
+		BeforeEach(func() {
+			elector = NewElector(store, "10.0.0.1:5678", 500*time.Millisecond, stats, lagertest.NewTestLogger("test"))
+		})
+
+		It("exits without maintaining a lock that would never expire", func() {
+			process = ifrit.Envoke(elector)
+			Eventually(process.Wait()).Should(Receive(Equal(ErrTTLTooShort)))
+			Ω(store.MaintainedNodeName).Should(BeEmpty())
+		})
+	})
+
+	Context("when the lock cannot be maintained", func() {
+		BeforeEach(func() {
+			store.MaintainNodeError = errors.New("etcd is down")
+		})
+
+		It("exits with the error", func() {
+			process = ifrit.Envoke(elector)
+			Eventually(process.Wait()).Should(Receive(MatchError("etcd is down")))
+		})
+	})
+})
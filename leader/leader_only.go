@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+)
+
+var ErrStandby = errors.New("standing by for the metrics server lock")
+
+type leaderOnlyInstrument struct {
+	instrumentation.Instrumentable
+	leadership Leadership
+}
+
+func NewLeaderOnlyInstrument(instrument instrumentation.Instrumentable, leadership Leadership) instrumentation.Instrumentable {
+	return &leaderOnlyInstrument{
+		Instrumentable: instrument,
+		leadership:     leadership,
+	}
+}
+
+func (i *leaderOnlyInstrument) Enabled() bool {
+	return i.leadership.IsLeader()
+}
+
+type leaderOnlyCheck struct {
+	check      health_check.Check
+	leadership Leadership
+}
+
+func NewLeaderOnlyCheck(check health_check.Check, leadership Leadership) health_check.Check {
+	return &leaderOnlyCheck{
+		check:      check,
+		leadership: leadership,
+	}
+}
+
+func (c *leaderOnlyCheck) Name() string {
+	return c.check.Name()
+}
+
+func (c *leaderOnlyCheck) Check() error {
+	if !c.leadership.IsLeader() {
+		return nil
+	}
+
+	return c.check.Check()
+}
+
+type leaderCheck struct {
+	leadership Leadership
+}
+
+func NewLeaderCheck(leadership Leadership) health_check.Check {
+	return &leaderCheck{leadership: leadership}
+}
+
+func (c *leaderCheck) Name() string {
+	return "leader"
+}
+
+func (c *leaderCheck) Check() error {
+	if !c.leadership.IsLeader() {
+		return ErrStandby
+	}
+
+	return nil
+}
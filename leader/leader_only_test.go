@@ -0,0 +1,73 @@
+package leader_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/leader"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeLeadership struct {
+	leader bool
+}
+
+func (l *fakeLeadership) IsLeader() bool {
+	return l.leader
+}
+
+type fakeInstrument struct{}
+
+func (fakeInstrument) Emit() instrumentation.Context {
+	return instrumentation.Context{Name: "Fake"}
+}
+
+type failingCheck struct{}
+
+func (failingCheck) Name() string { return "failing" }
+func (failingCheck) Check() error { return errors.New("boom") }
+
+var _ = Describe("Leader only", func() {
+	var leadership *fakeLeadership
+
+	BeforeEach(func() {
+		leadership = &fakeLeadership{}
+	})
+
+	Describe("NewLeaderOnlyInstrument", func() {
+		It("is only enabled while leading", func() {
+			instrument := NewLeaderOnlyInstrument(fakeInstrument{}, leadership)
+			Ω(instrument.Emit().Name).Should(Equal("Fake"))
+
+			conditional := instrument.(collection.Conditional)
+			Ω(conditional.Enabled()).Should(BeFalse())
+
+			leadership.leader = true
+			Ω(conditional.Enabled()).Should(BeTrue())
+		})
+	})
+
+	Describe("NewLeaderOnlyCheck", func() {
+		It("only checks while leading", func() {
+			check := NewLeaderOnlyCheck(failingCheck{}, leadership)
+			Ω(check.Name()).Should(Equal("failing"))
+			Ω(check.Check()).ShouldNot(HaveOccurred())
+
+			leadership.leader = true
+			Ω(check.Check()).Should(MatchError("boom"))
+		})
+	})
+
+	Describe("NewLeaderCheck", func() {
+		It("fails while standing by", func() {
+			check := NewLeaderCheck(leadership)
+			Ω(check.Name()).Should(Equal("leader"))
+			Ω(check.Check()).Should(Equal(ErrStandby))
+
+			leadership.leader = true
+			Ω(check.Check()).ShouldNot(HaveOccurred())
+		})
+	})
+})
@@ -3,13 +3,24 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry-incubator/cf-debug-server"
 	"github.com/cloudfoundry-incubator/cf-lager"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/anomaly"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/leader"
 	"github.com/cloudfoundry-incubator/runtime-metrics-server/metrics_server"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
 	Bbs "github.com/cloudfoundry-incubator/runtime-schema/bbs"
 	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/storeadapter"
 	"github.com/cloudfoundry/storeadapter/etcdstoreadapter"
 	"github.com/cloudfoundry/storeadapter/workerpool"
 	"github.com/cloudfoundry/yagnats"
@@ -18,6 +29,9 @@ import (
 	"github.com/tedsuo/ifrit/sigmon"
 )
 
+// set at build time with -ldflags "-X main.version <version>"
+var version = "dev"
+
 var etcdCluster = flag.String(
 	"etcdCluster",
 	"http://127.0.0.1:4001",
@@ -66,40 +80,492 @@ var natsPassword = flag.String(
 	"Password for nats user",
 )
 
+var collectionInterval = flag.Duration(
+	"collectionInterval",
+	10*time.Second,
+	"interval between collections of the runtime metrics",
+)
+
+var maxBBSReadAge = flag.Duration(
+	"maxBBSReadAge",
+	time.Minute,
+	"report unhealthy if there has not been a successful read from the BBS in this long",
+)
+
+var maxCollectionAge = flag.Duration(
+	"maxCollectionAge",
+	time.Minute,
+	"report unhealthy if there has not been a completed collection in this long",
+)
+
+var maxCollectionDuration = flag.Duration(
+	"maxCollectionDuration",
+	5*time.Minute,
+	"report not alive if a single collection has been running for this long",
+)
+
+var announceInterval = flag.Duration(
+	"announceInterval",
+	time.Minute,
+	"interval between announcements to the collector",
+)
+
+var streamWriteTimeout = flag.Duration(
+	"streamWriteTimeout",
+	10*time.Second,
+	"drop /v1/stream clients that take longer than this to receive an event",
+)
+
+var historyRetention = flag.Duration(
+	"historyRetention",
+	24*time.Hour,
+	"how long to keep past collections for /v1/history, 0 to keep none",
+)
+
+var historyResolution = flag.Duration(
+	"historyResolution",
+	time.Minute,
+	"interval between the past collections kept for /v1/history",
+)
+
+var historyMaxSeries = flag.Int(
+	"historyMaxSeries",
+	1000,
+	"maximum number of distinct metrics kept for /v1/history",
+)
+
+var journalPath = flag.String(
+	"journalPath",
+	"",
+	"file to append every collection to, or to read from with the replay command",
+)
+
+var journalMaxSize = flag.Int64(
+	"journalMaxSize",
+	100*1024*1024,
+	"rotate the journal before it grows beyond this many bytes, 0 to never rotate by size",
+)
+
+var journalMaxAge = flag.Duration(
+	"journalMaxAge",
+	24*time.Hour,
+	"rotate the journal once its first collection is this old, 0 to never rotate by age",
+)
+
+var journalMaxFiles = flag.Int(
+	"journalMaxFiles",
+	7,
+	"number of rotated journal files to keep, 0 to keep them all",
+)
+
+var replaySpeed = flag.Float64(
+	"replaySpeed",
+	1,
+	"replay command: how many times faster than collected to replay the journal",
+)
+
+var replayLoop = flag.Bool(
+	"replayLoop",
+	false,
+	"replay command: start over at the end of the journal",
+)
+
+var dumpPath = flag.String(
+	"dump",
+	"",
+	"analyze command: file of an exported etcd keyspace to run the BBS instruments, and the -sloObjectives, against; anomaly scores need a series of collections and are left out",
+)
+
+var alertRules = flag.String(
+	"alertRules",
+	"",
+	"file of alerting rules, one per line, like 'TooManyPending: Tasks.Pending > 50 for 5m'",
+)
+
+var alertRepeatInterval = flag.Duration(
+	"alertRepeatInterval",
+	time.Hour,
+	"interval between notifications of an alert that keeps firing, or fires again",
+)
+
+var alertWebhookURL = flag.String(
+	"alertWebhookURL",
+	"",
+	"URL to post alert notifications to as JSON",
+)
+
+var alertWebhookSecret = flag.String(
+	"alertWebhookSecret",
+	"",
+	"key to sign alert notifications with in the X-Runtime-Metrics-Signature header",
+)
+
+var alertWebhookMaxRetries = flag.Int(
+	"alertWebhookMaxRetries",
+	3,
+	"number of times to retry a failed alert notification",
+)
+
+var alertWebhookRetryInterval = flag.Duration(
+	"alertWebhookRetryInterval",
+	time.Second,
+	"interval between retries of a failed alert notification",
+)
+
+var alertNATSSubject = flag.String(
+	"alertNATSSubject",
+	"",
+	"NATS subject, e.g. diego.runtime.alerts, to publish alert notifications on",
+)
+
+var anomalyMetrics = flag.String(
+	"anomalyMetrics",
+	"",
+	"comma separated metrics, like Tasks.Pending,Tasks.Running, to emit Anomalies.Score for",
+)
+
+var anomalyAlpha = flag.Float64(
+	"anomalyAlpha",
+	0.05,
+	"weight of each collection in the baseline of the anomaly scores, between 0 and 1",
+)
+
+var anomalyWarmUp = flag.Int(
+	"anomalyWarmUp",
+	30,
+	"number of collections to learn a baseline from before scoring anomalies",
+)
+
+var anomalyMinDeviation = flag.Float64(
+	"anomalyMinDeviation",
+	1,
+	"smallest deviation from the baseline anomaly scores are relative to",
+)
+
+var anomalySeasonal = flag.Bool(
+	"anomalySeasonal",
+	false,
+	"learn a separate anomaly baseline for every hour of the day",
+)
+
+var sloObjectives = flag.String(
+	"sloObjectives",
+	"",
+	"file of service level objectives, one per line, like 'TaskClaims: 99% of tasks claimed within 30s over 30d'",
+)
+
+var sloResolution = flag.Duration(
+	"sloResolution",
+	time.Minute,
+	"interval the events of the service level objectives are counted in",
+)
+
+var sloBurnRateWindows = flag.String(
+	"sloBurnRateWindows",
+	"5m,30m,1h,6h,3d",
+	"comma separated windows to compute the burn rates of the service level objectives over",
+)
+
+var leaderElection = flag.Bool(
+	"leaderElection",
+	false,
+	"only collect BBS metrics while holding the metrics server lock",
+)
+
+var lockTTL = flag.Duration(
+	"lockTTL",
+	10*time.Second,
+	"time to live of the metrics server lock, at least 1s",
+)
+
+var natsSubject = flag.String(
+	"natsSubject",
+	"",
+	"NATS subject, e.g. diego.runtime.metrics, to publish every collection on",
+)
+
+var statsdAddress = flag.String(
+	"statsdAddress",
+	"",
+	"StatsD address (ip:port) to push the runtime metrics to after every collection",
+)
+
+var statsdPrefix = flag.String(
+	"statsdPrefix",
+	"diego.runtime",
+	"prefix of the metric names pushed to StatsD",
+)
+
+var dogStatsDTags = flag.Bool(
+	"dogStatsDTags",
+	false,
+	"push metric tags to StatsD as DogStatsD tags",
+)
+
+var graphiteAddress = flag.String(
+	"graphiteAddress",
+	"",
+	"Graphite carbon plaintext address (ip:port) to push the runtime metrics to after every collection",
+)
+
+var graphitePrefix = flag.String(
+	"graphitePrefix",
+	"diego.runtime",
+	"prefix of the metric names pushed to Graphite",
+)
+
+var graphiteBufferSize = flag.Int(
+	"graphiteBufferSize",
+	10000,
+	"number of lines to keep while Graphite is unreachable",
+)
+
+var graphiteMinBackoff = flag.Duration(
+	"graphiteMinBackoff",
+	time.Second,
+	"initial delay before reconnecting to Graphite",
+)
+
+var graphiteMaxBackoff = flag.Duration(
+	"graphiteMaxBackoff",
+	time.Minute,
+	"maximum delay before reconnecting to Graphite",
+)
+
+var deployment = flag.String(
+	"deployment",
+	"",
+	"name of the deployment, added as a tag to exported metrics",
+)
+
+var influxDBURL = flag.String(
+	"influxDBURL",
+	"",
+	"InfluxDB /write URL, including the database, to push the runtime metrics to after every collection",
+)
+
+var influxDBFile = flag.String(
+	"influxDBFile",
+	"",
+	"file to append the runtime metrics to in InfluxDB line protocol, when not pushing to InfluxDB",
+)
+
+var influxDBBatchSize = flag.Int(
+	"influxDBBatchSize",
+	5000,
+	"maximum number of lines per write to InfluxDB",
+)
+
+var influxDBMaxRetries = flag.Int(
+	"influxDBMaxRetries",
+	3,
+	"number of times to retry a failed write to InfluxDB",
+)
+
+var influxDBRetryInterval = flag.Duration(
+	"influxDBRetryInterval",
+	time.Second,
+	"delay between retries of a failed write to InfluxDB",
+)
+
+var otlpEndpoint = flag.String(
+	"otlpEndpoint",
+	"",
+	"OTLP/HTTP metrics endpoint, e.g. http://otel-collector:4318/v1/metrics, to export the runtime metrics to after every collection",
+)
+
+var otlpMaxRetries = flag.Int(
+	"otlpMaxRetries",
+	3,
+	"number of times to retry a failed OTLP export",
+)
+
+var otlpRetryInterval = flag.Duration(
+	"otlpRetryInterval",
+	time.Second,
+	"delay between retries of a failed OTLP export",
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		flag.CommandLine.Parse(os.Args[2:])
+		replayJournal()
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "analyze" {
+		flag.CommandLine.Parse(os.Args[2:])
+		analyzeDump()
+		return
+	}
+
 	flag.Parse()
 
 	logger := cf_lager.New("runtime-metrics-server")
-	natsClient := initializeNatsClient(logger)
-	metricsBBS := initializeMetricsBBS(logger)
+	timeProvider := timeprovider.NewTimeProvider()
+	stats := server_stats.New(version, timeProvider)
+	natsClient := yagnats.NewClient()
+	store := initializeStore(logger, stats)
+	runtimeBBS := Bbs.NewBBS(store, timeProvider, logger)
 
 	cf_debug_server.Run()
 
+	var rules []alerting.Rule
+	if *alertRules != "" {
+		var err error
+		rules, err = alerting.LoadRules(*alertRules)
+		if err != nil {
+			logger.Fatal("failed-to-load-alert-rules", err)
+		}
+	}
+
+	var objectives []slo.Objective
+	if *sloObjectives != "" {
+		var err error
+		objectives, err = slo.LoadObjectives(*sloObjectives)
+		if err != nil {
+			logger.Fatal("failed-to-load-slo-objectives", err)
+		}
+	}
+
+	burnRateWindows, err := slo.ParseWindows(*sloBurnRateWindows)
+	if err != nil {
+		logger.Fatal("invalid-slo-burn-rate-windows", err)
+	}
+
+	watches, err := anomaly.ParseWatches(*anomalyMetrics)
+	if err != nil {
+		logger.Fatal("invalid-anomaly-metrics", err)
+	}
+
 	config := metrics_server.Config{
 		Port:     uint32(*port),
 		Username: *username,
 		Password: *password,
 		Index:    *index,
+
+		CollectionInterval:    *collectionInterval,
+		MaxBBSReadAge:         *maxBBSReadAge,
+		MaxCollectionAge:      *maxCollectionAge,
+		MaxCollectionDuration: *maxCollectionDuration,
+		AnnounceInterval:      *announceInterval,
+		StreamWriteTimeout:    *streamWriteTimeout,
+
+		History: history.Config{
+			Retention:  *historyRetention,
+			Resolution: *historyResolution,
+			MaxSeries:  *historyMaxSeries,
+		},
+
+		Journal: journal.Config{
+			Path:     *journalPath,
+			MaxSize:  *journalMaxSize,
+			MaxAge:   *journalMaxAge,
+			MaxFiles: *journalMaxFiles,
+		},
+
+		Alerting: alerting.Config{
+			Rules:          rules,
+			RepeatInterval: *alertRepeatInterval,
+			Webhook: alerting.WebhookConfig{
+				URL:           *alertWebhookURL,
+				Secret:        *alertWebhookSecret,
+				MaxRetries:    *alertWebhookMaxRetries,
+				RetryInterval: *alertWebhookRetryInterval,
+			},
+			NATSSubject: *alertNATSSubject,
+		},
+
+		SLO: slo.Config{
+			Objectives:      objectives,
+			Resolution:      *sloResolution,
+			BurnRateWindows: burnRateWindows,
+		},
+
+		Anomaly: anomaly.Config{
+			Watches:      watches,
+			Alpha:        *anomalyAlpha,
+			WarmUp:       *anomalyWarmUp,
+			MinDeviation: *anomalyMinDeviation,
+			Seasonal:     *anomalySeasonal,
+		},
+
+		LeaderElection: *leaderElection,
+		LockTTL:        *lockTTL,
+
+		NATSSubject: *natsSubject,
+
+		StatsDAddress: *statsdAddress,
+		StatsDPrefix:  *statsdPrefix,
+		DogStatsDTags: *dogStatsDTags,
+
+		Graphite: emitters.GraphiteConfig{
+			Address:    *graphiteAddress,
+			Prefix:     *graphitePrefix,
+			BufferSize: *graphiteBufferSize,
+			MinBackoff: *graphiteMinBackoff,
+			MaxBackoff: *graphiteMaxBackoff,
+		},
+
+		InfluxDB: emitters.InfluxDBConfig{
+			URL:           *influxDBURL,
+			File:          *influxDBFile,
+			Deployment:    *deployment,
+			BatchSize:     *influxDBBatchSize,
+			MaxRetries:    *influxDBMaxRetries,
+			RetryInterval: *influxDBRetryInterval,
+		},
+
+		OTLP: emitters.OTLPConfig{
+			Endpoint:      *otlpEndpoint,
+			MaxRetries:    *otlpMaxRetries,
+			RetryInterval: *otlpRetryInterval,
+		},
+	}
+
+	if *leaderElection && *lockTTL < time.Second {
+		logger.Fatal("invalid-lock-ttl", leader.ErrTTLTooShort)
+	}
+
+	err = config.History.Validate()
+	if err != nil {
+		logger.Fatal("invalid-history-config", err)
+	}
+
+	err = config.SLO.Validate()
+	if err != nil {
+		logger.Fatal("invalid-slo-config", err)
 	}
 
-	server := ifrit.Envoke(metrics_server.New(
+	err = config.Anomaly.Validate()
+	if err != nil {
+		logger.Fatal("invalid-anomaly-config", err)
+	}
+
+	metricsServer := metrics_server.New(
 		natsClient,
-		metricsBBS,
+		runtimeBBS,
+		store,
+		stats,
+		timeProvider,
 		logger,
 		config,
-	))
+	)
+
+	natsClient.ConnectedCallback = metricsServer.NATSReconnected
+	connectToNats(logger, natsClient)
+
+	server := ifrit.Envoke(metricsServer)
 
 	monitor := ifrit.Envoke(sigmon.New(server))
 
-	err := <-monitor.Wait()
+	err = <-monitor.Wait()
 	if err != nil {
-		log.Fatal("runtime-metrics-server exited with error: %s", err)
+		log.Fatalf("runtime-metrics-server exited with error: %s", err)
 	}
 }
 
-func initializeNatsClient(logger lager.Logger) *yagnats.Client {
-	natsClient := yagnats.NewClient()
-
+func connectToNats(logger lager.Logger, natsClient *yagnats.Client) {
 	natsMembers := []yagnats.ConnectionProvider{}
 
 	for _, addr := range strings.Split(*natsAddresses, ",") {
@@ -120,11 +586,9 @@ func initializeNatsClient(logger lager.Logger) *yagnats.Client {
 	if err != nil {
 		logger.Fatal("connecting-to-nats-failed", err)
 	}
-
-	return natsClient
 }
 
-func initializeMetricsBBS(logger lager.Logger) Bbs.MetricsBBS {
+func initializeStore(logger lager.Logger, stats *server_stats.Stats) storeadapter.StoreAdapter {
 	etcdAdapter := etcdstoreadapter.NewETCDStoreAdapter(
 		strings.Split(*etcdCluster, ","),
 		workerpool.NewWorkerPool(10),
@@ -134,5 +598,5 @@ func initializeMetricsBBS(logger lager.Logger) Bbs.MetricsBBS {
 		logger.Fatal("failed-to-connect-to-etcd", err)
 	}
 
-	return Bbs.NewMetricsBBS(etcdAdapter, timeprovider.NewTimeProvider(), logger)
+	return server_stats.NewTimedStoreAdapter(etcdAdapter, stats)
 }
@@ -3,19 +3,27 @@ package main_test
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
 	"github.com/cloudfoundry/gunk/natsrunner"
 	"github.com/cloudfoundry/storeadapter/storerunner/etcdstorerunner"
 	"github.com/cloudfoundry/yagnats"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
 )
 
 func TestRuntimeMetricsServer(t *testing.T) {
@@ -93,3 +101,59 @@ var _ = Describe("Main", func() {
 		Ω(resp.StatusCode).Should(Equal(200))
 	})
 })
+
+var _ = Describe("Replay", func() {
+	It("serves the journal on /varz", func() {
+		dir, err := ioutil.TempDir("", "journal")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		journalPath := filepath.Join(dir, "snapshots.journal")
+
+		snapshots := make(chan collection.Snapshot)
+		writer := ifrit.Envoke(journal.NewWriter(snapshots, journal.Config{Path: journalPath}, lagertest.NewTestLogger("test")))
+		snapshots <- collection.Snapshot{
+			Sequence:  1,
+			Timestamp: time.Now(),
+			Contexts: []instrumentation.Context{
+				{Name: "Tasks", Metrics: []instrumentation.Metric{{Name: "Pending", Value: 42}}},
+			},
+		}
+		writer.Signal(os.Interrupt)
+		Eventually(writer.Wait()).Should(Receive(BeNil()))
+
+		metricsServerPath, err := gexec.Build("github.com/cloudfoundry-incubator/runtime-metrics-server")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		session, err := gexec.Start(exec.Command(
+			metricsServerPath,
+			"replay",
+			"-journalPath", journalPath,
+			"-port", "5679",
+			"-username", "the-username",
+			"-password", "the-password",
+		), GinkgoWriter, GinkgoWriter)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer func() {
+			session.Kill().Wait()
+		}()
+
+		req, err := http.NewRequest("GET", "http://127.0.0.1:5679/varz", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.SetBasicAuth("the-username", "the-password")
+
+		var body []byte
+		Eventually(func() error {
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			body, err = ioutil.ReadAll(resp.Body)
+			return err
+		}, 5).ShouldNot(HaveOccurred())
+
+		Ω(string(body)).Should(ContainSubstring(`{"name":"Pending","value":42}`))
+	})
+})
@@ -0,0 +1,128 @@
+package metric
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+)
+
+type Type string
+
+const (
+	Gauge     Type = "gauge"
+	Counter   Type = "counter"
+	Histogram Type = "histogram"
+	Untyped   Type = "untyped"
+)
+
+// Value is stored in instrumentation.Metric.Value. Gauges and counters
+// marshal to a plain number, so /varz looks the same as it did for untyped
+// values; histograms marshal to their count, sum and buckets.
+type Value struct {
+	Type        Type
+	Unit        string
+	Description string
+
+	// Number is the value of gauges, counters and untyped metrics
+	Number float64
+
+	// Buckets, Sum and Count are only set on histograms
+	Buckets []Bucket
+	Sum     float64
+	Count   uint64
+}
+
+// Bucket counts the observations less than or equal to its upper bound; the
+// buckets of a histogram are cumulative and observations above the last
+// bound are only included in its Count
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+type histogramJSON struct {
+	Count   uint64   `json:"count"`
+	Sum     float64  `json:"sum"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+func NewGauge(value float64, unit string, description string) Value {
+	return Value{Type: Gauge, Unit: unit, Description: description, Number: value}
+}
+
+func NewCounter(value uint64, unit string, description string) Value {
+	return Value{Type: Counter, Unit: unit, Description: description, Number: float64(value)}
+}
+
+func NewHistogram(buckets []Bucket, sum float64, count uint64, unit string, description string) Value {
+	return Value{
+		Type:        Histogram,
+		Unit:        unit,
+		Description: description,
+		Buckets:     buckets,
+		Sum:         sum,
+		Count:       count,
+	}
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	if v.Type == Histogram {
+		buckets := v.Buckets
+		if buckets == nil {
+			buckets = []Bucket{}
+		}
+
+		return json.Marshal(histogramJSON{Count: v.Count, Sum: v.Sum, Buckets: buckets})
+	}
+
+	return json.Marshal(v.Number)
+}
+
+// Of returns the typed value of a metric; plain numbers are untyped and
+// anything else is not a metric value at all
+func Of(m instrumentation.Metric) (Value, bool) {
+	switch v := m.Value.(type) {
+	case Value:
+		return v, true
+	case *Value:
+		if v == nil {
+			return Value{}, false
+		}
+		return *v, true
+	}
+
+	number, ok := toFloat(m.Value)
+	if !ok {
+		return Value{}, false
+	}
+
+	return Value{Type: Untyped, Number: number}, true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
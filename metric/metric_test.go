@@ -0,0 +1,63 @@
+package metric_test
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Value", func() {
+	marshal := func(value interface{}) string {
+		payload, err := json.Marshal(instrumentation.Metric{Name: "Some", Value: value})
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(payload)
+	}
+
+	Describe("MarshalJSON", func() {
+		It("marshals gauges and counters like plain numbers", func() {
+			Ω(marshal(NewGauge(-1, "tasks", "Pending tasks"))).Should(MatchJSON(marshal(-1)))
+			Ω(marshal(NewCounter(42, "requests", "Scrapes"))).Should(MatchJSON(marshal(uint64(42))))
+		})
+
+		It("marshals histograms with their count, sum and buckets", func() {
+			histogram := NewHistogram([]Bucket{{UpperBound: 1, Count: 2}, {UpperBound: 5, Count: 3}}, 7.5, 4, "milliseconds", "Latency")
+
+			Ω(marshal(histogram)).Should(MatchJSON(`{
+				"name": "Some",
+				"value": {
+					"count": 4,
+					"sum": 7.5,
+					"buckets": [{"le": 1, "count": 2}, {"le": 5, "count": 3}]
+				}
+			}`))
+		})
+	})
+
+	Describe("Of", func() {
+		It("returns typed values", func() {
+			value, ok := Of(instrumentation.Metric{Value: NewCounter(3, "errors", "Errors")})
+			Ω(ok).Should(BeTrue())
+			Ω(value).Should(Equal(Value{Type: Counter, Unit: "errors", Description: "Errors", Number: 3}))
+		})
+
+		It("treats plain numbers as untyped", func() {
+			value, ok := Of(instrumentation.Metric{Value: uint64(3)})
+			Ω(ok).Should(BeTrue())
+			Ω(value).Should(Equal(Value{Type: Untyped, Number: 3}))
+		})
+
+		It("rejects values that are not numbers", func() {
+			_, ok := Of(instrumentation.Metric{Value: "three"})
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("rejects nil values", func() {
+			var value *Value
+			_, ok := Of(instrumentation.Metric{Value: value})
+			Ω(ok).Should(BeFalse())
+		})
+	})
+})
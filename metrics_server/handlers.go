@@ -0,0 +1,139 @@
+package metrics_server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/metricz/auth"
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/leader"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/prometheus"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+)
+
+type healthChecks struct {
+	health    *health_check.HealthCheck
+	liveness  *health_check.HealthCheck
+	readiness *health_check.HealthCheck
+
+	// role is reported by detailed health checks when electing a leader
+	role func() string
+}
+
+func (server *MetricsServer) handler(
+	checks healthChecks,
+	snapshots collection.Source,
+	events http.Handler,
+	api map[string]http.Handler,
+) http.Handler {
+	url := server.component.URL()
+	password, _ := url.User.Password()
+	basicAuth := auth.NewBasicAuth("Realm", []string{url.User.Username(), password})
+
+	mux := http.NewServeMux()
+	server.handle(mux, "/healthz", healthHandler(checks.health, checks.role, http.StatusOK))
+	server.handle(mux, "/livez", healthHandler(checks.liveness, checks.role, http.StatusServiceUnavailable))
+	server.handle(mux, "/readyz", healthHandler(checks.readiness, checks.role, http.StatusServiceUnavailable))
+	server.handle(mux, "/varz", basicAuth.Wrap(collection.VarzHandler(server.component.Name(), snapshots)))
+	server.handle(mux, "/metrics", basicAuth.Wrap(prometheusHandler(server.component.Name(), snapshots)))
+
+	for endpoint, handler := range api {
+		server.handle(mux, endpoint, basicAuth.Wrap(handler.ServeHTTP))
+	}
+
+	// streams last as long as the client stays connected, so aren't timed
+	mux.HandleFunc("/v1/stream", basicAuth.Wrap(events.ServeHTTP))
+
+	return mux
+}
+
+func (server *MetricsServer) handle(mux *http.ServeMux, endpoint string, handler http.HandlerFunc) {
+	mux.Handle(endpoint, server_stats.NewTimedHandler(endpoint, handler, server.stats))
+}
+
+func healthHandler(healthCheck *health_check.HealthCheck, role func() string, unhealthyStatus int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		results := healthCheck.Results()
+
+		healthy := true
+		for _, result := range results {
+			healthy = healthy && result.Healthy
+		}
+
+		status := http.StatusOK
+		if !healthy {
+			status = unhealthyStatus
+		}
+
+		if req.URL.Query().Get("detailed") == "true" {
+			report := healthReport{Healthy: healthy, Checks: results}
+			if role != nil {
+				report.Role = role()
+			}
+
+			writeJSON(w, status, report)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(status)
+		if healthy {
+			fmt.Fprintf(w, "ok")
+		} else {
+			fmt.Fprintf(w, "bad")
+		}
+	}
+}
+
+type healthReport struct {
+	Healthy bool                  `json:"healthy"`
+	Checks  []health_check.Result `json:"checks"`
+	Role    string                `json:"role,omitempty"`
+}
+
+func roleOf(elector *leader.Elector) func() string {
+	if elector == nil {
+		return nil
+	}
+
+	return func() string {
+		if elector.IsLeader() {
+			return "leader"
+		}
+
+		return "standby"
+	}
+}
+
+func prometheusHandler(name string, snapshots collection.Source) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		snapshot, _ := snapshots.Latest()
+
+		message, err := instrumentation.NewVarzMessage(name, snapshot.Instrumentables())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", prometheus.ContentType)
+		w.WriteHeader(http.StatusOK)
+		prometheus.Write(w, "diego", message)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(payload)
+}
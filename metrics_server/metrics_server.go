@@ -1,16 +1,35 @@
 package metrics_server
 
 import (
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/cloudfoundry-incubator/metricz"
-	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
 	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/anomaly"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/dashboard"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/detail"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/emitters"
 	"github.com/cloudfoundry-incubator/runtime-metrics-server/health_check"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
 	"github.com/cloudfoundry-incubator/runtime-metrics-server/instruments"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/leader"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/registrar"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/stream"
 	"github.com/cloudfoundry-incubator/runtime-schema/bbs"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/storeadapter"
 	"github.com/cloudfoundry/yagnats"
 	"github.com/pivotal-golang/lager"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/http_server"
 )
 
 type Config struct {
@@ -18,96 +37,350 @@ type Config struct {
 	Username string
 	Password string
 	Index    uint
+
+	CollectionInterval    time.Duration
+	MaxBBSReadAge         time.Duration
+	MaxCollectionAge      time.Duration
+	MaxCollectionDuration time.Duration
+	AnnounceInterval      time.Duration
+	StreamWriteTimeout    time.Duration
+
+	History history.Config
+	Journal journal.Config
+
+	Alerting alerting.Config
+	Anomaly  anomaly.Config
+	SLO      slo.Config
+
+	LeaderElection bool
+	LockTTL        time.Duration
+
+	NATSSubject string
+
+	StatsDAddress string
+	StatsDPrefix  string
+	DogStatsDTags bool
+
+	Graphite emitters.GraphiteConfig
+	InfluxDB emitters.InfluxDBConfig
+	OTLP     emitters.OTLPConfig
+}
+
+// BBS is what the metrics server reads: the counts of the instruments, the
+// tasks and LRPs behind the SLOs, the state of a single LRP and the work
+// assigned to the executors
+type BBS interface {
+	bbs.MetricsBBS
+
+	GetAllDesiredLRPs() ([]models.DesiredLRP, error)
+	GetAllActualLRPs() ([]models.ActualLRP, error)
+	GetAllExecutors() ([]models.ExecutorPresence, error)
+
+	detail.LRPBBS
 }
 
 type MetricsServer struct {
-	natsClient yagnats.NATSClient
-	bbs        bbs.MetricsBBS
-	logger     lager.Logger
-	config     Config
-	component  metricz.Component
+	natsClient   yagnats.NATSClient
+	bbs          BBS
+	store        storeadapter.StoreAdapter
+	stats        *server_stats.Stats
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+	config       Config
+	component    metricz.Component
+	reconnects   chan struct{}
 }
 
 func New(
 	natsClient yagnats.NATSClient,
-	bbs bbs.MetricsBBS,
+	bbs BBS,
+	store storeadapter.StoreAdapter,
+	stats *server_stats.Stats,
+	timeProvider timeprovider.TimeProvider,
 	logger lager.Logger,
 	config Config,
 ) *MetricsServer {
 	serverLogger := logger.Session("metrics-server")
 	return &MetricsServer{
-		natsClient: natsClient,
-		bbs:        bbs,
-		logger:     serverLogger,
-		config:     config,
+		natsClient:   server_stats.NewCountingNATSClient(natsClient, stats),
+		bbs:          bbs,
+		store:        store,
+		stats:        stats,
+		timeProvider: timeProvider,
+		logger:       serverLogger,
+		config:       config,
+		reconnects:   make(chan struct{}, 1),
 	}
 }
 
-func (server *MetricsServer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
-	registrar := collector_registrar.New(server.natsClient)
+func (server *MetricsServer) NATSReconnected() {
+	select {
+	case server.reconnects <- struct{}{}:
+	default:
+	}
+}
 
+func (server *MetricsServer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	// the component only provides the address and credentials to announce;
+	// its endpoints are served by the handler below
 	var err error
 	server.component, err = metricz.NewComponent(
 		server.logger,
 		"runtime",
 		server.config.Index,
-		health_check.New(),
+		nil,
 		server.config.Port,
 		[]string{server.config.Username, server.config.Password},
-		[]instrumentation.Instrumentable{
-			instruments.NewTaskInstrument(server.bbs),
-			instruments.NewServiceRegistryInstrument(server.bbs),
-		},
+		nil,
 	)
-
-	err = registrar.RegisterWithCollector(server.component)
 	if err != nil {
 		return err
 	}
 
-	close(ready)
+	var elector *leader.Elector
+	if server.config.LeaderElection {
+		elector = leader.NewElector(
+			server.store,
+			server.component.URL().Host,
+			server.config.LockTTL,
+			server.stats,
+			server.logger,
+		)
+	}
 
-	serverStopped := make(chan error)
-	go func() {
-		serverStopped <- server.component.StartMonitoringEndpoints()
-	}()
+	checkedBBS := health_check.NewBBSCheck(server.bbs, server.config.MaxBBSReadAge, server.timeProvider)
+	bbsInstruments := []instrumentation.Instrumentable{
+		server_stats.NewTimedInstrument(instruments.NewTaskInstrument(checkedBBS), server.stats),
+		server_stats.NewTimedInstrument(instruments.NewServiceRegistryInstrument(checkedBBS), server.stats),
+	}
 
-	select {
-	case <-signals:
-		server.component.StopMonitoringEndpoints()
-		return nil
-	case err := <-serverStopped:
-		return err
+	// the endpoints beyond the component's, served with basic auth
+	api := map[string]http.Handler{}
+
+	if len(server.config.SLO.Objectives) > 0 {
+		objectives, err := slo.New(server.bbs, server.config.SLO, server.timeProvider, server.logger)
+		if err != nil {
+			return err
+		}
+
+		bbsInstruments = append(bbsInstruments, server_stats.NewTimedInstrument(objectives, server.stats))
+		api["/v1/slo"] = objectives
 	}
-}
 
-/*
-func (server *MetricsServer) Listen() error {
-	registrar := collector_registrar.New(server.natsClient)
+	// the scores are emitted after the instruments they are computed from,
+	// so they can be alerted on like any other metric
+	if len(server.config.Anomaly.Watches) > 0 {
+		detector, err := anomaly.New(server.config.Anomaly, server.timeProvider)
+		if err != nil {
+			return err
+		}
 
-	var err error
-	server.component, err = metricz.NewComponent(
+		for i, instrument := range bbsInstruments {
+			bbsInstruments[i] = detector.Observe(instrument)
+		}
+		bbsInstruments = append(bbsInstruments, detector)
+	}
+
+	// followers don't read the BBS, so its check only applies to the leader
+	var bbsCheck health_check.Check = checkedBBS
+
+	if elector != nil {
+		for i, instrument := range bbsInstruments {
+			bbsInstruments[i] = leader.NewLeaderOnlyInstrument(instrument, elector)
+		}
+		bbsCheck = leader.NewLeaderOnlyCheck(bbsCheck, elector)
+	}
+
+	collectionLoop := collection.New(
+		append(bbsInstruments, instruments.NewMetricsServerInstrument(server.stats)),
+		server.config.CollectionInterval,
+		server.timeProvider,
 		server.logger,
-		"runtime",
-		server.config.Index,
-		health_check.New(),
-		server.config.Port,
-		[]string{server.config.Username, server.config.Password},
-		[]instrumentation.Instrumentable{
-			instruments.NewTaskInstrument(server.bbs),
-			instruments.NewServiceRegistryInstrument(server.bbs),
-		},
 	)
 
-	err = registrar.RegisterWithCollector(server.component)
-	if err != nil {
-		return err
+	collectionCheck := health_check.NewCollectionCheck(collectionLoop, server.config.MaxCollectionAge, server.timeProvider)
+	healthCheck := health_check.New(bbsCheck, health_check.NewNATSCheck(server.natsClient), collectionCheck)
+	livenessCheck := health_check.New(
+		health_check.NewLivenessCheck(collectionLoop, server.config.MaxCollectionDuration, server.timeProvider),
+	)
+	readinessChecks := []health_check.Check{bbsCheck, collectionCheck}
+	if elector != nil {
+		readinessChecks = append(readinessChecks, leader.NewLeaderCheck(elector))
 	}
+	readinessCheck := health_check.New(readinessChecks...)
 
-	server.component.StartMonitoringEndpoints()
-}
+	emitterRunners := []ifrit.Runner{}
+	if server.config.NATSSubject != "" {
+		emitterRunners = append(emitterRunners, emitters.NewNATS(
+			collectionLoop.Subscribe(),
+			server.natsClient,
+			server.config.NATSSubject,
+			server.component.Name(),
+			server.config.Index,
+			server.logger,
+		))
+	}
+
+	if server.config.StatsDAddress != "" {
+		emitterRunners = append(emitterRunners, emitters.NewStatsD(
+			collectionLoop.Subscribe(),
+			server.config.StatsDAddress,
+			server.config.StatsDPrefix,
+			server.config.DogStatsDTags,
+			server.logger,
+		))
+	}
+
+	if server.config.Graphite.Address != "" {
+		graphite, err := emitters.NewGraphite(
+			collectionLoop.Subscribe(),
+			server.config.Graphite,
+			server.logger,
+		)
+		if err != nil {
+			return err
+		}
+
+		emitterRunners = append(emitterRunners, graphite)
+	}
+
+	if server.config.InfluxDB.URL != "" || server.config.InfluxDB.File != "" {
+		influxDBConfig := server.config.InfluxDB
+		influxDBConfig.Index = server.config.Index
+
+		emitterRunners = append(emitterRunners, emitters.NewInfluxDB(
+			collectionLoop.Subscribe(),
+			influxDBConfig,
+			server.logger,
+		))
+	}
+
+	if server.config.OTLP.Endpoint != "" {
+		otlpConfig := server.config.OTLP
+		otlpConfig.Type = server.component.Name()
+		otlpConfig.Index = server.config.Index
+		otlpConfig.Host = server.component.URL().Host
+		otlpConfig.StartTime = server.stats.StartedAt()
+
+		emitterRunners = append(emitterRunners, emitters.NewOTLP(
+			collectionLoop.Subscribe(),
+			otlpConfig,
+			server.logger,
+		))
+	}
+
+	api["/v1/tasks"] = detail.NewTasksHandler(checkedBBS, server.timeProvider, server.logger)
+	api["/v1/lrps/"] = detail.NewLRPsHandler(server.bbs, server.logger)
+	api["/v1/executors"] = detail.NewExecutorsHandler(server.bbs, server.timeProvider, server.logger)
+
+	// the dashboard only draws sparklines when history is kept
+	var sparklines dashboard.History
+	if server.config.History.Retention > 0 {
+		pastCollections, err := history.New(
+			collectionLoop.Subscribe(),
+			server.config.History,
+			server.timeProvider,
+			server.logger,
+		)
+		if err != nil {
+			return err
+		}
+
+		emitterRunners = append(emitterRunners, pastCollections)
+		api["/v1/history"] = pastCollections
+		sparklines = pastCollections
+	}
+
+	api["/dashboard"] = dashboard.New(
+		server.component.Name(),
+		collectionLoop,
+		server.store,
+		sparklines,
+		roleOf(elector),
+		server.timeProvider,
+		server.logger,
+	)
+
+	if len(server.config.Alerting.Rules) > 0 {
+		var notifier *alerting.Notifier
+		if server.config.Alerting.Webhook.URL != "" || server.config.Alerting.NATSSubject != "" {
+			notifier = alerting.NewNotifier(server.config.Alerting.RepeatInterval, server.logger)
+		}
+
+		// the sinks are envoked before the engine so they are stopped after it
+		if server.config.Alerting.Webhook.URL != "" {
+			emitterRunners = append(emitterRunners, alerting.NewWebhook(
+				notifier.Subscribe(),
+				server.config.Alerting.Webhook,
+				server.logger,
+			))
+		}
+
+		if server.config.Alerting.NATSSubject != "" {
+			emitterRunners = append(emitterRunners, alerting.NewNATS(
+				notifier.Subscribe(),
+				server.natsClient,
+				server.config.Alerting.NATSSubject,
+				server.logger,
+			))
+		}
+
+		alerts := alerting.NewEngine(
+			collectionLoop.Subscribe(),
+			server.config.Alerting.Rules,
+			notifier,
+			server.logger,
+		)
+		emitterRunners = append(emitterRunners, alerts)
+		api["/v1/alerts"] = alerts
+	}
+
+	if server.config.Journal.Path != "" {
+		emitterRunners = append(emitterRunners, journal.NewWriter(
+			collectionLoop.Subscribe(),
+			server.config.Journal,
+			server.logger,
+		))
+	}
+
+	eventStream := stream.New(collectionLoop, server.config.StreamWriteTimeout, server.logger)
 
-func (server *MetricsServer) Stop() {
-	server.component.StopMonitoringEndpoints()
+	running := processes{}
+	if elector != nil {
+		running.envoke(elector)
+	}
+	running.envoke(collectionLoop)
+	for _, emitter := range emitterRunners {
+		running.envoke(emitter)
+	}
+	running.envoke(http_server.New(
+		server.component.URL().Host,
+		server.handler(healthChecks{
+			health:    healthCheck,
+			liveness:  livenessCheck,
+			readiness: readinessCheck,
+			role:      roleOf(elector),
+		}, collectionLoop, eventStream, api),
+	))
+	// stopped before the http server, which waits for the open streams
+	running.envoke(eventStream)
+	running.envoke(registrar.New(
+		server.natsClient,
+		server.component,
+		server.config.AnnounceInterval,
+		server.reconnects,
+		server.stats,
+		server.timeProvider,
+		server.logger,
+	))
+
+	close(ready)
+
+	select {
+	case signal := <-signals:
+		return running.stop(signal)
+	case err := <-running.exited():
+		running.stop(os.Interrupt)
+		return err
+	}
 }
-*/
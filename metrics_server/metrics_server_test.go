@@ -1,34 +1,106 @@
 package metrics_server_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/cloudfoundry-incubator/cf-lager"
 	"github.com/cloudfoundry-incubator/metricz/instrumentation"
 	"github.com/cloudfoundry-incubator/metricz/localip"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/alerting"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/anomaly"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/history"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
 	. "github.com/cloudfoundry-incubator/runtime-metrics-server/metrics_server"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
 	"github.com/cloudfoundry-incubator/runtime-schema/bbs/fake_bbs"
 	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/cloudfoundry/storeadapter/fakestoreadapter"
 	"github.com/cloudfoundry/yagnats"
 	"github.com/cloudfoundry/yagnats/fakeyagnats"
 	. "github.com/onsi/ginkgo"
-	"github.com/onsi/ginkgo/config"
+	ginkgoconfig "github.com/onsi/ginkgo/config"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
 	"github.com/pivotal-golang/lager"
 	"github.com/tedsuo/ifrit"
 )
 
+type fakeBBS struct {
+	*fake_bbs.FakeMetricsBBS
+
+	desiredLRPs []models.DesiredLRP
+	actualLRPs  []models.ActualLRP
+	executors   []models.ExecutorPresence
+}
+
+func (f *fakeBBS) GetAllExecutors() ([]models.ExecutorPresence, error) {
+	return f.executors, nil
+}
+
+func (f *fakeBBS) GetDesiredLRPByProcessGuid(processGuid string) (models.DesiredLRP, error) {
+	for _, desired := range f.desiredLRPs {
+		if desired.ProcessGuid == processGuid {
+			return desired, nil
+		}
+	}
+
+	return models.DesiredLRP{}, storeadapter.ErrorKeyNotFound
+}
+
+func (f *fakeBBS) GetActualLRPsByProcessGuid(processGuid string) ([]models.ActualLRP, error) {
+	actuals := []models.ActualLRP{}
+	for _, actual := range f.actualLRPs {
+		if actual.ProcessGuid == processGuid {
+			actuals = append(actuals, actual)
+		}
+	}
+
+	return actuals, nil
+}
+
+func (f *fakeBBS) GetAllLRPStartAuctions() ([]models.LRPStartAuction, error) {
+	return []models.LRPStartAuction{}, nil
+}
+
+func (f *fakeBBS) GetAllLRPStopAuctions() ([]models.LRPStopAuction, error) {
+	return []models.LRPStopAuction{}, nil
+}
+
+func (f *fakeBBS) GetAllStopLRPInstances() ([]models.StopLRPInstance, error) {
+	return []models.StopLRPInstance{}, nil
+}
+
+func (f *fakeBBS) GetAllDesiredLRPs() ([]models.DesiredLRP, error) {
+	return f.desiredLRPs, nil
+}
+
+func (f *fakeBBS) GetAllActualLRPs() ([]models.ActualLRP, error) {
+	return f.actualLRPs, nil
+}
+
 var _ = Describe("Metrics Server", func() {
 	var (
 		fakenats   *fakeyagnats.FakeYagnats
 		logger     lager.Logger
-		bbs        *fake_bbs.FakeMetricsBBS
+		bbs        *fakeBBS
+		store      *fakestoreadapter.FakeStoreAdapter
+		config     Config
+		stats      *server_stats.Stats
 		port       uint32
 		server     *MetricsServer
 		httpClient *http.Client
@@ -36,23 +108,42 @@ var _ = Describe("Metrics Server", func() {
 
 	BeforeEach(func() {
 		fakenats = fakeyagnats.New()
-		bbs = fake_bbs.NewFakeMetricsBBS()
+		bbs = &fakeBBS{FakeMetricsBBS: fake_bbs.NewFakeMetricsBBS()}
+		store = fakestoreadapter.New()
+		stats = server_stats.New("some-version", faketimeprovider.New(time.Unix(1000, 0)))
 		logger = cf_lager.New("fake-logger")
 
-		port = 34567 + uint32(config.GinkgoConfig.ParallelNode)
+		port = 34567 + uint32(ginkgoconfig.GinkgoConfig.ParallelNode)
 
-		server = New(fakenats, bbs, logger, Config{
+		config = Config{
 			Port:     port,
 			Username: "the-username",
 			Password: "the-password",
 			Index:    3,
-		})
+
+			CollectionInterval:    time.Minute,
+			MaxBBSReadAge:         time.Minute,
+			MaxCollectionAge:      time.Minute,
+			MaxCollectionDuration: time.Minute,
+			AnnounceInterval:      time.Minute,
+			StreamWriteTimeout:    time.Second,
+
+			History: history.Config{
+				Retention:  time.Hour,
+				Resolution: time.Minute,
+				MaxSeries:  100,
+			},
+		}
 
 		httpClient = &http.Client{
 			Transport: &http.Transport{},
 		}
 	})
 
+	JustBeforeEach(func() {
+		server = New(fakenats, bbs, store, stats, timeprovider.NewTimeProvider(), logger, config)
+	})
+
 	Describe("Envoke", func() {
 		var (
 			payloadChan chan []byte
@@ -69,7 +160,9 @@ var _ = Describe("Metrics Server", func() {
 			fakenats.Subscribe("vcap.component.announce", func(msg *yagnats.Message) {
 				payloadChan <- msg.Payload
 			})
+		})
 
+		JustBeforeEach(func() {
 			process = ifrit.Envoke(server)
 		})
 
@@ -98,6 +191,29 @@ var _ = Describe("Metrics Server", func() {
 			close(done)
 		}, 3)
 
+		It("announces again when NATS reconnects", func(done Done) {
+			<-payloadChan
+
+			server.NATSReconnected()
+
+			<-payloadChan
+
+			close(done)
+		}, 3)
+
+		Context("when announcing fails", func() {
+			BeforeEach(func() {
+				fakenats.WhenPublishing("vcap.component.announce", func(*yagnats.Message) error {
+					return errors.New("nats is down")
+				})
+			})
+
+			It("keeps running and records the failure", func() {
+				Consistently(process.Wait()).ShouldNot(Receive())
+				Ω(stats.RegistrationFailures()).Should(Equal(uint64(1)))
+			})
+		})
+
 		Describe("the varz endpoint", func() {
 			var varzMessage instrumentation.VarzMessage
 
@@ -182,6 +298,48 @@ var _ = Describe("Metrics Server", func() {
 						},
 					}))
 				})
+
+				It("reports on the metrics server itself", func() {
+					Ω(varzMessage.Contexts[2].Name).Should(Equal("MetricsServer"))
+					Ω(varzMessage.Contexts[2].Metrics).Should(ContainElement(instrumentation.Metric{
+						Name:  "BuildInfo",
+						Value: float64(1),
+						Tags:  map[string]interface{}{"version": "some-version"},
+					}))
+				})
+
+				It("records the collection of each instrument", func() {
+					Ω(stats.Collections()).Should(HaveKey("Tasks"))
+					Ω(stats.Collections()).Should(HaveKey("ServiceRegistrations"))
+				})
+
+				It("records the scrape", func() {
+					Eventually(func() uint64 {
+						return stats.Scrapes()["/varz"].Count
+					}).Should(Equal(uint64(1)))
+				})
+
+				Context("when detecting anomalies", func() {
+					BeforeEach(func() {
+						config.Anomaly = anomaly.Config{
+							Watches:      []anomaly.Watch{{Context: "Tasks", Metric: "Pending"}},
+							Alpha:        0.1,
+							WarmUp:       10,
+							MinDeviation: 1,
+						}
+					})
+
+					It("reports the anomaly score of the watched metrics after the instruments", func() {
+						tags := map[string]interface{}{"context": "Tasks", "metric": "Pending"}
+						Ω(varzMessage.Contexts[2]).Should(Equal(instrumentation.Context{
+							Name: "Anomalies",
+							Metrics: []instrumentation.Metric{
+								{Name: "Score", Value: float64(0), Tags: tags},
+								{Name: "Baseline", Value: float64(3), Tags: tags},
+							},
+						}))
+					})
+				})
 			})
 
 			Context("when there is an error reading from the store", func() {
@@ -219,6 +377,442 @@ var _ = Describe("Metrics Server", func() {
 			})
 		})
 
+		Describe("the metrics endpoint", func() {
+			BeforeEach(func() {
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{State: models.TaskStatePending},
+					models.Task{State: models.TaskStatePending},
+					models.Task{State: models.TaskStateRunning},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/metrics", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("serves the metrics in the Prometheus text format", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/metrics", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+				Ω(response.Header.Get("Content-Type")).Should(Equal("text/plain; version=0.0.4"))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(string(body)).Should(ContainSubstring(`diego_tasks{state="pending"} 2`))
+				Ω(string(body)).Should(ContainSubstring(`diego_tasks{state="running"} 1`))
+				Ω(string(body)).Should(ContainSubstring(`diego_metrics_server_build_info{version="some-version"} 1`))
+				Ω(string(body)).Should(ContainSubstring("go_goroutines "))
+			})
+		})
+
+		Describe("the history endpoint", func() {
+			BeforeEach(func() {
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{State: models.TaskStatePending},
+					models.Task{State: models.TaskStatePending},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/history?context=Tasks&metric=Pending", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("returns the past collections of a metric", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/history?context=Tasks&metric=Pending", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				var body []byte
+				Eventually(func() string {
+					response, err := httpClient.Do(request)
+					Ω(err).ShouldNot(HaveOccurred())
+					defer response.Body.Close()
+
+					Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+					body, err = ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+					return string(body)
+				}).Should(ContainSubstring(`"points"`))
+
+				Ω(string(body)).Should(ContainSubstring(`"min":2,"max":2,"avg":2,"count":1`))
+			})
+		})
+
+		Describe("the alerts endpoint", func() {
+			BeforeEach(func() {
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{State: models.TaskStatePending},
+					models.Task{State: models.TaskStatePending},
+				}
+
+				rule, err := alerting.ParseRule("TooManyPending: Tasks.Pending > 1")
+				Ω(err).ShouldNot(HaveOccurred())
+				config.Alerting = alerting.Config{
+					Rules:          []alerting.Rule{rule},
+					RepeatInterval: time.Hour,
+					NATSSubject:    "diego.runtime.alerts",
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/alerts", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("lists the alerts of the rules that hold", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/alerts", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				Eventually(func() string {
+					response, err := httpClient.Do(request)
+					Ω(err).ShouldNot(HaveOccurred())
+					defer response.Body.Close()
+
+					Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+					return string(body)
+				}).Should(ContainSubstring(`"rule":"TooManyPending","expression":"Tasks.Pending \u003e 1","state":"firing"`))
+			})
+
+			It("notifies the sinks of firing alerts", func() {
+				Eventually(func() []yagnats.Message {
+					return fakenats.PublishedMessages("diego.runtime.alerts")
+				}).Should(HaveLen(1))
+
+				notification := map[string]interface{}{}
+				err := json.Unmarshal(fakenats.PublishedMessages("diego.runtime.alerts")[0].Payload, &notification)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(notification["rule"]).Should(Equal("TooManyPending"))
+				Ω(notification["state"]).Should(Equal("firing"))
+			})
+		})
+
+		Describe("the slo endpoint", func() {
+			BeforeEach(func() {
+				bbs.desiredLRPs = []models.DesiredLRP{{ProcessGuid: "web", Instances: 2}}
+				bbs.actualLRPs = []models.ActualLRP{
+					{ProcessGuid: "web", Index: 0, State: models.ActualLRPStateRunning},
+				}
+
+				objective, err := slo.ParseObjective("LRPs: 75% of lrp instances running over 30d")
+				Ω(err).ShouldNot(HaveOccurred())
+				config.SLO = slo.Config{
+					Objectives:      []slo.Objective{objective},
+					Resolution:      time.Minute,
+					BurnRateWindows: []time.Duration{time.Hour},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/slo", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("reports the status of the objectives", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/slo", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				Eventually(func() string {
+					response, err := httpClient.Do(request)
+					Ω(err).ShouldNot(HaveOccurred())
+					defer response.Body.Close()
+
+					Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+					return string(body)
+				}).Should(ContainSubstring(`"name":"LRPs","expression":"75% of lrp instances running over 30d","target":0.75,"window":2592000,"good":1,"total":2,"compliance":0.5,"error_budget_remaining":-1`))
+			})
+
+			It("emits them in the SLOs context", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/varz", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				defer response.Body.Close()
+
+				varzMessage := instrumentation.VarzMessage{}
+				err = json.NewDecoder(response.Body).Decode(&varzMessage)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(varzMessage.Contexts).Should(ContainElement(instrumentation.Context{
+					Name: "SLOs",
+					Metrics: []instrumentation.Metric{
+						{Name: "Compliance", Value: 0.5, Tags: map[string]interface{}{"slo": "LRPs"}},
+						{Name: "ErrorBudgetRemaining", Value: float64(-1), Tags: map[string]interface{}{"slo": "LRPs"}},
+						{Name: "BurnRate", Value: float64(2), Tags: map[string]interface{}{"slo": "LRPs", "window": "1h0m0s"}},
+					},
+				}))
+			})
+		})
+
+		Describe("the tasks endpoint", func() {
+			BeforeEach(func() {
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{Guid: "pending-task", State: models.TaskStatePending},
+					models.Task{Guid: "running-task", State: models.TaskStateRunning},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/tasks", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("lists the tasks in the BBS", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/tasks?state=running", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				defer response.Body.Close()
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(body)).Should(ContainSubstring(`"total":1`))
+				Ω(string(body)).Should(ContainSubstring(`"guid":"running-task"`))
+			})
+		})
+
+		Describe("the lrps endpoint", func() {
+			BeforeEach(func() {
+				bbs.desiredLRPs = []models.DesiredLRP{{ProcessGuid: "web", Instances: 2}}
+				bbs.actualLRPs = []models.ActualLRP{
+					{ProcessGuid: "web", InstanceGuid: "instance-0", Index: 0, State: models.ActualLRPStateRunning},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/lrps/web", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("describes the lrp", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/lrps/web", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				defer response.Body.Close()
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(body)).Should(ContainSubstring(`"instance_guid":"instance-0"`))
+				Ω(string(body)).Should(ContainSubstring(`"indices_to_start":[1]`))
+			})
+		})
+
+		Describe("the executors endpoint", func() {
+			BeforeEach(func() {
+				bbs.executors = []models.ExecutorPresence{
+					{ExecutorID: "executor-1", Stack: "lucid64"},
+					{ExecutorID: "executor-2", Stack: "lucid64"},
+				}
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					{Guid: "running-task", State: models.TaskStateRunning, ExecutorID: "executor-1"},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/executors", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("lists the executors and flags the idle ones", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/executors", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				defer response.Body.Close()
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(body)).Should(ContainSubstring(`"running_tasks":["running-task"]`))
+				Ω(string(body)).Should(ContainSubstring(`"idle":["executor-2"]`))
+			})
+		})
+
+		Describe("the dashboard", func() {
+			BeforeEach(func() {
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{State: models.TaskStatePending},
+				}
+			})
+
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/dashboard", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("renders the latest collection with sparklines from the history", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/dashboard", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				Eventually(func() string {
+					response, err := httpClient.Do(request)
+					Ω(err).ShouldNot(HaveOccurred())
+					defer response.Body.Close()
+
+					Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+					return string(body)
+				}).Should(MatchRegexp(`<tr><td>Pending</td><td class="value">1</td><td><svg`))
+			})
+		})
+
+		Describe("the stream endpoint", func() {
+			It("requires basic auth", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/v1/stream", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+			})
+
+			It("streams the latest snapshot and lets the server stop while streaming", func() {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/v1/stream?context=MetricsServer", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				defer response.Body.Close()
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+				Ω(response.Header.Get("Content-Type")).Should(Equal("text/event-stream"))
+
+				reader := bufio.NewReader(response.Body)
+				var data string
+				for !strings.HasPrefix(data, "data: ") {
+					data, err = reader.ReadString('\n')
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+
+				Ω(data).Should(ContainSubstring(`"name":"MetricsServer"`))
+				Ω(data).ShouldNot(ContainSubstring(`"name":"Tasks"`))
+
+				process.Signal(syscall.SIGTERM)
+				Eventually(process.Wait()).Should(Receive())
+			})
+		})
+
+		Describe("the livez endpoint", func() {
+			It("returns success", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/livez", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(string(body)).Should(Equal("ok"))
+			})
+
+			Context("when reading from the BBS fails", func() {
+				BeforeEach(func() {
+					bbs.GetAllTasksReturns.Err = errors.New("etcd is down")
+				})
+
+				It("still returns success", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/livez", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(response.StatusCode).Should(Equal(http.StatusOK))
+				})
+			})
+		})
+
+		Describe("the readyz endpoint", func() {
+			It("returns success after the first collection", func() {
+				response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/readyz", myIP, port))
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+				body, err := ioutil.ReadAll(response.Body)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(string(body)).Should(Equal("ok"))
+			})
+
+			Context("when reading from the BBS fails", func() {
+				BeforeEach(func() {
+					bbs.GetAllTasksReturns.Err = errors.New("etcd is down")
+				})
+
+				It("returns service unavailable", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/readyz", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(response.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(string(body)).Should(Equal("bad"))
+				})
+
+				It("reports the failing checks in detail", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/readyz?detailed=true", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(response.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(body).Should(MatchJSON(`{
+						"healthy": false,
+						"checks": [
+							{
+								"name": "bbs",
+								"healthy": false,
+								"error": "no successful GetAllTasks from the BBS: etcd is down",
+								"last_error": "no successful GetAllTasks from the BBS: etcd is down"
+							},
+							{"name": "collection", "healthy": true}
+						]
+					}`))
+				})
+			})
+		})
+
 		Describe("the healthz endpoint", func() {
 			It("returns success", func() {
 				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/healthz", myIP, port), nil)
@@ -234,6 +828,284 @@ var _ = Describe("Metrics Server", func() {
 
 				Ω(string(body)).Should(Equal("ok"))
 			})
+
+			Context("when reading from the BBS fails", func() {
+				BeforeEach(func() {
+					bbs.GetAllTasksReturns.Err = errors.New("etcd is down")
+				})
+
+				It("reports bad health", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/healthz", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(string(body)).Should(Equal("bad"))
+				})
+
+				It("reports the failing check in detail", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/healthz?detailed=true", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(response.StatusCode).Should(Equal(200))
+					Ω(response.Header.Get("Content-Type")).Should(Equal("application/json"))
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(body).Should(MatchJSON(`{
+						"healthy": false,
+						"checks": [
+							{
+								"name": "bbs",
+								"healthy": false,
+								"error": "no successful GetAllTasks from the BBS: etcd is down",
+								"last_error": "no successful GetAllTasks from the BBS: etcd is down"
+							},
+							{"name": "nats", "healthy": true},
+							{"name": "collection", "healthy": true}
+						]
+					}`))
+				})
+			})
+		})
+
+		Context("when publishing snapshots over NATS", func() {
+			BeforeEach(func() {
+				config.NATSSubject = "diego.runtime.metrics"
+			})
+
+			It("publishes every collection with its sequence number", func() {
+				Eventually(func() []yagnats.Message {
+					return fakenats.PublishedMessages("diego.runtime.metrics")
+				}).ShouldNot(BeEmpty())
+
+				publication := map[string]interface{}{}
+				err := json.Unmarshal(fakenats.PublishedMessages("diego.runtime.metrics")[0].Payload, &publication)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(publication["name"]).Should(Equal("runtime"))
+				Ω(publication["index"]).Should(Equal(float64(3)))
+				Ω(publication["sequence"]).Should(Equal(float64(1)))
+				Ω(publication).Should(HaveKey("timestamp"))
+				Ω(publication["contexts"]).Should(HaveLen(3))
+			})
+		})
+
+		Context("when journaling", func() {
+			var dir string
+
+			BeforeEach(func() {
+				var err error
+				dir, err = ioutil.TempDir("", "journal")
+				Ω(err).ShouldNot(HaveOccurred())
+
+				config.Journal = journal.Config{Path: filepath.Join(dir, "snapshots.journal")}
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("appends every collection to the journal", func() {
+				Eventually(func() uint64 {
+					file, err := os.Open(config.Journal.Path)
+					if err != nil {
+						return 0
+					}
+					defer file.Close()
+
+					snapshot, _ := journal.NewReader(file).Next()
+					return snapshot.Sequence
+				}).Should(Equal(uint64(1)))
+			})
+		})
+
+		Context("when pushing to StatsD", func() {
+			var listener net.PacketConn
+
+			BeforeEach(func() {
+				var err error
+				listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+				Ω(err).ShouldNot(HaveOccurred())
+
+				config.StatsDAddress = listener.LocalAddr().String()
+				config.StatsDPrefix = "diego.runtime"
+
+				bbs.GetAllTasksReturns.Models = []models.Task{
+					models.Task{State: models.TaskStatePending},
+				}
+			})
+
+			AfterEach(func() {
+				listener.Close()
+			})
+
+			It("pushes every collection", func() {
+				buffer := make([]byte, 65536)
+				listener.SetReadDeadline(time.Now().Add(time.Second))
+				n, _, err := listener.ReadFrom(buffer)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(string(buffer[:n])).Should(ContainSubstring("diego.runtime.Tasks.Pending:1|g\n"))
+			})
+		})
+
+		Context("when exporting over OTLP", func() {
+			var (
+				collector *ghttp.Server
+				payloads  chan map[string]interface{}
+			)
+
+			BeforeEach(func() {
+				collector = ghttp.NewServer()
+				collector.AllowUnhandledRequests = true
+				payloads = make(chan map[string]interface{}, 10)
+
+				collector.RouteToHandler("POST", "/v1/metrics", func(w http.ResponseWriter, req *http.Request) {
+					payload := map[string]interface{}{}
+					json.NewDecoder(req.Body).Decode(&payload)
+					payloads <- payload
+				})
+
+				config.OTLP.Endpoint = collector.URL() + "/v1/metrics"
+			})
+
+			AfterEach(func() {
+				collector.Close()
+			})
+
+			It("exports with the type, index and host as resource attributes", func() {
+				var payload map[string]interface{}
+				Eventually(payloads).Should(Receive(&payload))
+
+				resource := payload["resourceMetrics"].([]interface{})[0].(map[string]interface{})["resource"]
+				Ω(resource).Should(Equal(map[string]interface{}{
+					"attributes": []interface{}{
+						map[string]interface{}{"key": "type", "value": map[string]interface{}{"stringValue": "runtime"}},
+						map[string]interface{}{"key": "index", "value": map[string]interface{}{"intValue": "3"}},
+						map[string]interface{}{"key": "host", "value": map[string]interface{}{"stringValue": fmt.Sprintf("%s:%d", myIP, port)}},
+					},
+				}))
+			})
+		})
+
+		Context("when electing a leader", func() {
+			varz := func() instrumentation.VarzMessage {
+				request, _ := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/varz", myIP, port), nil)
+				request.SetBasicAuth("the-username", "the-password")
+
+				response, err := httpClient.Do(request)
+				Ω(err).ShouldNot(HaveOccurred())
+				bytes, _ := ioutil.ReadAll(response.Body)
+
+				varzMessage := instrumentation.VarzMessage{}
+				err = json.Unmarshal(bytes, &varzMessage)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				return varzMessage
+			}
+
+			contextNames := func() []string {
+				names := []string{}
+				for _, context := range varz().Contexts {
+					names = append(names, context.Name)
+				}
+				return names
+			}
+
+			BeforeEach(func() {
+				config.LeaderElection = true
+				config.LockTTL = 10 * time.Second
+				config.CollectionInterval = 50 * time.Millisecond
+
+				bbs.GetAllTasksReturns.Err = errors.New("etcd is down")
+			})
+
+			AfterEach(func() {
+				process.Signal(syscall.SIGTERM)
+
+				var released chan bool
+				Eventually(store.ReleaseNodeChannel).Should(Receive(&released))
+				close(released)
+			})
+
+			It("maintains the metrics server lock with its address", func() {
+				Ω(store.MaintainedNodeName).Should(Equal("/v1/locks/metrics_server_lock"))
+				Ω(string(store.MaintainedNodeValue)).Should(Equal(fmt.Sprintf("%s:%d", myIP, port)))
+			})
+
+			Context("while standing by", func() {
+				It("only reports on the metrics server itself", func() {
+					Ω(contextNames()).Should(Equal([]string{"MetricsServer"}))
+					Ω(varz().Contexts[0].Metrics).Should(ContainElement(instrumentation.Metric{
+						Name:  "Leader",
+						Value: float64(0),
+					}))
+				})
+
+				It("reports healthy as a standby", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/healthz?detailed=true", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					body, err := ioutil.ReadAll(response.Body)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(body).Should(MatchJSON(`{
+						"healthy": true,
+						"role": "standby",
+						"checks": [
+							{"name": "bbs", "healthy": true},
+							{"name": "nats", "healthy": true},
+							{"name": "collection", "healthy": true}
+						]
+					}`))
+				})
+
+				It("is not ready", func() {
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/readyz", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(response.StatusCode).Should(Equal(http.StatusServiceUnavailable))
+				})
+			})
+
+			Context("once the lock is acquired", func() {
+				JustBeforeEach(func() {
+					store.MaintainNodeStatus <- true
+				})
+
+				It("collects from the BBS", func() {
+					Eventually(contextNames).Should(Equal([]string{"Tasks", "ServiceRegistrations", "MetricsServer"}))
+				})
+
+				It("reports its health as the leader", func() {
+					Eventually(func() int {
+						response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/readyz", myIP, port))
+						Ω(err).ShouldNot(HaveOccurred())
+						return response.StatusCode
+					}).Should(Equal(http.StatusServiceUnavailable))
+
+					response, err := httpClient.Get(fmt.Sprintf("http://%s:%d/healthz?detailed=true", myIP, port))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					report := map[string]interface{}{}
+					err = json.NewDecoder(response.Body).Decode(&report)
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(report["role"]).Should(Equal("leader"))
+				})
+
+				Context("and then lost", func() {
+					It("stops collecting from the BBS", func() {
+						Eventually(contextNames).Should(HaveLen(3))
+
+						store.MaintainNodeStatus <- false
+
+						Eventually(contextNames).Should(Equal([]string{"MetricsServer"}))
+					})
+				})
+			})
 		})
 	})
 })
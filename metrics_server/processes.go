@@ -0,0 +1,39 @@
+package metrics_server
+
+import (
+	"os"
+
+	"github.com/tedsuo/ifrit"
+)
+
+// processes are envoked in order and stopped in reverse order
+type processes []ifrit.Process
+
+func (ps *processes) envoke(runner ifrit.Runner) ifrit.Process {
+	process := ifrit.Envoke(runner)
+	*ps = append(*ps, process)
+	return process
+}
+
+func (ps processes) exited() <-chan error {
+	exited := make(chan error, len(ps))
+	for _, process := range ps {
+		go func(process ifrit.Process) {
+			exited <- <-process.Wait()
+		}(process)
+	}
+
+	return exited
+}
+
+func (ps processes) stop(signal os.Signal) error {
+	var firstErr error
+	for i := len(ps) - 1; i >= 0; i-- {
+		ps[i].Signal(signal)
+		if err := <-ps[i].Wait(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,219 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+)
+
+const ContentType = "text/plain; version=0.0.4"
+
+// the metric names of these contexts are values of a single label rather
+// than separate metrics, e.g. Tasks.Pending becomes diego_tasks{state="pending"}
+var labelledContexts = map[string]string{
+	"Tasks":                "state",
+	"ServiceRegistrations": "service",
+}
+
+type family struct {
+	name       string
+	help       string
+	metricType metric.Type
+	samples    []sample
+}
+
+type sample struct {
+	labels map[string]string
+	value  metric.Value
+}
+
+// Write renders the message in the Prometheus text exposition format, with
+// every metric name prefixed by the namespace
+func Write(w io.Writer, namespace string, message *instrumentation.VarzMessage) error {
+	families := []*family{}
+	byName := map[string]*family{}
+
+	add := func(name, help string, labels map[string]string, value metric.Value) {
+		f, found := byName[name]
+		if !found {
+			f = &family{name: name, help: help, metricType: value.Type}
+			byName[name] = f
+			families = append(families, f)
+		}
+
+		f.samples = append(f.samples, sample{labels: labels, value: value})
+	}
+
+	for _, context := range message.Contexts {
+		labelName, labelled := labelledContexts[context.Name]
+
+		for _, m := range context.Metrics {
+			value, ok := metric.Of(m)
+			if !ok {
+				continue
+			}
+
+			labels := map[string]string{}
+			for key, tag := range m.Tags {
+				labels[sanitize(key)] = fmt.Sprintf("%v", tag)
+			}
+
+			var name, help string
+			if labelled {
+				name = join(namespace, snakeCase(context.Name))
+				help = fmt.Sprintf("%s by %s", context.Name, labelName)
+				labels[labelName] = snakeCase(m.Name)
+			} else {
+				name = join(namespace, snakeCase(context.Name), snakeCase(m.Name))
+				help = value.Description
+				if help == "" {
+					help = fmt.Sprintf("%s %s", context.Name, m.Name)
+				}
+			}
+
+			add(name, help, labels, value)
+		}
+	}
+
+	memoryStats := message.MemoryStats
+	add("go_goroutines", "Number of goroutines that currently exist.", nil, metric.NewGauge(float64(message.NumGoRoutines), "", ""))
+	add("go_cpus", "Number of logical CPUs usable by the process.", nil, metric.NewGauge(float64(message.NumCpus), "", ""))
+	add("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", nil, metric.NewGauge(float64(memoryStats.BytesAllocatedHeap), "", ""))
+	add("go_memstats_stack_inuse_bytes", "Number of bytes in use by the stack allocator.", nil, metric.NewGauge(float64(memoryStats.BytesAllocatedStack), "", ""))
+	add("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", nil, metric.NewGauge(float64(memoryStats.BytesAllocated), "", ""))
+	add("go_memstats_mallocs_total", "Total number of mallocs.", nil, metric.NewCounter(memoryStats.NumMallocs, "", ""))
+	add("go_memstats_frees_total", "Total number of frees.", nil, metric.NewCounter(memoryStats.NumFrees, "", ""))
+	add("go_gc_last_pause_seconds", "Duration of the last garbage collection pause.", nil, metric.NewGauge(float64(memoryStats.LastGCPauseTimeNS)/1e9, "", ""))
+
+	buffer := &bytes.Buffer{}
+	for _, f := range families {
+		fmt.Fprintf(buffer, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(buffer, "# TYPE %s %s\n", f.name, f.metricType)
+
+		for _, s := range f.samples {
+			if s.value.Type == metric.Histogram {
+				writeHistogram(buffer, f.name, s)
+				continue
+			}
+
+			fmt.Fprintf(buffer, "%s%s %s\n", f.name, renderLabels(s.labels), formatValue(s.value.Number))
+		}
+	}
+
+	_, err := buffer.WriteTo(w)
+	return err
+}
+
+func writeHistogram(w io.Writer, name string, s sample) {
+	for _, bucket := range s.value.Buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, renderLabels(withLabel(s.labels, "le", formatValue(bucket.UpperBound))), bucket.Count)
+	}
+
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, renderLabels(withLabel(s.labels, "le", "+Inf")), s.value.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, renderLabels(s.labels), formatValue(s.value.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, renderLabels(s.labels), s.value.Count)
+}
+
+func withLabel(labels map[string]string, name string, value string) map[string]string {
+	copied := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	copied[name] = value
+
+	return copied
+}
+
+func renderLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(labels[name]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func join(parts ...string) string {
+	nonEmpty := []string{}
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+
+	return strings.Join(nonEmpty, "_")
+}
+
+// snakeCase turns names like NATSPublishErrors into nats_publish_errors
+func snakeCase(name string) string {
+	runes := []rune(name)
+	snake := []rune{}
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				snake = append(snake, '_')
+			}
+		}
+
+		snake = append(snake, unicode.ToLower(r))
+	}
+
+	return sanitize(string(snake))
+}
+
+func sanitize(name string) string {
+	sanitized := []rune{}
+	for i, r := range name {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+		if valid {
+			sanitized = append(sanitized, r)
+		} else {
+			sanitized = append(sanitized, '_')
+		}
+	}
+
+	return string(sanitized)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,119 @@
+package prometheus_test
+
+import (
+	"bytes"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/prometheus"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Write", func() {
+	var message *instrumentation.VarzMessage
+	var output string
+
+	BeforeEach(func() {
+		message = &instrumentation.VarzMessage{
+			Name:          "runtime",
+			NumCpus:       4,
+			NumGoRoutines: 12,
+			Contexts: []instrumentation.Context{
+				{
+					Name: "Tasks",
+					Metrics: []instrumentation.Metric{
+						{Name: "Pending", Value: metric.NewGauge(3, "tasks", "Pending tasks")},
+						{Name: "Running", Value: metric.NewGauge(-1, "tasks", "Running tasks")},
+					},
+				},
+				{
+					Name: "ServiceRegistrations",
+					Metrics: []instrumentation.Metric{
+						{Name: "FileServer", Value: 1},
+					},
+				},
+				{
+					Name: "MetricsServer",
+					Metrics: []instrumentation.Metric{
+						{Name: "NATSPublishErrors", Value: metric.NewCounter(2, "errors", "Failed publishes to NATS")},
+						{Name: "Scrapes", Value: uint64(5), Tags: map[string]interface{}{"endpoint": "/varz"}},
+						{Name: "Scrapes", Value: uint64(1), Tags: map[string]interface{}{"endpoint": "/metrics"}},
+						{Name: "BuildInfo", Value: 1, Tags: map[string]interface{}{"version": "some \"quoted\" version"}},
+						{
+							Name: "ScrapeDurationMS",
+							Value: metric.NewHistogram(
+								[]metric.Bucket{{UpperBound: 1, Count: 1}, {UpperBound: 5, Count: 3}},
+								12.5, 4, "milliseconds", "Duration of requests",
+							),
+							Tags: map[string]interface{}{"endpoint": "/varz"},
+						},
+						{Name: "Unrenderable", Value: "not a number"},
+					},
+				},
+			},
+		}
+		message.MemoryStats.NumMallocs = 100
+		message.MemoryStats.LastGCPauseTimeNS = 1500000
+	})
+
+	JustBeforeEach(func() {
+		buffer := &bytes.Buffer{}
+		err := Write(buffer, "diego", message)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		output = buffer.String()
+	})
+
+	It("renders labelled contexts as a single metric with a label per metric name", func() {
+		Ω(output).Should(ContainSubstring(`# HELP diego_tasks Tasks by state
+# TYPE diego_tasks gauge
+diego_tasks{state="pending"} 3
+diego_tasks{state="running"} -1
+`))
+
+		Ω(output).Should(ContainSubstring(`diego_service_registrations{service="file_server"} 1
+`))
+	})
+
+	It("renders other metrics by context and metric name, with their description and type", func() {
+		Ω(output).Should(ContainSubstring(`# HELP diego_metrics_server_nats_publish_errors Failed publishes to NATS
+# TYPE diego_metrics_server_nats_publish_errors counter
+diego_metrics_server_nats_publish_errors 2
+`))
+	})
+
+	It("renders plain numbers as untyped, with tags as labels", func() {
+		Ω(output).Should(ContainSubstring(`# HELP diego_metrics_server_scrapes MetricsServer Scrapes
+# TYPE diego_metrics_server_scrapes untyped
+diego_metrics_server_scrapes{endpoint="/varz"} 5
+diego_metrics_server_scrapes{endpoint="/metrics"} 1
+`))
+	})
+
+	It("renders histograms as cumulative buckets, a sum and a count", func() {
+		Ω(output).Should(ContainSubstring(`# HELP diego_metrics_server_scrape_duration_ms Duration of requests
+# TYPE diego_metrics_server_scrape_duration_ms histogram
+diego_metrics_server_scrape_duration_ms_bucket{endpoint="/varz",le="1"} 1
+diego_metrics_server_scrape_duration_ms_bucket{endpoint="/varz",le="5"} 3
+diego_metrics_server_scrape_duration_ms_bucket{endpoint="/varz",le="+Inf"} 4
+diego_metrics_server_scrape_duration_ms_sum{endpoint="/varz"} 12.5
+diego_metrics_server_scrape_duration_ms_count{endpoint="/varz"} 4
+`))
+	})
+
+	It("escapes label values", func() {
+		Ω(output).Should(ContainSubstring(`diego_metrics_server_build_info{version="some \"quoted\" version"} 1`))
+	})
+
+	It("skips values that are not numbers", func() {
+		Ω(output).ShouldNot(ContainSubstring("unrenderable"))
+	})
+
+	It("renders the Go runtime and memory stats", func() {
+		Ω(output).Should(ContainSubstring("go_goroutines 12\n"))
+		Ω(output).Should(ContainSubstring("go_cpus 4\n"))
+		Ω(output).Should(ContainSubstring("# TYPE go_memstats_mallocs_total counter\ngo_memstats_mallocs_total 100\n"))
+		Ω(output).Should(ContainSubstring("go_gc_last_pause_seconds 0.0015\n"))
+	})
+})
@@ -0,0 +1,112 @@
+package registrar
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz"
+	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/pivotal-golang/lager"
+)
+
+type Registrar struct {
+	natsClient   yagnats.NATSClient
+	component    metricz.Component
+	interval     time.Duration
+	reconnects   <-chan struct{}
+	stats        *server_stats.Stats
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+
+	subscribed bool
+}
+
+func New(
+	natsClient yagnats.NATSClient,
+	component metricz.Component,
+	interval time.Duration,
+	reconnects <-chan struct{},
+	stats *server_stats.Stats,
+	timeProvider timeprovider.TimeProvider,
+	logger lager.Logger,
+) *Registrar {
+	return &Registrar{
+		natsClient:   natsClient,
+		component:    component,
+		interval:     interval,
+		reconnects:   reconnects,
+		stats:        stats,
+		timeProvider: timeProvider,
+		logger:       logger.Session("registrar"),
+	}
+}
+
+func (r *Registrar) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	message, err := json.Marshal(collector_registrar.NewAnnounceComponentMessage(r.component))
+	if err != nil {
+		return err
+	}
+
+	r.subscribe(message)
+	r.announce(message)
+
+	close(ready)
+
+	ticker := r.timeProvider.NewTickerChannel("announce", r.interval)
+
+	for {
+		select {
+		case <-ticker:
+			if !r.subscribed {
+				r.subscribe(message)
+			}
+			r.announce(message)
+
+		case <-r.reconnects:
+			r.logger.Info("nats-reconnected")
+			r.natsClient.UnsubscribeAll(collector_registrar.DiscoverComponentMessageSubject)
+			r.subscribed = false
+
+			r.subscribe(message)
+			r.announce(message)
+
+		case <-signals:
+			r.natsClient.UnsubscribeAll(collector_registrar.DiscoverComponentMessageSubject)
+			return nil
+		}
+	}
+}
+
+func (r *Registrar) subscribe(message []byte) {
+	_, err := r.natsClient.Subscribe(
+		collector_registrar.DiscoverComponentMessageSubject,
+		func(msg *yagnats.Message) {
+			err := r.natsClient.Publish(msg.ReplyTo, message)
+			if err != nil {
+				r.logger.Error("failed-to-respond-to-discover", err)
+			}
+		},
+	)
+	if err != nil {
+		r.logger.Error("failed-to-subscribe-to-discover", err)
+		r.stats.RecordRegistrationFailure()
+		return
+	}
+
+	r.subscribed = true
+}
+
+func (r *Registrar) announce(message []byte) {
+	err := r.natsClient.Publish(collector_registrar.AnnounceComponentMessageSubject, message)
+	if err != nil {
+		r.logger.Error("failed-to-announce", err)
+		r.stats.RecordRegistrationFailure()
+		return
+	}
+
+	r.stats.RecordAnnouncement()
+}
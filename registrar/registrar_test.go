@@ -0,0 +1,143 @@
+package registrar_test
+
+import (
+	"encoding/json"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz"
+	"github.com/cloudfoundry-incubator/metricz/collector_registrar"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/registrar"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Registrar", func() {
+	var (
+		fakenats     *fakeyagnats.FakeYagnats
+		timeProvider *faketimeprovider.FakeTimeProvider
+		stats        *server_stats.Stats
+		logger       *lagertest.TestLogger
+		reconnects   chan struct{}
+		component    metricz.Component
+		expectedJSON []byte
+		process      ifrit.Process
+	)
+
+	announcements := func() []yagnats.Message {
+		return fakenats.PublishedMessages(collector_registrar.AnnounceComponentMessageSubject)
+	}
+
+	BeforeEach(func() {
+		fakenats = fakeyagnats.New()
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		timeProvider.ProvideFakeChannels = true
+		stats = server_stats.New("some-version", timeProvider)
+		logger = lagertest.NewTestLogger("test")
+		reconnects = make(chan struct{})
+
+		var err error
+		component, err = metricz.NewComponent(logger, "runtime", 3, nil, 5678, []string{"user", "pass"}, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		expectedJSON, err = json.Marshal(collector_registrar.NewAnnounceComponentMessage(component))
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Envoke(New(fakenats, component, time.Minute, reconnects, stats, timeProvider, logger))
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("announces the component before becoming ready", func() {
+		Ω(announcements()).Should(Equal([]yagnats.Message{
+			{Subject: collector_registrar.AnnounceComponentMessageSubject, Payload: expectedJSON},
+		}))
+		Ω(stats.Announcements()).Should(Equal(uint64(1)))
+	})
+
+	It("responds to discover requests with the component info", func() {
+		fakenats.PublishWithReplyTo(collector_registrar.DiscoverComponentMessageSubject, "reply-subject", nil)
+
+		Ω(fakenats.PublishedMessages("reply-subject")).Should(Equal([]yagnats.Message{
+			{Subject: "reply-subject", Payload: expectedJSON},
+		}))
+	})
+
+	It("announces again on every interval", func() {
+		Ω(timeProvider.TickerDurationFor("announce")).Should(Equal(time.Minute))
+
+		timeProvider.TickerChannelFor("announce") <- timeProvider.Time()
+		Eventually(announcements).Should(HaveLen(2))
+	})
+
+	Context("when NATS reconnects", func() {
+		JustBeforeEach(func() {
+			reconnects <- struct{}{}
+		})
+
+		It("re-subscribes to discover requests", func() {
+			Eventually(func() []yagnats.Subscription {
+				return fakenats.Subscriptions(collector_registrar.DiscoverComponentMessageSubject)
+			}).Should(HaveLen(2))
+		})
+
+		It("announces again", func() {
+			Eventually(announcements).Should(HaveLen(2))
+		})
+	})
+
+	Context("when announcing fails", func() {
+		BeforeEach(func() {
+			fakenats.WhenPublishing(collector_registrar.AnnounceComponentMessageSubject, func(*yagnats.Message) error {
+				return errors.New("oh no!")
+			})
+		})
+
+		It("keeps running", func() {
+			Consistently(process.Wait()).ShouldNot(Receive())
+		})
+
+		It("logs and records the failure", func() {
+			Ω(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-announce"))
+			Ω(stats.RegistrationFailures()).Should(Equal(uint64(1)))
+		})
+	})
+
+	Context("when subscribing fails", func() {
+		var subscribeErr error
+
+		BeforeEach(func() {
+			subscribeErr = errors.New("oh no!")
+			fakenats.WhenSubscribing(collector_registrar.DiscoverComponentMessageSubject, func(yagnats.Callback) error {
+				return subscribeErr
+			})
+		})
+
+		It("logs and records the failure", func() {
+			Ω(logger.TestSink.Buffer).Should(gbytes.Say("failed-to-subscribe-to-discover"))
+			Ω(stats.RegistrationFailures()).Should(Equal(uint64(1)))
+		})
+
+		It("retries on the next interval", func() {
+			subscribeErr = nil
+			timeProvider.TickerChannelFor("announce") <- timeProvider.Time()
+
+			Eventually(func() []yagnats.Subscription {
+				return fakenats.Subscriptions(collector_registrar.DiscoverComponentMessageSubject)
+			}).Should(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/cloudfoundry-incubator/cf-lager"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/replay"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/sigmon"
+)
+
+// replayJournal serves the collections in the journal on /varz, at the pace
+// they were collected at, as if they were live
+func replayJournal() {
+	if *journalPath == "" {
+		log.Fatal("replay requires -journalPath")
+	}
+
+	logger := cf_lager.New("runtime-metrics-server")
+
+	replayer := replay.New(replay.Config{
+		Path:  *journalPath,
+		Speed: *replaySpeed,
+		Loop:  *replayLoop,
+	}, logger)
+
+	server := ifrit.Envoke(replay.NewServer(
+		replayer,
+		fmt.Sprintf(":%d", *port),
+		*username,
+		*password,
+	))
+
+	monitor := ifrit.Envoke(sigmon.New(server))
+
+	err := <-monitor.Wait()
+	if err != nil {
+		log.Fatalf("runtime-metrics-server replay exited with error: %s", err)
+	}
+}
@@ -0,0 +1,19 @@
+package replay
+
+import (
+	"net/http"
+
+	"github.com/cloudfoundry-incubator/metricz/auth"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+)
+
+// NewHandler serves the snapshots being replayed on /varz, the same way the
+// metrics server does
+func NewHandler(name string, snapshots collection.Source, username string, password string) http.Handler {
+	basicAuth := auth.NewBasicAuth("Realm", []string{username, password})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/varz", basicAuth.Wrap(collection.VarzHandler(name, snapshots)))
+
+	return mux
+}
@@ -0,0 +1,160 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
+	"github.com/pivotal-golang/lager"
+)
+
+var ErrEmptyJournal = errors.New("journal has no snapshots")
+
+type Config struct {
+	Path string
+
+	// Speed multiplies the pace of the replay; 2 replays a collection
+	// interval of 10 seconds every 5 seconds
+	Speed float64
+
+	// Loop starts over at the end of the journal instead of holding on to
+	// its last snapshot
+	Loop bool
+}
+
+// Replayer is a collection.Source that goes through the snapshots of a
+// journal at the pace they were collected at
+type Replayer struct {
+	config Config
+	logger lager.Logger
+
+	lock   *sync.RWMutex
+	latest collection.Snapshot
+	played bool
+
+	// interval is the last interval between snapshots, which the last
+	// snapshot is held for before starting over
+	interval time.Duration
+}
+
+func New(config Config, logger lager.Logger) *Replayer {
+	if config.Speed <= 0 {
+		config.Speed = 1
+	}
+
+	return &Replayer{
+		config: config,
+		logger: logger.Session("replay", lager.Data{"path": config.Path}),
+		lock:   &sync.RWMutex{},
+	}
+}
+
+func (r *Replayer) Latest() (collection.Snapshot, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.latest, r.played
+}
+
+// Run becomes ready once the first snapshot of the journal is played
+func (r *Replayer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	played := func() {
+		if ready != nil {
+			close(ready)
+			ready = nil
+		}
+	}
+
+	for {
+		files, err := journal.Files(r.config.Path)
+		if err != nil {
+			return err
+		}
+
+		var previous time.Time
+		replayed := 0
+		for _, file := range files {
+			var count int
+			var signalled bool
+			previous, count, signalled, err = r.replayFile(file, previous, signals, played)
+			if err != nil {
+				return err
+			}
+
+			if signalled {
+				return nil
+			}
+
+			replayed += count
+		}
+
+		if replayed == 0 {
+			return ErrEmptyJournal
+		}
+
+		r.logger.Info("finished", lager.Data{"snapshots": replayed, "loop": r.config.Loop})
+		if !r.config.Loop {
+			<-signals
+			return nil
+		}
+
+		select {
+		case <-time.After(r.delay(r.interval)):
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+func (r *Replayer) replayFile(
+	path string,
+	previous time.Time,
+	signals <-chan os.Signal,
+	played func(),
+) (time.Time, int, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return previous, 0, false, err
+	}
+	defer file.Close()
+
+	reader := journal.NewReader(file)
+	for count := 0; ; count++ {
+		snapshot, err := reader.Next()
+		if err == io.EOF {
+			return previous, count, false, nil
+		}
+
+		if err != nil {
+			// the rest of the file can't be read, but the next might be fine
+			r.logger.Error("failed-to-read", err, lager.Data{"file": path})
+			return previous, count, false, nil
+		}
+
+		if !previous.IsZero() && snapshot.Timestamp.After(previous) {
+			r.interval = snapshot.Timestamp.Sub(previous)
+
+			select {
+			case <-time.After(r.delay(r.interval)):
+			case <-signals:
+				return previous, count, true, nil
+			}
+		}
+		previous = snapshot.Timestamp
+
+		r.lock.Lock()
+		r.latest = snapshot
+		r.played = true
+		r.lock.Unlock()
+
+		played()
+	}
+}
+
+func (r *Replayer) delay(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) / r.config.Speed)
+}
@@ -0,0 +1,206 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/journal"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/replay"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+func snapshot(sequence uint64) collection.Snapshot {
+	return collection.Snapshot{
+		Sequence:  sequence,
+		Timestamp: time.Unix(int64(1000+sequence), 0),
+		Contexts: []instrumentation.Context{
+			{Name: "Tasks", Metrics: []instrumentation.Metric{{Name: "Pending", Value: sequence * 10}}},
+		},
+	}
+}
+
+func record(config journal.Config, sequences ...uint64) {
+	snapshots := make(chan collection.Snapshot)
+	process := ifrit.Envoke(journal.NewWriter(snapshots, config, lagertest.NewTestLogger("test")))
+	for _, sequence := range sequences {
+		snapshots <- snapshot(sequence)
+	}
+
+	process.Signal(syscall.SIGTERM)
+	Eventually(process.Wait()).Should(Receive(BeNil()))
+}
+
+var _ = Describe("Replay", func() {
+	var (
+		dir      string
+		path     string
+		config   Config
+		replayer *Replayer
+		process  ifrit.Process
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "replay")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		path = filepath.Join(dir, "snapshots.journal")
+		config = Config{Path: path, Speed: 10}
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive())
+		}
+		os.RemoveAll(dir)
+	})
+
+	JustBeforeEach(func() {
+		replayer = New(config, lagertest.NewTestLogger("test"))
+	})
+
+	sequence := func() uint64 {
+		snapshot, _ := replayer.Latest()
+		return snapshot.Sequence
+	}
+
+	Context("with a journal", func() {
+		BeforeEach(func() {
+			record(journal.Config{Path: path, MaxSize: 1}, 1, 2, 3)
+		})
+
+		It("is ready once the first snapshot is played", func() {
+			_, played := replayer.Latest()
+			Ω(played).Should(BeFalse())
+
+			process = ifrit.Envoke(replayer)
+
+			snapshot, played := replayer.Latest()
+			Ω(played).Should(BeTrue())
+			Ω(snapshot.Sequence).Should(Equal(uint64(1)))
+		})
+
+		It("plays every snapshot, across rotated files, at the pace they were collected", func() {
+			process = ifrit.Envoke(replayer)
+
+			Consistently(sequence, 50*time.Millisecond).Should(Equal(uint64(1)))
+			Eventually(sequence).Should(Equal(uint64(2)))
+			Eventually(sequence).Should(Equal(uint64(3)))
+		})
+
+		It("holds on to the last snapshot", func() {
+			process = ifrit.Envoke(replayer)
+
+			Eventually(sequence).Should(Equal(uint64(3)))
+			Consistently(sequence, 300*time.Millisecond).Should(Equal(uint64(3)))
+		})
+
+		Context("when looping", func() {
+			BeforeEach(func() {
+				config.Loop = true
+			})
+
+			It("starts over at the end of the journal", func() {
+				process = ifrit.Envoke(replayer)
+
+				Eventually(sequence).Should(Equal(uint64(3)))
+				Eventually(sequence).Should(Equal(uint64(1)))
+			})
+		})
+
+		It("serves the snapshot being played on /varz", func() {
+			process = ifrit.Envoke(replayer)
+
+			server := httptest.NewServer(NewHandler("runtime", replayer, "the-username", "the-password"))
+			defer server.Close()
+
+			response, err := http.Get(server.URL + "/varz")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(response.StatusCode).Should(Equal(http.StatusUnauthorized))
+
+			request, _ := http.NewRequest("GET", server.URL+"/varz", nil)
+			request.SetBasicAuth("the-username", "the-password")
+			response, err = http.DefaultClient.Do(request)
+			Ω(err).ShouldNot(HaveOccurred())
+			defer response.Body.Close()
+
+			Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+			var varz instrumentation.VarzMessage
+			err = json.NewDecoder(response.Body).Decode(&varz)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(varz.Name).Should(Equal("runtime"))
+			Ω(varz.Contexts).Should(HaveLen(1))
+			Ω(varz.Contexts[0].Metrics[0].Name).Should(Equal("Pending"))
+		})
+	})
+
+	Context("when the journal has a truncated record", func() {
+		BeforeEach(func() {
+			record(journal.Config{Path: path}, 1)
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+			Ω(err).ShouldNot(HaveOccurred())
+			file.Write([]byte{0, 0, 1})
+			file.Close()
+		})
+
+		It("plays the snapshots before it", func() {
+			process = ifrit.Envoke(replayer)
+			Ω(sequence()).Should(Equal(uint64(1)))
+		})
+	})
+
+	Context("when the journal is empty", func() {
+		It("exits with an error", func() {
+			process = nil
+			Ω(replayer.Run(make(chan os.Signal), make(chan struct{}))).Should(Equal(ErrEmptyJournal))
+		})
+	})
+
+	Describe("Server", func() {
+		var address string
+
+		BeforeEach(func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Ω(err).ShouldNot(HaveOccurred())
+			address = listener.Addr().String()
+			listener.Close()
+		})
+
+		It("serves the journal until signalled", func() {
+			record(journal.Config{Path: path}, 1)
+
+			process = ifrit.Envoke(NewServer(replayer, address, "the-username", "the-password"))
+
+			request, _ := http.NewRequest("GET", "http://"+address+"/varz", nil)
+			request.SetBasicAuth("the-username", "the-password")
+			response, err := http.DefaultClient.Do(request)
+			Ω(err).ShouldNot(HaveOccurred())
+			response.Body.Close()
+			Ω(response.StatusCode).Should(Equal(http.StatusOK))
+
+			process.Signal(syscall.SIGTERM)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("exits when the journal can't be replayed", func() {
+			process = ifrit.Envoke(NewServer(replayer, address, "the-username", "the-password"))
+			Eventually(process.Wait()).Should(Receive(Equal(ErrEmptyJournal)))
+		})
+	})
+})
@@ -0,0 +1,51 @@
+package replay
+
+import (
+	"os"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/http_server"
+)
+
+// Server replays a journal and serves it on /varz until it is signalled or
+// the replay fails
+type Server struct {
+	replayer *Replayer
+	address  string
+	username string
+	password string
+}
+
+func NewServer(replayer *Replayer, address string, username string, password string) *Server {
+	return &Server{
+		replayer: replayer,
+		address:  address,
+		username: username,
+		password: password,
+	}
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	replaying := ifrit.Envoke(s.replayer)
+	serving := ifrit.Envoke(http_server.New(s.address, NewHandler("runtime", s.replayer, s.username, s.password)))
+
+	close(ready)
+
+	select {
+	case signal := <-signals:
+		serving.Signal(signal)
+		<-serving.Wait()
+		replaying.Signal(signal)
+		return <-replaying.Wait()
+
+	case err := <-replaying.Wait():
+		serving.Signal(os.Interrupt)
+		<-serving.Wait()
+		return err
+
+	case err := <-serving.Wait():
+		replaying.Signal(os.Interrupt)
+		<-replaying.Wait()
+		return err
+	}
+}
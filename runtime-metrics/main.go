@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/client"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/cloudfoundry/yagnats"
+)
+
+var url = flag.String(
+	"url",
+	"http://127.0.0.1:5678",
+	"url of the metrics server, ignored when discovering servers",
+)
+
+var username = flag.String(
+	"username",
+	"",
+	"basic auth username",
+)
+
+var password = flag.String(
+	"password",
+	"",
+	"basic auth password",
+)
+
+var discover = flag.Bool(
+	"discover",
+	false,
+	"discover the metrics servers through NATS, with the credentials they announce",
+)
+
+var component = flag.String(
+	"component",
+	"runtime",
+	"type of the components to discover",
+)
+
+var natsAddresses = flag.String(
+	"natsAddresses",
+	"127.0.0.1:4222",
+	"comma-separated list of NATS addresses (ip:port)",
+)
+
+var natsUsername = flag.String(
+	"natsUsername",
+	"nats",
+	"Username to connect to nats",
+)
+
+var natsPassword = flag.String(
+	"natsPassword",
+	"nats",
+	"Password for nats user",
+)
+
+var contexts = flag.String(
+	"contexts",
+	"",
+	"comma-separated list of the contexts to show, all when empty",
+)
+
+var interval = flag.Duration(
+	"interval",
+	2*time.Second,
+	"how often to refresh when watching, and how far apart to scrape when diffing a live server",
+)
+
+var timeout = flag.Duration(
+	"timeout",
+	5*time.Second,
+	"how long to wait for servers to answer",
+)
+
+const usage = `usage: runtime-metrics [flags] <command> [args]
+
+commands:
+  get                    print every context of /varz as a table
+  watch                  refresh the tables every -interval, like top
+  diff [before after]    compare two scrapes saved with json, or two live
+                         scrapes -interval apart
+  json [path]            print the raw response of path, /varz by default
+
+flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// flags may follow the command too
+	command := flag.Arg(0)
+	flag.CommandLine.Parse(flag.Args()[1:])
+	args := flag.Args()
+
+	metrics := client.New(*timeout, timeprovider.NewTimeProvider())
+
+	var err error
+	switch command {
+	case "get":
+		err = get(metrics, servers())
+	case "watch":
+		err = watch(metrics, servers())
+	case "diff":
+		if len(args) == 2 {
+			err = diffFiles(args[0], args[1])
+		} else {
+			err = diff(metrics, servers())
+		}
+	case "json":
+		path := "/varz"
+		if len(args) > 0 {
+			path = args[0]
+		}
+		err = raw(metrics, servers(), path)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func get(metrics *client.Client, servers []client.Server) error {
+	for i, server := range servers {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		scrape, err := metrics.Scrape(server)
+		if err != nil {
+			return err
+		}
+
+		err = client.WriteTables(os.Stdout, scrape, nil, contextFilter())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func watch(metrics *client.Client, servers []client.Server) error {
+	previous := make([]*client.Scrape, len(servers))
+
+	for {
+		// clear the screen and move to its top
+		fmt.Print("\033[H\033[2J")
+
+		for i, server := range servers {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			scrape, err := metrics.Scrape(server)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+
+			err = client.WriteTables(os.Stdout, scrape, previous[i], contextFilter())
+			if err != nil {
+				return err
+			}
+			previous[i] = &scrape
+		}
+
+		time.Sleep(*interval)
+	}
+}
+
+func diff(metrics *client.Client, servers []client.Server) error {
+	for i, server := range servers {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		before, err := metrics.Scrape(server)
+		if err != nil {
+			return err
+		}
+
+		time.Sleep(*interval)
+
+		after, err := metrics.Scrape(server)
+		if err != nil {
+			return err
+		}
+
+		err = client.WriteDiff(os.Stdout, before, after, contextFilter())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func diffFiles(beforePath string, afterPath string) error {
+	before, err := client.LoadScrape(beforePath)
+	if err != nil {
+		return err
+	}
+
+	after, err := client.LoadScrape(afterPath)
+	if err != nil {
+		return err
+	}
+
+	return client.WriteDiff(os.Stdout, before, after, contextFilter())
+}
+
+func raw(metrics *client.Client, servers []client.Server, path string) error {
+	for _, server := range servers {
+		body, err := metrics.Get(server, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(body))
+	}
+
+	return nil
+}
+
+func servers() []client.Server {
+	if !*discover {
+		return []client.Server{{URL: strings.TrimRight(*url, "/"), Username: *username, Password: *password}}
+	}
+
+	natsMembers := []yagnats.ConnectionProvider{}
+	for _, addr := range strings.Split(*natsAddresses, ",") {
+		natsMembers = append(
+			natsMembers,
+			&yagnats.ConnectionInfo{
+				Addr:     addr,
+				Username: *natsUsername,
+				Password: *natsPassword,
+			},
+		)
+	}
+
+	natsClient := yagnats.NewClient()
+	err := natsClient.Connect(&yagnats.ConnectionCluster{
+		Members: natsMembers,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connecting to nats failed:", err)
+		os.Exit(1)
+	}
+	defer natsClient.Disconnect()
+
+	servers, err := client.Discover(natsClient, *component, *timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	return servers
+}
+
+func contextFilter() []string {
+	filter := []string{}
+	for _, context := range strings.Split(*contexts, ",") {
+		context = strings.TrimSpace(context)
+		if context != "" {
+			filter = append(filter, context)
+		}
+	}
+
+	return filter
+}
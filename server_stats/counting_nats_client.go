@@ -0,0 +1,31 @@
+package server_stats
+
+import "github.com/cloudfoundry/yagnats"
+
+type countingNATSClient struct {
+	yagnats.NATSClient
+	stats *Stats
+}
+
+func NewCountingNATSClient(natsClient yagnats.NATSClient, stats *Stats) yagnats.NATSClient {
+	return &countingNATSClient{
+		NATSClient: natsClient,
+		stats:      stats,
+	}
+}
+
+func (client *countingNATSClient) Publish(subject string, payload []byte) error {
+	return client.count(client.NATSClient.Publish(subject, payload))
+}
+
+func (client *countingNATSClient) PublishWithReplyTo(subject, reply string, payload []byte) error {
+	return client.count(client.NATSClient.PublishWithReplyTo(subject, reply, payload))
+}
+
+func (client *countingNATSClient) count(err error) error {
+	if err != nil {
+		client.stats.RecordNATSPublishError()
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+package server_stats_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/yagnats"
+	"github.com/cloudfoundry/yagnats/fakeyagnats"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CountingNATSClient", func() {
+	var fakenats *fakeyagnats.FakeYagnats
+	var stats *Stats
+	var natsClient yagnats.NATSClient
+
+	BeforeEach(func() {
+		fakenats = fakeyagnats.New()
+		stats = New("some-version", faketimeprovider.New(time.Unix(1000, 0)))
+		natsClient = NewCountingNATSClient(fakenats, stats)
+	})
+
+	It("publishes through the wrapped client", func() {
+		err := natsClient.Publish("some-subject", []byte("some-payload"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(fakenats.PublishedMessages("some-subject")).Should(HaveLen(1))
+		Ω(stats.NATSPublishErrors()).Should(BeZero())
+	})
+
+	Context("when publishing fails", func() {
+		BeforeEach(func() {
+			fakenats.WhenPublishing("some-subject", func(*yagnats.Message) error {
+				return errors.New("oh no!")
+			})
+		})
+
+		It("counts the error", func() {
+			natsClient.Publish("some-subject", nil)
+			natsClient.PublishWithReplyTo("some-subject", "some-reply", nil)
+
+			Ω(stats.NATSPublishErrors()).Should(Equal(uint64(2)))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package server_stats_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestServerStats(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ServerStats Suite")
+}
@@ -0,0 +1,218 @@
+package server_stats
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/gunk/timeprovider"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram of a Timing
+var LatencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+type Timing struct {
+	Count         uint64
+	LastDuration  time.Duration
+	MaxDuration   time.Duration
+	TotalDuration time.Duration
+
+	// BucketCounts are cumulative counts of the durations within each of the
+	// LatencyBuckets
+	BucketCounts [len(LatencyBuckets)]uint64
+}
+
+type StoreCall struct {
+	Timing
+	Errors uint64
+}
+
+type Stats struct {
+	timeProvider timeprovider.TimeProvider
+	version      string
+	startedAt    time.Time
+
+	lock              *sync.Mutex
+	scrapes           map[string]Timing
+	collections       map[string]Timing
+	storeCalls        map[string]StoreCall
+	natsPublishErrors uint64
+
+	announcements        uint64
+	registrationFailures uint64
+
+	electing          bool
+	leader            bool
+	leadershipChanges uint64
+}
+
+func New(version string, timeProvider timeprovider.TimeProvider) *Stats {
+	return &Stats{
+		timeProvider: timeProvider,
+		version:      version,
+		startedAt:    timeProvider.Time(),
+
+		lock:        &sync.Mutex{},
+		scrapes:     map[string]Timing{},
+		collections: map[string]Timing{},
+		storeCalls:  map[string]StoreCall{},
+	}
+}
+
+func (s *Stats) Version() string {
+	return s.version
+}
+
+func (s *Stats) StartedAt() time.Time {
+	return s.startedAt
+}
+
+func (s *Stats) Uptime() time.Duration {
+	return s.timeProvider.Time().Sub(s.startedAt)
+}
+
+func (s *Stats) RecordScrape(endpoint string, duration time.Duration) {
+	s.lock.Lock()
+	s.scrapes[endpoint] = s.scrapes[endpoint].record(duration)
+	s.lock.Unlock()
+}
+
+func (s *Stats) RecordCollection(context string, duration time.Duration) {
+	s.lock.Lock()
+	s.collections[context] = s.collections[context].record(duration)
+	s.lock.Unlock()
+}
+
+func (s *Stats) RecordStoreCall(operation string, duration time.Duration, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	call := s.storeCalls[operation]
+	call.Timing = call.Timing.record(duration)
+	if err != nil {
+		call.Errors++
+	}
+
+	s.storeCalls[operation] = call
+}
+
+func (s *Stats) RecordNATSPublishError() {
+	s.lock.Lock()
+	s.natsPublishErrors++
+	s.lock.Unlock()
+}
+
+func (s *Stats) RecordAnnouncement() {
+	s.lock.Lock()
+	s.announcements++
+	s.lock.Unlock()
+}
+
+func (s *Stats) RecordRegistrationFailure() {
+	s.lock.Lock()
+	s.registrationFailures++
+	s.lock.Unlock()
+}
+
+func (s *Stats) RecordLeadership(leader bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.electing && s.leader != leader {
+		s.leadershipChanges++
+	}
+
+	s.electing = true
+	s.leader = leader
+}
+
+func (s *Stats) Scrapes() map[string]Timing {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	scrapes := make(map[string]Timing, len(s.scrapes))
+	for endpoint, timing := range s.scrapes {
+		scrapes[endpoint] = timing
+	}
+
+	return scrapes
+}
+
+func (s *Stats) Collections() map[string]Timing {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	collections := make(map[string]Timing, len(s.collections))
+	for context, timing := range s.collections {
+		collections[context] = timing
+	}
+
+	return collections
+}
+
+func (s *Stats) StoreCalls() map[string]StoreCall {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	calls := make(map[string]StoreCall, len(s.storeCalls))
+	for operation, call := range s.storeCalls {
+		calls[operation] = call
+	}
+
+	return calls
+}
+
+func (s *Stats) NATSPublishErrors() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.natsPublishErrors
+}
+
+func (s *Stats) Announcements() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.announcements
+}
+
+func (s *Stats) RegistrationFailures() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.registrationFailures
+}
+
+// Leadership reports whether this server holds the metrics server lock;
+// electing is false when leader election is disabled
+func (s *Stats) Leadership() (leader bool, electing bool, changes uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.leader, s.electing, s.leadershipChanges
+}
+
+func (t Timing) record(duration time.Duration) Timing {
+	t.Count++
+	t.LastDuration = duration
+	t.TotalDuration += duration
+	if duration > t.MaxDuration {
+		t.MaxDuration = duration
+	}
+
+	for i, upperBound := range LatencyBuckets {
+		if duration <= upperBound {
+			t.BucketCounts[i]++
+		}
+	}
+
+	return t
+}
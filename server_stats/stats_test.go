@@ -0,0 +1,119 @@
+package server_stats_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+	var timeProvider *faketimeprovider.FakeTimeProvider
+	var stats *Stats
+
+	BeforeEach(func() {
+		timeProvider = faketimeprovider.New(time.Unix(1000, 0))
+		stats = New("some-version", timeProvider)
+	})
+
+	It("reports the version", func() {
+		Ω(stats.Version()).Should(Equal("some-version"))
+	})
+
+	It("reports the time since it was created", func() {
+		timeProvider.Increment(5 * time.Second)
+		Ω(stats.Uptime()).Should(Equal(5 * time.Second))
+		Ω(stats.StartedAt()).Should(Equal(time.Unix(1000, 0)))
+	})
+
+	Describe("RecordScrape", func() {
+		BeforeEach(func() {
+			stats.RecordScrape("/varz", 3*time.Millisecond)
+			stats.RecordScrape("/varz", 2*time.Millisecond)
+			stats.RecordScrape("/healthz", time.Millisecond)
+		})
+
+		It("counts scrapes and tracks the last, max and total latency per endpoint", func() {
+			Ω(stats.Scrapes()).Should(Equal(map[string]Timing{
+				"/varz": {
+					Count:         2,
+					LastDuration:  2 * time.Millisecond,
+					MaxDuration:   3 * time.Millisecond,
+					TotalDuration: 5 * time.Millisecond,
+					BucketCounts:  [len(LatencyBuckets)]uint64{0, 2, 2, 2, 2, 2, 2, 2},
+				},
+				"/healthz": {
+					Count:         1,
+					LastDuration:  time.Millisecond,
+					MaxDuration:   time.Millisecond,
+					TotalDuration: time.Millisecond,
+					BucketCounts:  [len(LatencyBuckets)]uint64{1, 1, 1, 1, 1, 1, 1, 1},
+				},
+			}))
+		})
+	})
+
+	Describe("RecordCollection", func() {
+		It("tracks the duration per context", func() {
+			stats.RecordCollection("Tasks", time.Second)
+			Ω(stats.Collections()["Tasks"]).Should(Equal(Timing{
+				Count:         1,
+				LastDuration:  time.Second,
+				MaxDuration:   time.Second,
+				TotalDuration: time.Second,
+				BucketCounts:  [len(LatencyBuckets)]uint64{0, 0, 0, 0, 0, 0, 1, 1},
+			}))
+		})
+	})
+
+	Describe("RecordStoreCall", func() {
+		It("counts calls and errors per operation", func() {
+			stats.RecordStoreCall("Get", time.Millisecond, nil)
+			stats.RecordStoreCall("Get", time.Millisecond, errors.New("boom"))
+
+			Ω(stats.StoreCalls()["Get"].Count).Should(Equal(uint64(2)))
+			Ω(stats.StoreCalls()["Get"].Errors).Should(Equal(uint64(1)))
+		})
+	})
+
+	Describe("RecordAnnouncement and RecordRegistrationFailure", func() {
+		It("counts announcements and failures separately", func() {
+			stats.RecordAnnouncement()
+			stats.RecordAnnouncement()
+			stats.RecordRegistrationFailure()
+
+			Ω(stats.Announcements()).Should(Equal(uint64(2)))
+			Ω(stats.RegistrationFailures()).Should(Equal(uint64(1)))
+		})
+	})
+
+	Describe("RecordNATSPublishError", func() {
+		It("counts the errors", func() {
+			stats.RecordNATSPublishError()
+			stats.RecordNATSPublishError()
+			Ω(stats.NATSPublishErrors()).Should(Equal(uint64(2)))
+		})
+	})
+
+	Describe("RecordLeadership", func() {
+		It("is not electing until leadership is recorded", func() {
+			_, electing, _ := stats.Leadership()
+			Ω(electing).Should(BeFalse())
+		})
+
+		It("tracks the current leadership and counts changes", func() {
+			stats.RecordLeadership(false)
+			stats.RecordLeadership(true)
+			stats.RecordLeadership(true)
+			stats.RecordLeadership(false)
+
+			leader, electing, changes := stats.Leadership()
+			Ω(leader).Should(BeFalse())
+			Ω(electing).Should(BeTrue())
+			Ω(changes).Should(Equal(uint64(2)))
+		})
+	})
+})
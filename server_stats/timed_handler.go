@@ -0,0 +1,23 @@
+package server_stats
+
+import "net/http"
+
+type timedHandler struct {
+	endpoint string
+	handler  http.Handler
+	stats    *Stats
+}
+
+func NewTimedHandler(endpoint string, handler http.Handler, stats *Stats) http.Handler {
+	return &timedHandler{
+		endpoint: endpoint,
+		handler:  handler,
+		stats:    stats,
+	}
+}
+
+func (h *timedHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	startedAt := h.stats.timeProvider.Time()
+	h.handler.ServeHTTP(w, req)
+	h.stats.RecordScrape(h.endpoint, h.stats.timeProvider.Time().Sub(startedAt))
+}
@@ -0,0 +1,23 @@
+package server_stats
+
+import "github.com/cloudfoundry-incubator/metricz/instrumentation"
+
+type timedInstrument struct {
+	instrument instrumentation.Instrumentable
+	stats      *Stats
+}
+
+func NewTimedInstrument(instrument instrumentation.Instrumentable, stats *Stats) instrumentation.Instrumentable {
+	return &timedInstrument{
+		instrument: instrument,
+		stats:      stats,
+	}
+}
+
+func (t *timedInstrument) Emit() instrumentation.Context {
+	startedAt := t.stats.timeProvider.Time()
+	context := t.instrument.Emit()
+	t.stats.RecordCollection(context.Name, t.stats.timeProvider.Time().Sub(startedAt))
+
+	return context
+}
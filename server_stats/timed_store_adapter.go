@@ -0,0 +1,93 @@
+package server_stats
+
+import (
+	"time"
+
+	"github.com/cloudfoundry/storeadapter"
+)
+
+type timedStoreAdapter struct {
+	storeadapter.StoreAdapter
+	stats *Stats
+}
+
+func NewTimedStoreAdapter(store storeadapter.StoreAdapter, stats *Stats) storeadapter.StoreAdapter {
+	return &timedStoreAdapter{
+		StoreAdapter: store,
+		stats:        stats,
+	}
+}
+
+func (adapter *timedStoreAdapter) Create(node storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.Create(node)
+	adapter.record("Create", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) Update(node storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.Update(node)
+	adapter.record("Update", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) CompareAndSwap(oldNode storeadapter.StoreNode, newNode storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.CompareAndSwap(oldNode, newNode)
+	adapter.record("CompareAndSwap", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) CompareAndSwapByIndex(prevIndex uint64, newNode storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.CompareAndSwapByIndex(prevIndex, newNode)
+	adapter.record("CompareAndSwapByIndex", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) SetMulti(nodes []storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.SetMulti(nodes)
+	adapter.record("SetMulti", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) Get(key string) (storeadapter.StoreNode, error) {
+	startedAt := adapter.stats.timeProvider.Time()
+	node, err := adapter.StoreAdapter.Get(key)
+	adapter.record("Get", startedAt, err)
+	return node, err
+}
+
+func (adapter *timedStoreAdapter) ListRecursively(key string) (storeadapter.StoreNode, error) {
+	startedAt := adapter.stats.timeProvider.Time()
+	node, err := adapter.StoreAdapter.ListRecursively(key)
+	adapter.record("ListRecursively", startedAt, err)
+	return node, err
+}
+
+func (adapter *timedStoreAdapter) Delete(keys ...string) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.Delete(keys...)
+	adapter.record("Delete", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) CompareAndDelete(node storeadapter.StoreNode) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.CompareAndDelete(node)
+	adapter.record("CompareAndDelete", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) UpdateDirTTL(key string, ttl uint64) error {
+	startedAt := adapter.stats.timeProvider.Time()
+	err := adapter.StoreAdapter.UpdateDirTTL(key, ttl)
+	adapter.record("UpdateDirTTL", startedAt, err)
+	return err
+}
+
+func (adapter *timedStoreAdapter) record(operation string, startedAt time.Time, err error) {
+	adapter.stats.RecordStoreCall(operation, adapter.stats.timeProvider.Time().Sub(startedAt), err)
+}
@@ -0,0 +1,49 @@
+package server_stats_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/server_stats"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/cloudfoundry/storeadapter"
+	"github.com/cloudfoundry/storeadapter/fakestoreadapter"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TimedStoreAdapter", func() {
+	var fakeStore *fakestoreadapter.FakeStoreAdapter
+	var stats *Stats
+	var store storeadapter.StoreAdapter
+
+	BeforeEach(func() {
+		fakeStore = fakestoreadapter.New()
+		stats = New("some-version", faketimeprovider.New(time.Unix(1000, 0)))
+		store = NewTimedStoreAdapter(fakeStore, stats)
+	})
+
+	It("passes calls through to the wrapped store", func() {
+		err := store.SetMulti([]storeadapter.StoreNode{{Key: "/v1/task/some-guid", Value: []byte("some-value")}})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		node, err := fakeStore.Get("/v1/task/some-guid")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(node.Value).Should(Equal([]byte("some-value")))
+	})
+
+	It("records each call", func() {
+		store.ListRecursively("/v1/task")
+		store.ListRecursively("/v1/task")
+
+		Ω(stats.StoreCalls()["ListRecursively"].Count).Should(Equal(uint64(2)))
+	})
+
+	Context("when the store returns an error", func() {
+		It("records the error", func() {
+			_, err := store.Get("/v1/nonexistent")
+			Ω(err).Should(Equal(storeadapter.ErrorKeyNotFound))
+
+			Ω(stats.StoreCalls()["Get"].Errors).Should(Equal(uint64(1)))
+		})
+	})
+})
@@ -0,0 +1,184 @@
+package slo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind string
+
+const (
+	// TasksClaimed counts a task as good when it is claimed within the
+	// objective's Within duration of being created
+	TasksClaimed Kind = "tasks claimed"
+
+	// LRPInstancesRunning counts every desired LRP instance at every
+	// collection, as good when an actual LRP is running at its index
+	LRPInstancesRunning Kind = "lrp instances running"
+)
+
+// Objective is a target for the ratio of good events over a window, like
+//
+//	TaskClaims: 99% of tasks claimed within 30s over 30d
+//	LRPAvailability: 99.5% of lrp instances running over 30d
+//
+// The name is optional and defaults to the expression.
+type Objective struct {
+	Name   string
+	Kind   Kind
+	Target float64
+	Within time.Duration
+	Window time.Duration
+}
+
+var objectiveExpression = regexp.MustCompile(
+	`^(?:([\w-]+)\s*:\s*)?` + // name
+		`(\S+)%\s+of\s+` + // target
+		`(tasks claimed|lrp instances running)` + // kind
+		`(?:\s+within\s+(\S+))?` + // claim duration
+		`\s+over\s+(\S+)$`, // window
+)
+
+func ParseObjective(expression string) (Objective, error) {
+	expression = strings.TrimSpace(expression)
+
+	match := objectiveExpression.FindStringSubmatch(expression)
+	if match == nil {
+		return Objective{}, fmt.Errorf("invalid objective %q: expected [name:] <target>%% of tasks claimed within <duration> over <window>, or [name:] <target>%% of lrp instances running over <window>", expression)
+	}
+
+	objective := Objective{
+		Name: match[1],
+		Kind: Kind(match[3]),
+	}
+
+	percent, err := strconv.ParseFloat(match[2], 64)
+	if err != nil || percent <= 0 || percent >= 100 {
+		return Objective{}, fmt.Errorf("invalid objective %q: invalid target %q, expected a percentage between 0 and 100", expression, match[2])
+	}
+	objective.Target = percent / 100
+
+	switch objective.Kind {
+	case TasksClaimed:
+		if match[4] == "" {
+			return Objective{}, fmt.Errorf("invalid objective %q: tasks must be claimed within a duration", expression)
+		}
+
+		objective.Within, err = time.ParseDuration(match[4])
+		if err != nil || objective.Within <= 0 {
+			return Objective{}, fmt.Errorf("invalid objective %q: invalid duration %q", expression, match[4])
+		}
+	case LRPInstancesRunning:
+		if match[4] != "" {
+			return Objective{}, fmt.Errorf("invalid objective %q: lrp instances can't be running within a duration", expression)
+		}
+	}
+
+	objective.Window, err = parseWindow(match[5])
+	if err != nil || objective.Window <= 0 {
+		return Objective{}, fmt.Errorf("invalid objective %q: invalid window %q", expression, match[5])
+	}
+
+	if objective.Name == "" {
+		objective.Name = objective.Expression()
+	}
+
+	return objective, nil
+}
+
+// ParseObjectives reads an objective per line, skipping blank lines and #
+// comments
+func ParseObjectives(reader io.Reader) ([]Objective, error) {
+	objectives := []Objective{}
+	names := map[string]bool{}
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		objective, err := ParseObjective(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		if names[objective.Name] {
+			return nil, fmt.Errorf("line %d: duplicate objective %q", line, objective.Name)
+		}
+		names[objective.Name] = true
+
+		objectives = append(objectives, objective)
+	}
+
+	return objectives, scanner.Err()
+}
+
+func LoadObjectives(path string) ([]Objective, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseObjectives(file)
+}
+
+// Expression is the objective without its name
+func (o Objective) Expression() string {
+	expression := strconv.FormatFloat(o.Target*100, 'g', -1, 64) + "% of " + string(o.Kind)
+	if o.Kind == TasksClaimed {
+		expression += " within " + o.Within.String()
+	}
+
+	return expression + " over " + formatWindow(o.Window)
+}
+
+// ParseWindows parses a comma separated list of windows, like 5m,1h,3d
+func ParseWindows(list string) ([]time.Duration, error) {
+	windows := []time.Duration{}
+	for _, window := range strings.Split(list, ",") {
+		window = strings.TrimSpace(window)
+		if window == "" {
+			continue
+		}
+
+		duration, err := parseWindow(window)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid window %q", window)
+		}
+
+		windows = append(windows, duration)
+	}
+
+	return windows, nil
+}
+
+// parseWindow is time.ParseDuration, with whole days like 30d as well
+func parseWindow(window string) (time.Duration, error) {
+	if strings.HasSuffix(window, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(window)
+}
+
+func formatWindow(window time.Duration) string {
+	if window%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(window/(24*time.Hour))) + "d"
+	}
+
+	return window.String()
+}
@@ -0,0 +1,94 @@
+package slo_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Objective", func() {
+	Describe("ParseObjective", func() {
+		It("parses a task claim objective", func() {
+			objective, err := ParseObjective("TaskClaims: 99% of tasks claimed within 30s over 30d")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(objective).Should(Equal(Objective{
+				Name:   "TaskClaims",
+				Kind:   TasksClaimed,
+				Target: 0.99,
+				Within: 30 * time.Second,
+				Window: 30 * 24 * time.Hour,
+			}))
+		})
+
+		It("parses an unnamed lrp availability objective", func() {
+			objective, err := ParseObjective("99.5% of lrp instances running over 12h")
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(objective.Name).Should(Equal("99.5% of lrp instances running over 12h0m0s"))
+			Ω(objective.Kind).Should(Equal(LRPInstancesRunning))
+			Ω(objective.Target).Should(BeNumerically("~", 0.995, 1e-9))
+			Ω(objective.Window).Should(Equal(12 * time.Hour))
+		})
+
+		It("rejects invalid objectives", func() {
+			for _, expression := range []string{
+				"",
+				"99% of tasks claimed over 30d",
+				"99% of tasks claimed within soon over 30d",
+				"99% of lrp instances running within 30s over 30d",
+				"100% of lrp instances running over 30d",
+				"ninety% of lrp instances running over 30d",
+				"99% of lrp instances running over a month",
+				"99% of lrps crashing over 30d",
+			} {
+				_, err := ParseObjective(expression)
+				Ω(err).Should(HaveOccurred(), expression)
+			}
+		})
+	})
+
+	Describe("ParseObjectives", func() {
+		It("parses an objective per line, skipping blank lines and comments", func() {
+			objectives, err := ParseObjectives(strings.NewReader(`
+# scheduling
+TaskClaims: 99% of tasks claimed within 30s over 30d
+
+99.5% of lrp instances running over 30d
+`))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(objectives).Should(HaveLen(2))
+			Ω(objectives[0].Name).Should(Equal("TaskClaims"))
+			Ω(objectives[1].Name).Should(Equal("99.5% of lrp instances running over 30d"))
+		})
+
+		It("reports the line of an invalid objective", func() {
+			_, err := ParseObjectives(strings.NewReader("\n99% of everything over 30d\n"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("line 2"))
+		})
+
+		It("rejects duplicate names", func() {
+			_, err := ParseObjectives(strings.NewReader("A: 99% of tasks claimed within 30s over 30d\nA: 99% of lrp instances running over 30d"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("duplicate"))
+		})
+	})
+
+	Describe("ParseWindows", func() {
+		It("parses a comma separated list of durations and days", func() {
+			windows, err := ParseWindows("5m, 1h,3d")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(windows).Should(Equal([]time.Duration{5 * time.Minute, time.Hour, 72 * time.Hour}))
+		})
+
+		It("rejects invalid windows", func() {
+			_, err := ParseWindows("5m,soon")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
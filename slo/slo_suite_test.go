@@ -0,0 +1,13 @@
+package slo_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSLO(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SLO Suite")
+}
@@ -0,0 +1,129 @@
+package slo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type Status struct {
+	Objective
+
+	Good                 float64
+	Total                float64
+	Compliance           float64
+	ErrorBudgetRemaining float64
+	BurnRates            []BurnRate
+}
+
+// BurnRate is the ratio of bad events over the window to the ratio the
+// objective allows, so a burn rate of 1 spends the error budget exactly over
+// the window of the objective
+type BurnRate struct {
+	Window   time.Duration
+	BurnRate float64
+}
+
+type statusJSON struct {
+	Name                 string         `json:"name"`
+	Expression           string         `json:"expression"`
+	Target               float64        `json:"target"`
+	Window               float64        `json:"window"`
+	Good                 float64        `json:"good"`
+	Total                float64        `json:"total"`
+	Compliance           float64        `json:"compliance"`
+	ErrorBudgetRemaining float64        `json:"error_budget_remaining"`
+	BurnRates            []burnRateJSON `json:"burn_rates"`
+}
+
+type burnRateJSON struct {
+	Window   float64 `json:"window"`
+	BurnRate float64 `json:"burn_rate"`
+}
+
+func (t *Tracker) Statuses() []Status {
+	now := t.timeProvider.Time()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	statuses := make([]Status, 0, len(t.objectives))
+	for _, objective := range t.objectives {
+		status := Status{
+			Objective:            objective.Objective,
+			Compliance:           1,
+			ErrorBudgetRemaining: 1,
+			BurnRates:            []BurnRate{},
+		}
+
+		status.Good, status.Total = objective.sum(now, t.config.Resolution, objective.Window)
+		if status.Total > 0 {
+			status.Compliance = status.Good / status.Total
+			status.ErrorBudgetRemaining = 1 - objective.burnRate(status.Good, status.Total)
+		}
+
+		for _, window := range t.config.BurnRateWindows {
+			if window > objective.Window {
+				continue
+			}
+
+			good, total := objective.sum(now, t.config.Resolution, window)
+			status.BurnRates = append(status.BurnRates, BurnRate{
+				Window:   window,
+				BurnRate: objective.burnRate(good, total),
+			})
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+func (o *tracked) burnRate(good float64, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return ((total - good) / total) / (1 - o.Target)
+}
+
+// ServeHTTP lists the status of every objective, with windows in seconds
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	objectives := []statusJSON{}
+	for _, status := range t.Statuses() {
+		burnRates := make([]burnRateJSON, len(status.BurnRates))
+		for i, burnRate := range status.BurnRates {
+			burnRates[i] = burnRateJSON{
+				Window:   burnRate.Window.Seconds(),
+				BurnRate: burnRate.BurnRate,
+			}
+		}
+
+		objectives = append(objectives, statusJSON{
+			Name:                 status.Name,
+			Expression:           status.Expression(),
+			Target:               status.Target,
+			Window:               status.Window.Seconds(),
+			Good:                 status.Good,
+			Total:                status.Total,
+			Compliance:           status.Compliance,
+			ErrorBudgetRemaining: status.ErrorBudgetRemaining,
+			BurnRates:            burnRates,
+		})
+	}
+
+	payload, err := json.Marshal(struct {
+		Objectives []statusJSON `json:"objectives"`
+	}{objectives})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(payload)
+}
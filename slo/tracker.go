@@ -0,0 +1,288 @@
+package slo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider"
+	"github.com/pivotal-golang/lager"
+)
+
+const ContextName = "SLOs"
+
+type Config struct {
+	Objectives []Objective
+
+	// Resolution is the interval the good and total events are counted
+	// in; together with the window of an objective it bounds the memory
+	// used, and it is the shortest burn rate window that is meaningful
+	Resolution time.Duration
+
+	// BurnRateWindows are the windows the burn rates are computed over,
+	// e.g. 5m and 1h to alert on fast burns, 6h and 3d on slow ones; the
+	// ones longer than the window of an objective are skipped for it
+	BurnRateWindows []time.Duration
+}
+
+type BBS interface {
+	GetAllTasks() ([]models.Task, error)
+	GetAllDesiredLRPs() ([]models.DesiredLRP, error)
+	GetAllActualLRPs() ([]models.ActualLRP, error)
+}
+
+// Tracker counts the good and total events of every objective at each
+// collection and emits their compliance, remaining error budget and burn
+// rates.
+//
+// A task is counted once: when it has been pending for longer than the
+// objective allows, or when it is first seen claimed. Tasks are only
+// timestamped when created and last updated, so a task that was seen pending
+// but had already started by the next collection counts as claimed when it
+// started. A task first seen past claimed is counted the same way, by when
+// it was last updated, whether or not that was in time.
+type Tracker struct {
+	bbs          BBS
+	config       Config
+	timeProvider timeprovider.TimeProvider
+	logger       lager.Logger
+
+	lock       *sync.Mutex
+	objectives []*tracked
+}
+
+type tracked struct {
+	Objective
+
+	buckets []bucket
+
+	// counted are the guids of the tasks already counted, or seen pending
+	// and not counted yet
+	counted map[string]bool
+}
+
+type bucket struct {
+	slot  time.Time
+	good  float64
+	total float64
+}
+
+// Validate checks the resolution is positive
+func (config Config) Validate() error {
+	if config.Resolution <= 0 {
+		return fmt.Errorf("invalid resolution %s, expected a positive duration", config.Resolution)
+	}
+
+	return nil
+}
+
+func New(bbs BBS, config Config, timeProvider timeprovider.TimeProvider, logger lager.Logger) (*Tracker, error) {
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	objectives := make([]*tracked, len(config.Objectives))
+	for i, objective := range config.Objectives {
+		objectives[i] = &tracked{
+			Objective: objective,
+			buckets:   make([]bucket, int(objective.Window/config.Resolution)+1),
+			counted:   map[string]bool{},
+		}
+	}
+
+	return &Tracker{
+		bbs:          bbs,
+		config:       config,
+		timeProvider: timeProvider,
+		logger:       logger.Session("slo"),
+
+		lock:       new(sync.Mutex),
+		objectives: objectives,
+	}, nil
+}
+
+func (t *Tracker) Emit() instrumentation.Context {
+	t.record()
+
+	metrics := []instrumentation.Metric{}
+	for _, status := range t.Statuses() {
+		tags := map[string]interface{}{"slo": status.Name}
+
+		metrics = append(metrics,
+			instrumentation.Metric{
+				Name:  "Compliance",
+				Value: metric.NewGauge(status.Compliance, "ratio", "Ratio of good events over the window of the objective, 1 when there were none"),
+				Tags:  tags,
+			},
+			instrumentation.Metric{
+				Name:  "ErrorBudgetRemaining",
+				Value: metric.NewGauge(status.ErrorBudgetRemaining, "ratio", "Ratio of the bad events allowed over the window of the objective that are left, negative when overspent"),
+				Tags:  tags,
+			},
+		)
+
+		for _, burnRate := range status.BurnRates {
+			metrics = append(metrics, instrumentation.Metric{
+				Name:  "BurnRate",
+				Value: metric.NewGauge(burnRate.BurnRate, "", "Rate the error budget was spent at over the window, 1 spends it exactly over the window of the objective"),
+				Tags:  map[string]interface{}{"slo": status.Name, "window": formatWindow(burnRate.Window)},
+			})
+		}
+	}
+
+	return instrumentation.Context{
+		Name:    ContextName,
+		Metrics: metrics,
+	}
+}
+
+func (t *Tracker) record() {
+	now := t.timeProvider.Time()
+
+	needsTasks, needsLRPs := false, false
+	for _, objective := range t.objectives {
+		switch objective.Kind {
+		case TasksClaimed:
+			needsTasks = true
+		case LRPInstancesRunning:
+			needsLRPs = true
+		}
+	}
+
+	// objectives whose inputs couldn't be read count nothing this time
+	var tasks []models.Task
+	tasksRead := false
+	if needsTasks {
+		var err error
+		tasks, err = t.bbs.GetAllTasks()
+		if err != nil {
+			t.logger.Error("failed-to-get-tasks", err)
+		}
+		tasksRead = err == nil
+	}
+
+	var desired []models.DesiredLRP
+	var actual []models.ActualLRP
+	lrpsRead := false
+	if needsLRPs {
+		var err error
+		desired, err = t.bbs.GetAllDesiredLRPs()
+		if err == nil {
+			actual, err = t.bbs.GetAllActualLRPs()
+		}
+		if err != nil {
+			t.logger.Error("failed-to-get-lrps", err)
+		}
+		lrpsRead = err == nil
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, objective := range t.objectives {
+		switch {
+		case objective.Kind == TasksClaimed && tasksRead:
+			good, total := objective.countTasks(tasks, now)
+			objective.add(now, t.config.Resolution, good, total)
+		case objective.Kind == LRPInstancesRunning && lrpsRead:
+			good, total := countInstances(desired, actual)
+			objective.add(now, t.config.Resolution, good, total)
+		}
+	}
+}
+
+func (o *tracked) countTasks(tasks []models.Task, now time.Time) (good float64, total float64) {
+	present := map[string]bool{}
+
+	for _, task := range tasks {
+		counted := o.counted[task.Guid]
+		present[task.Guid] = true
+		if counted {
+			continue
+		}
+
+		createdAt := time.Unix(0, task.CreatedAt)
+
+		switch task.State {
+		case models.TaskStatePending:
+			if now.Sub(createdAt) > o.Within {
+				o.counted[task.Guid] = true
+				total++
+			} else {
+				o.counted[task.Guid] = false
+			}
+
+		default:
+			o.counted[task.Guid] = true
+			total++
+			if time.Unix(0, task.UpdatedAt).Sub(createdAt) <= o.Within {
+				good++
+			}
+		}
+	}
+
+	for guid := range o.counted {
+		if !present[guid] {
+			delete(o.counted, guid)
+		}
+	}
+
+	return good, total
+}
+
+func countInstances(desired []models.DesiredLRP, actual []models.ActualLRP) (good float64, total float64) {
+	running := map[string]map[int]bool{}
+	for _, lrp := range actual {
+		if lrp.State != models.ActualLRPStateRunning {
+			continue
+		}
+
+		if running[lrp.ProcessGuid] == nil {
+			running[lrp.ProcessGuid] = map[int]bool{}
+		}
+		running[lrp.ProcessGuid][lrp.Index] = true
+	}
+
+	for _, lrp := range desired {
+		for index := 0; index < lrp.Instances; index++ {
+			total++
+			if running[lrp.ProcessGuid][index] {
+				good++
+			}
+		}
+	}
+
+	return good, total
+}
+
+func (o *tracked) add(now time.Time, resolution time.Duration, good float64, total float64) {
+	slot := now.Truncate(resolution)
+	b := &o.buckets[int((slot.UnixNano()/int64(resolution))%int64(len(o.buckets)))]
+
+	if !b.slot.Equal(slot) {
+		*b = bucket{slot: slot}
+	}
+
+	b.good += good
+	b.total += total
+}
+
+// sum counts the events in the buckets within the window before now
+func (o *tracked) sum(now time.Time, resolution time.Duration, window time.Duration) (good float64, total float64) {
+	from := now.Truncate(resolution).Add(-window)
+
+	for _, b := range o.buckets {
+		if b.slot.IsZero() || !b.slot.After(from) || b.slot.After(now) {
+			continue
+		}
+
+		good += b.good
+		total += b.total
+	}
+
+	return good, total
+}
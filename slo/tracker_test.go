@@ -0,0 +1,334 @@
+package slo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/metric"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/slo"
+	"github.com/cloudfoundry-incubator/runtime-schema/models"
+	"github.com/cloudfoundry/gunk/timeprovider/faketimeprovider"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeBBS struct {
+	tasks       []models.Task
+	desiredLRPs []models.DesiredLRP
+	actualLRPs  []models.ActualLRP
+	err         error
+}
+
+func (f *fakeBBS) GetAllTasks() ([]models.Task, error) {
+	return f.tasks, f.err
+}
+
+func (f *fakeBBS) GetAllDesiredLRPs() ([]models.DesiredLRP, error) {
+	return f.desiredLRPs, f.err
+}
+
+func (f *fakeBBS) GetAllActualLRPs() ([]models.ActualLRP, error) {
+	return f.actualLRPs, f.err
+}
+
+var _ = Describe("Tracker", func() {
+	var (
+		bbs          *fakeBBS
+		timeProvider *faketimeprovider.FakeTimeProvider
+		config       Config
+		tracker      *Tracker
+	)
+
+	objective := func(expression string) Objective {
+		objective, err := ParseObjective(expression)
+		Ω(err).ShouldNot(HaveOccurred())
+		return objective
+	}
+
+	task := func(guid string, state models.TaskState, createdAgo time.Duration, updatedAgo time.Duration) models.Task {
+		now := timeProvider.Time()
+		return models.Task{
+			Guid:      guid,
+			State:     state,
+			CreatedAt: now.Add(-createdAgo).UnixNano(),
+			UpdatedAt: now.Add(-updatedAgo).UnixNano(),
+		}
+	}
+
+	instances := func(processGuid string, running int, starting int) []models.ActualLRP {
+		lrps := []models.ActualLRP{}
+		for index := 0; index < running+starting; index++ {
+			state := models.ActualLRPStateRunning
+			if index >= running {
+				state = models.ActualLRPStateStarting
+			}
+
+			lrps = append(lrps, models.ActualLRP{ProcessGuid: processGuid, Index: index, State: state})
+		}
+		return lrps
+	}
+
+	BeforeEach(func() {
+		bbs = &fakeBBS{}
+		timeProvider = faketimeprovider.New(time.Unix(1000000, 0))
+		config = Config{
+			Resolution:      time.Minute,
+			BurnRateWindows: []time.Duration{5 * time.Minute, time.Hour},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		tracker, err = New(bbs, config, timeProvider, lagertest.NewTestLogger("test"))
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	Describe("task claims", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("TaskClaims: 99% of tasks claimed within 30s over 1h")}
+		})
+
+		It("counts tasks claimed within the duration as good, and later as bad", func() {
+			bbs.tasks = []models.Task{
+				task("quick", models.TaskStateClaimed, 20*time.Second, 10*time.Second),
+				task("slow", models.TaskStateClaimed, time.Minute, 10*time.Second),
+			}
+			tracker.Emit()
+
+			status := tracker.Statuses()[0]
+			Ω(status.Good).Should(Equal(float64(1)))
+			Ω(status.Total).Should(Equal(float64(2)))
+			Ω(status.Compliance).Should(Equal(0.5))
+		})
+
+		It("counts a task pending for longer than the duration as bad, once", func() {
+			bbs.tasks = []models.Task{task("stuck", models.TaskStatePending, time.Minute, time.Minute)}
+			tracker.Emit()
+			tracker.Emit()
+
+			bbs.tasks = []models.Task{task("stuck", models.TaskStateClaimed, 2*time.Minute, 0)}
+			tracker.Emit()
+
+			status := tracker.Statuses()[0]
+			Ω(status.Good).Should(BeZero())
+			Ω(status.Total).Should(Equal(float64(1)))
+		})
+
+		It("counts a task seen pending and then running by when it started", func() {
+			bbs.tasks = []models.Task{task("fast", models.TaskStatePending, 5*time.Second, 5*time.Second)}
+			tracker.Emit()
+
+			Ω(tracker.Statuses()[0].Total).Should(BeZero())
+
+			bbs.tasks = []models.Task{task("fast", models.TaskStateRunning, 15*time.Second, 0)}
+			tracker.Emit()
+
+			status := tracker.Statuses()[0]
+			Ω(status.Good).Should(Equal(float64(1)))
+			Ω(status.Total).Should(Equal(float64(1)))
+		})
+
+		It("counts a task first seen past claimed by when it was last updated", func() {
+			bbs.tasks = []models.Task{
+				task("running", models.TaskStateRunning, time.Minute, 50*time.Second),
+				task("completed", models.TaskStateCompleted, time.Minute, 40*time.Second),
+				task("resolving", models.TaskStateResolving, time.Minute, 35*time.Second),
+				task("old", models.TaskStateCompleted, time.Hour, time.Minute),
+			}
+			tracker.Emit()
+
+			status := tracker.Statuses()[0]
+			Ω(status.Good).Should(Equal(float64(3)))
+			Ω(status.Total).Should(Equal(float64(4)))
+		})
+
+		It("counts a task again once it has been resolved and desired again", func() {
+			bbs.tasks = []models.Task{task("again", models.TaskStateClaimed, 10*time.Second, 5*time.Second)}
+			tracker.Emit()
+
+			bbs.tasks = []models.Task{}
+			tracker.Emit()
+
+			bbs.tasks = []models.Task{task("again", models.TaskStateClaimed, 10*time.Second, 5*time.Second)}
+			tracker.Emit()
+
+			Ω(tracker.Statuses()[0].Total).Should(Equal(float64(2)))
+		})
+	})
+
+	Describe("lrp availability", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("LRPs: 90% of lrp instances running over 1h")}
+
+			bbs.desiredLRPs = []models.DesiredLRP{
+				{ProcessGuid: "web", Instances: 3},
+				{ProcessGuid: "worker", Instances: 7},
+			}
+		})
+
+		It("counts every desired instance at every collection, as good when running at its index", func() {
+			bbs.actualLRPs = append(instances("web", 2, 1), instances("worker", 7, 0)...)
+			tracker.Emit()
+			tracker.Emit()
+
+			status := tracker.Statuses()[0]
+			Ω(status.Good).Should(Equal(float64(18)))
+			Ω(status.Total).Should(Equal(float64(20)))
+		})
+
+		It("computes the remaining error budget over the window and the burn rates over theirs", func() {
+			bbs.actualLRPs = append(instances("web", 3, 0), instances("worker", 7, 0)...)
+			for i := 0; i < 50; i++ {
+				timeProvider.Increment(time.Minute)
+				tracker.Emit()
+			}
+
+			bbs.actualLRPs = append(instances("web", 1, 0), instances("worker", 7, 0)...)
+			for i := 0; i < 5; i++ {
+				timeProvider.Increment(time.Minute)
+				tracker.Emit()
+			}
+
+			status := tracker.Statuses()[0]
+			Ω(status.Total).Should(Equal(float64(550)))
+			Ω(status.Good).Should(Equal(float64(540)))
+			Ω(status.ErrorBudgetRemaining).Should(BeNumerically("~", 1-(10.0/550)/0.1, 1e-9))
+
+			Ω(status.BurnRates).Should(HaveLen(2))
+			Ω(status.BurnRates[0].Window).Should(Equal(5 * time.Minute))
+			Ω(status.BurnRates[0].BurnRate).Should(BeNumerically("~", 2, 1e-9))
+			Ω(status.BurnRates[1].Window).Should(Equal(time.Hour))
+			Ω(status.BurnRates[1].BurnRate).Should(BeNumerically("~", (10.0/550)/0.1, 1e-9))
+		})
+
+		It("forgets the events that have left the window", func() {
+			bbs.actualLRPs = instances("web", 1, 0)
+			tracker.Emit()
+
+			timeProvider.Increment(2 * time.Hour)
+
+			status := tracker.Statuses()[0]
+			Ω(status.Total).Should(BeZero())
+			Ω(status.Compliance).Should(Equal(float64(1)))
+			Ω(status.ErrorBudgetRemaining).Should(Equal(float64(1)))
+		})
+	})
+
+	Context("when the BBS can't be read", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("LRPs: 90% of lrp instances running over 1h")}
+			bbs.desiredLRPs = []models.DesiredLRP{{ProcessGuid: "web", Instances: 3}}
+			bbs.err = errors.New("etcd is down")
+		})
+
+		It("counts nothing, rather than every instance as bad", func() {
+			tracker.Emit()
+
+			Ω(tracker.Statuses()[0].Total).Should(BeZero())
+		})
+	})
+
+	Describe("Emit", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("LRPs: 75% of lrp instances running over 1h")}
+			bbs.desiredLRPs = []models.DesiredLRP{{ProcessGuid: "web", Instances: 2}}
+			bbs.actualLRPs = instances("web", 1, 0)
+		})
+
+		It("emits the compliance, remaining error budget and burn rates of every objective", func() {
+			context := tracker.Emit()
+
+			Ω(context.Name).Should(Equal("SLOs"))
+
+			values := map[string]float64{}
+			for _, m := range context.Metrics {
+				Ω(m.Tags["slo"]).Should(Equal("LRPs"))
+
+				name := m.Name
+				if window, found := m.Tags["window"]; found {
+					name += "/" + window.(string)
+				}
+				values[name] = m.Value.(metric.Value).Number
+			}
+
+			Ω(values).Should(Equal(map[string]float64{
+				"Compliance":           0.5,
+				"ErrorBudgetRemaining": -1,
+				"BurnRate/5m0s":        2,
+				"BurnRate/1h0m0s":      2,
+			}))
+		})
+	})
+
+	Describe("ServeHTTP", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("LRPs: 90% of lrp instances running over 1h")}
+			bbs.desiredLRPs = []models.DesiredLRP{{ProcessGuid: "web", Instances: 2}}
+			bbs.actualLRPs = instances("web", 2, 0)
+		})
+
+		It("lists the status of every objective, with windows in seconds", func() {
+			tracker.Emit()
+
+			recorder := httptest.NewRecorder()
+			request, _ := http.NewRequest("GET", "/v1/slo", nil)
+			tracker.ServeHTTP(recorder, request)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.HeaderMap.Get("Content-Type")).Should(Equal("application/json"))
+
+			response := map[string]interface{}{}
+			err := json.Unmarshal(recorder.Body.Bytes(), &response)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(response).Should(Equal(map[string]interface{}{
+				"objectives": []interface{}{
+					map[string]interface{}{
+						"name":                   "LRPs",
+						"expression":             "90% of lrp instances running over 1h0m0s",
+						"target":                 0.9,
+						"window":                 float64(3600),
+						"good":                   float64(2),
+						"total":                  float64(2),
+						"compliance":             float64(1),
+						"error_budget_remaining": float64(1),
+						"burn_rates": []interface{}{
+							map[string]interface{}{"window": float64(300), "burn_rate": float64(0)},
+							map[string]interface{}{"window": float64(3600), "burn_rate": float64(0)},
+						},
+					},
+				},
+			}))
+		})
+	})
+
+	Describe("New", func() {
+		BeforeEach(func() {
+			config.Objectives = []Objective{objective("TaskClaims: 99% of tasks claimed within 30s over 1h")}
+		})
+
+		It("fails without a positive resolution", func() {
+			config.Resolution = 0
+
+			_, err := New(bbs, config, timeProvider, lagertest.NewTestLogger("test"))
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(ContainSubstring("resolution"))
+		})
+
+		It("skips the burn rate windows longer than the window of an objective", func() {
+			config.BurnRateWindows = []time.Duration{5 * time.Minute, 6 * time.Hour}
+
+			tracker, err := New(bbs, config, timeProvider, lagertest.NewTestLogger("test"))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			burnRates := tracker.Statuses()[0].BurnRates
+			Ω(burnRates).Should(HaveLen(1))
+			Ω(burnRates[0].Window).Should(Equal(5 * time.Minute))
+		})
+	})
+})
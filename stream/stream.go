@@ -0,0 +1,270 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	"github.com/pivotal-golang/lager"
+)
+
+type Source interface {
+	collection.Source
+	Subscribe() <-chan collection.Snapshot
+	Unsubscribe(<-chan collection.Snapshot)
+}
+
+// Stream serves Server-Sent Events: a snapshot event when a client
+// connects, then a delta event with the metrics that changed in each
+// collection, or another snapshot event when metrics are gone from it.
+// Clients only ever get the latest collection, and are dropped when a write
+// takes longer than the write timeout.
+//
+// The connection is hijacked, as a response can't be given a write
+// deadline, and the stream ends when the client closes it.
+//
+// Stream must be stopped before the HTTP server serving it, which would
+// otherwise wait on the open streams forever.
+type Stream struct {
+	source       Source
+	writeTimeout time.Duration
+	logger       lager.Logger
+
+	done chan struct{}
+}
+
+func New(source Source, writeTimeout time.Duration, logger lager.Logger) *Stream {
+	return &Stream{
+		source:       source,
+		writeTimeout: writeTimeout,
+		logger:       logger.Session("stream"),
+
+		done: make(chan struct{}),
+	}
+}
+
+func (s *Stream) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	<-signals
+	close(s.done)
+	return nil
+}
+
+type event struct {
+	Sequence  uint64                    `json:"sequence"`
+	Timestamp time.Time                 `json:"timestamp"`
+	Contexts  []instrumentation.Context `json:"contexts"`
+}
+
+func (s *Stream) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := s.logger.Session("client", lager.Data{"remote-addr": req.RemoteAddr})
+	filter := contextFilter(req)
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		logger.Error("failed-to-hijack", err)
+		return
+	}
+	defer conn.Close()
+
+	snapshots := s.source.Subscribe()
+	defer s.source.Unsubscribe(snapshots)
+
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, buffered)
+		close(closed)
+	}()
+
+	write := func(message string) error {
+		return writeWithin(conn, buffered.Writer, s.writeTimeout, message)
+	}
+
+	if err := write("HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nCache-Control: no-cache\r\nConnection: close\r\n\r\n: connected\n\n"); err != nil {
+		logger.Info("dropped", lager.Data{"error": err.Error()})
+		return
+	}
+
+	var sent map[string]instrumentation.Metric
+	var sequence uint64
+
+	if snapshot, ok := s.source.Latest(); ok {
+		sent = index(snapshot.Contexts, filter)
+		sequence = snapshot.Sequence
+
+		if err := write(message("snapshot", filtered(snapshot, filter))); err != nil {
+			logger.Info("dropped", lager.Data{"error": err.Error()})
+			return
+		}
+	}
+
+	for {
+		select {
+		case snapshot := <-snapshots:
+			if snapshot.Sequence <= sequence {
+				continue
+			}
+			sequence = snapshot.Sequence
+
+			current := index(snapshot.Contexts, filter)
+
+			var err error
+			if sent == nil || removed(sent, current) {
+				// a delta can't tell what is gone, so the client starts over
+				err = write(message("snapshot", filtered(snapshot, filter)))
+			} else if changes := delta(sent, snapshot, filter); len(changes.Contexts) > 0 {
+				err = write(message("delta", changes))
+			} else {
+				// lets proxies and us know the connection is still alive
+				err = write(": no changes\n\n")
+			}
+
+			if err != nil {
+				logger.Info("dropped", lager.Data{"error": err.Error()})
+				return
+			}
+
+			sent = current
+
+		case <-closed:
+			return
+
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeWithin writes and flushes the message, failing when the client
+// doesn't take it within the timeout
+func writeWithin(conn net.Conn, w *bufio.Writer, timeout time.Duration, message string) error {
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+
+	_, err := w.WriteString(message)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+func message(eventType string, e event) string {
+	payload, _ := json.Marshal(e)
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.Sequence, eventType, payload)
+}
+
+// contextFilter is the set of contexts asked for with ?context=, either
+// repeated or comma separated; nil means all of them
+func contextFilter(req *http.Request) map[string]bool {
+	values := req.URL.Query()["context"]
+	if len(values) == 0 {
+		return nil
+	}
+
+	filter := map[string]bool{}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			if name != "" {
+				filter[name] = true
+			}
+		}
+	}
+
+	return filter
+}
+
+func included(filter map[string]bool, context string) bool {
+	return filter == nil || filter[context]
+}
+
+func filtered(snapshot collection.Snapshot, filter map[string]bool) event {
+	contexts := []instrumentation.Context{}
+	for _, context := range snapshot.Contexts {
+		if included(filter, context.Name) {
+			contexts = append(contexts, context)
+		}
+	}
+
+	return event{Sequence: snapshot.Sequence, Timestamp: snapshot.Timestamp, Contexts: contexts}
+}
+
+func delta(sent map[string]instrumentation.Metric, snapshot collection.Snapshot, filter map[string]bool) event {
+	changes := event{
+		Sequence:  snapshot.Sequence,
+		Timestamp: snapshot.Timestamp,
+		Contexts:  []instrumentation.Context{},
+	}
+
+	for _, context := range snapshot.Contexts {
+		if !included(filter, context.Name) {
+			continue
+		}
+
+		changed := instrumentation.Context{Name: context.Name}
+		for _, m := range context.Metrics {
+			previous, found := sent[key(context.Name, m)]
+			if !found || !reflect.DeepEqual(previous.Value, m.Value) {
+				changed.Metrics = append(changed.Metrics, m)
+			}
+		}
+
+		if len(changed.Metrics) > 0 {
+			changes.Contexts = append(changes.Contexts, changed)
+		}
+	}
+
+	return changes
+}
+
+// removed is whether any metric sent before is gone
+func removed(sent map[string]instrumentation.Metric, current map[string]instrumentation.Metric) bool {
+	for key := range sent {
+		if _, found := current[key]; !found {
+			return true
+		}
+	}
+
+	return false
+}
+
+func index(contexts []instrumentation.Context, filter map[string]bool) map[string]instrumentation.Metric {
+	indexed := map[string]instrumentation.Metric{}
+	for _, context := range contexts {
+		if !included(filter, context.Name) {
+			continue
+		}
+
+		for _, m := range context.Metrics {
+			indexed[key(context.Name, m)] = m
+		}
+	}
+
+	return indexed
+}
+
+func key(context string, m instrumentation.Metric) string {
+	tags := make([]string, 0, len(m.Tags))
+	for tag, value := range m.Tags {
+		tags = append(tags, fmt.Sprintf("%s=%v", tag, value))
+	}
+	sort.Strings(tags)
+
+	return context + "/" + m.Name + "{" + strings.Join(tags, ",") + "}"
+}
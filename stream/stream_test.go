@@ -0,0 +1,316 @@
+package stream_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/metricz/instrumentation"
+	"github.com/cloudfoundry-incubator/runtime-metrics-server/collection"
+	. "github.com/cloudfoundry-incubator/runtime-metrics-server/stream"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+type fakeSource struct {
+	lock         sync.Mutex
+	latest       collection.Snapshot
+	subscribers  []chan collection.Snapshot
+	unsubscribed int
+}
+
+func (s *fakeSource) Latest() (collection.Snapshot, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.latest, s.latest.Sequence > 0
+}
+
+func (s *fakeSource) InProgress() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (s *fakeSource) Subscribe() <-chan collection.Snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subscriber := make(chan collection.Snapshot, 1)
+	s.subscribers = append(s.subscribers, subscriber)
+	return subscriber
+}
+
+func (s *fakeSource) Unsubscribe(<-chan collection.Snapshot) {
+	s.lock.Lock()
+	s.unsubscribed++
+	s.lock.Unlock()
+}
+
+func (s *fakeSource) Subscribers() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.subscribers) - s.unsubscribed
+}
+
+func (s *fakeSource) collect(snapshot collection.Snapshot) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.latest = snapshot
+	for _, subscriber := range s.subscribers {
+		select {
+		case <-subscriber:
+		default:
+		}
+		subscriber <- snapshot
+	}
+}
+
+type event struct {
+	ID   string
+	Type string
+	Data struct {
+		Sequence uint64                    `json:"sequence"`
+		Contexts []instrumentation.Context `json:"contexts"`
+	}
+}
+
+func readEvent(reader *bufio.Reader) event {
+	var e event
+	for {
+		line, err := reader.ReadString('\n')
+		Ω(err).ShouldNot(HaveOccurred())
+
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && e.Type != "":
+			return e
+		case strings.HasPrefix(line, "id: "):
+			e.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.Data)
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+	}
+}
+
+func snapshot(sequence uint64, pending, running float64) collection.Snapshot {
+	return collection.Snapshot{
+		Sequence:  sequence,
+		Timestamp: time.Unix(int64(1000+sequence), 0),
+		Contexts: []instrumentation.Context{
+			{
+				Name: "Tasks",
+				Metrics: []instrumentation.Metric{
+					{Name: "Pending", Value: pending},
+					{Name: "Running", Value: running},
+				},
+			},
+			{
+				Name:    "MetricsServer",
+				Metrics: []instrumentation.Metric{{Name: "UptimeSeconds", Value: float64(sequence)}},
+			},
+		},
+	}
+}
+
+var _ = Describe("Stream", func() {
+	var (
+		source  *fakeSource
+		stream  *Stream
+		process ifrit.Process
+		server  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		source = &fakeSource{}
+		stream = New(source, 100*time.Millisecond, lagertest.NewTestLogger("test"))
+		process = ifrit.Envoke(stream)
+		server = httptest.NewServer(stream)
+	})
+
+	AfterEach(func() {
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		server.Close()
+	})
+
+	connect := func(query string) (*http.Response, *bufio.Reader) {
+		response, err := http.Get(server.URL + query)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(response.StatusCode).Should(Equal(http.StatusOK))
+		Ω(response.Header.Get("Content-Type")).Should(Equal("text/event-stream"))
+
+		return response, bufio.NewReader(response.Body)
+	}
+
+	It("sends the latest snapshot when a client connects", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("")
+		defer response.Body.Close()
+
+		e := readEvent(reader)
+		Ω(e.Type).Should(Equal("snapshot"))
+		Ω(e.ID).Should(Equal("1"))
+		Ω(e.Data.Sequence).Should(Equal(uint64(1)))
+		Ω(e.Data.Contexts).Should(HaveLen(2))
+		Ω(e.Data.Contexts[0].Metrics).Should(HaveLen(2))
+	})
+
+	It("sends the first snapshot when none has been collected yet", func() {
+		response, reader := connect("")
+		defer response.Body.Close()
+
+		Eventually(source.Subscribers).Should(Equal(1))
+		source.collect(snapshot(1, 3, 4))
+
+		e := readEvent(reader)
+		Ω(e.Type).Should(Equal("snapshot"))
+		Ω(e.Data.Sequence).Should(Equal(uint64(1)))
+	})
+
+	It("sends only the metrics that changed", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("")
+		defer response.Body.Close()
+		readEvent(reader)
+
+		source.collect(snapshot(2, 5, 4))
+
+		e := readEvent(reader)
+		Ω(e.Type).Should(Equal("delta"))
+		Ω(e.ID).Should(Equal("2"))
+		Ω(e.Data.Contexts).Should(HaveLen(2))
+		Ω(e.Data.Contexts[0].Name).Should(Equal("Tasks"))
+		Ω(e.Data.Contexts[0].Metrics).Should(HaveLen(1))
+		Ω(e.Data.Contexts[0].Metrics[0].Name).Should(Equal("Pending"))
+		Ω(e.Data.Contexts[0].Metrics[0].Value).Should(Equal(float64(5)))
+	})
+
+	It("sends a snapshot again when metrics are gone", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("")
+		defer response.Body.Close()
+		readEvent(reader)
+
+		withoutTasks := snapshot(2, 3, 4)
+		withoutTasks.Contexts = withoutTasks.Contexts[1:]
+		source.collect(withoutTasks)
+
+		e := readEvent(reader)
+		Ω(e.Type).Should(Equal("snapshot"))
+		Ω(e.ID).Should(Equal("2"))
+		Ω(e.Data.Contexts).Should(HaveLen(1))
+		Ω(e.Data.Contexts[0].Name).Should(Equal("MetricsServer"))
+
+		source.collect(snapshot(3, 3, 4))
+
+		e = readEvent(reader)
+		Ω(e.Type).Should(Equal("delta"))
+		Ω(e.Data.Contexts[0].Name).Should(Equal("Tasks"))
+		Ω(e.Data.Contexts[0].Metrics).Should(HaveLen(2))
+	})
+
+	It("filters by context", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("?context=MetricsServer")
+		defer response.Body.Close()
+
+		e := readEvent(reader)
+		Ω(e.Data.Contexts).Should(HaveLen(1))
+		Ω(e.Data.Contexts[0].Name).Should(Equal("MetricsServer"))
+
+		source.collect(snapshot(2, 5, 4))
+
+		e = readEvent(reader)
+		Ω(e.Type).Should(Equal("delta"))
+		Ω(e.Data.Contexts).Should(HaveLen(1))
+		Ω(e.Data.Contexts[0].Name).Should(Equal("MetricsServer"))
+	})
+
+	It("accepts comma separated contexts", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("?context=Tasks,MetricsServer")
+		defer response.Body.Close()
+
+		Ω(readEvent(reader).Data.Contexts).Should(HaveLen(2))
+	})
+
+	It("does not send a delta when nothing in the filter changed", func() {
+		source.collect(snapshot(1, 3, 4))
+
+		response, reader := connect("?context=Tasks")
+		defer response.Body.Close()
+		readEvent(reader)
+
+		source.collect(snapshot(2, 3, 4))
+		source.collect(snapshot(3, 3, 6))
+
+		e := readEvent(reader)
+		Ω(e.ID).Should(Equal("3"))
+		Ω(e.Data.Contexts[0].Metrics).Should(HaveLen(1))
+		Ω(e.Data.Contexts[0].Metrics[0].Name).Should(Equal("Running"))
+	})
+
+	It("stops streaming when the client disconnects", func() {
+		response, _ := connect("")
+		Eventually(source.Subscribers).Should(Equal(1))
+
+		response.Body.Close()
+		Eventually(source.Subscribers).Should(Equal(0))
+	})
+
+	It("closes open streams when stopped", func() {
+		response, reader := connect("")
+		defer response.Body.Close()
+		Eventually(source.Subscribers).Should(Equal(1))
+
+		process.Signal(syscall.SIGTERM)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		Eventually(source.Subscribers).Should(Equal(0))
+		_, err := reader.ReadString(0)
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("drops clients that do not keep up", func() {
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		Ω(err).ShouldNot(HaveOccurred())
+		defer conn.Close()
+
+		fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+		Eventually(source.Subscribers).Should(Equal(1))
+
+		// the client never reads, so writes block once the socket buffers fill
+		for sequence := uint64(1); source.Subscribers() > 0 && sequence < 100; sequence++ {
+			large := make([]instrumentation.Metric, 100000)
+			for i := range large {
+				large[i] = instrumentation.Metric{Name: fmt.Sprintf("Metric%d", i), Value: sequence}
+			}
+
+			source.collect(collection.Snapshot{
+				Sequence: sequence,
+				Contexts: []instrumentation.Context{{Name: "Large", Metrics: large}},
+			})
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		Eventually(source.Subscribers).Should(Equal(0))
+	})
+})